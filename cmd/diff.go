@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/samc1213/gtfs-analyze/core"
+	"github.com/spf13/cobra"
+)
+
+var FromVersion string
+var ToVersion string
+var FromPath string
+var ToPath string
+var StopMoveThresholdMeters float64
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show what changed between two static GTFS feeds",
+	Long: `The diff command compares two static feeds, either two versions stored
+in a database by the store command, or two local zip files or directories.
+It reports added, removed and modified stops, routes, trips and calendars,
+stops that moved, and changes in the number of scheduled trips per route
+on each weekday`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var diff *core.FeedDiff
+		var err error
+		if FromPath != "" || ToPath != "" {
+			if FromPath == "" || ToPath == "" {
+				return errors.New("must provide both from-path and to-path")
+			}
+			diff, err = core.DiffFeedPaths(FromPath, ToPath, StopMoveThresholdMeters, LogLevel)
+		} else {
+			if DbPath == "" || FromVersion == "" || ToVersion == "" {
+				return errors.New("must provide db-path, from-version and to-version, or from-path and to-path")
+			}
+			diff, err = core.DiffFeedVersions(DbPath, FromVersion, ToVersion, StopMoveThresholdMeters, LogLevel)
+		}
+		if err != nil {
+			return err
+		}
+		return printReport(diff)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to a local SQLite database, or the postgres:// url of a PostgreSQL database")
+	diffCmd.Flags().StringVar(&FromVersion, "from-version", "", "The stored feed version to compare from")
+	diffCmd.Flags().StringVar(&ToVersion, "to-version", "", "The stored feed version to compare to")
+	diffCmd.Flags().StringVar(&FromPath, "from-path", "", "A local GTFS zip file or directory to compare from")
+	diffCmd.Flags().StringVar(&ToPath, "to-path", "", "A local GTFS zip file or directory to compare to")
+	diffCmd.Flags().Float64Var(&StopMoveThresholdMeters, "stop-move-threshold", 50, "How far a stop must move, in meters, to be reported as moved")
+	diffCmd.Flags().StringVar(&OutputFormat, "format", "text", `Output format, "text" or "json"`)
+}
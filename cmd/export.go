@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export stored data out of the database",
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+}
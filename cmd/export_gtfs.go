@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/samc1213/gtfs-analyze/core"
+	"github.com/spf13/cobra"
+)
+
+var FeedVersion string
+var OutputPath string
+
+var exportGtfsCmd = &cobra.Command{
+	Use:   "gtfs",
+	Short: "Export a stored static feed version as a GTFS zip file",
+	Long: `The gtfs command rebuilds a GTFS zip file from a feed version stored
+by the store command. This allows recovering historical schedules that
+the agency no longer publishes`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return core.ExportStaticGtfsToPath(DbPath, FeedVersion, OutputPath, LogLevel)
+	},
+}
+
+func init() {
+	exportCmd.AddCommand(exportGtfsCmd)
+
+	exportGtfsCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to a local SQLite database, or the postgres:// url of a PostgreSQL database")
+	exportGtfsCmd.MarkFlagRequired("db-path")
+
+	exportGtfsCmd.Flags().StringVar(&FeedVersion, "version", "", "The feed version to export")
+	exportGtfsCmd.MarkFlagRequired("version")
+
+	exportGtfsCmd.Flags().StringVar(&OutputPath, "output", "google_transit.zip", "Where to write the GTFS zip file")
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/core"
+	"github.com/spf13/cobra"
+)
+
+var ParquetOutputDir string
+
+var exportParquetCmd = &cobra.Command{
+	Use:   "parquet",
+	Short: "Export stored feeds and vehicle positions as Parquet files",
+	Long: `The parquet command writes the data stored by the store command to a folder
+of Parquet files, for analysis in tools like DuckDB, pandas and Spark. Static
+feed tables are partitioned by feed version, and vehicle positions and the
+stop events observed in them are partitioned by service date`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var startTime, endTime time.Time
+		var err error
+		if StartTime != "" {
+			startTime, err = parseTime(StartTime)
+			if err != nil {
+				return errors.New("start-time must be in format " + time.RFC3339 + " or " + time.RFC822Z)
+			}
+		}
+		if EndTime != "" {
+			endTime, err = parseTime(EndTime)
+			if err != nil {
+				return errors.New("end-time must be in format " + time.RFC3339 + " or " + time.RFC822Z)
+			}
+		}
+		return core.ExportParquet(DbPath, ParquetOutputDir, startTime, endTime, LogLevel)
+	},
+}
+
+func init() {
+	exportCmd.AddCommand(exportParquetCmd)
+
+	exportParquetCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to a local SQLite database, or the postgres:// url of a PostgreSQL database")
+	exportParquetCmd.MarkFlagRequired("db-path")
+
+	exportParquetCmd.Flags().StringVar(&ParquetOutputDir, "output", "parquet", "The folder to write the Parquet files to")
+
+	exportParquetCmd.Flags().StringVar(&StartTime, "start-time", "", "Only export vehicle positions from this time on")
+
+	exportParquetCmd.Flags().StringVar(&EndTime, "end-time", "", "Only export vehicle positions up to this time")
+}
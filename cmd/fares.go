@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/samc1213/gtfs-analyze/core"
+	"github.com/spf13/cobra"
+)
+
+var FareQuery core.FareQuery
+
+var faresCmd = &cobra.Command{
+	Use:   "fares",
+	Short: "Find the fares for a ride between two stops on a route",
+	Long: `The fares command looks up which fares from fare_attributes.txt and
+fare_rules.txt apply to a ride on a route, from an origin stop to a destination
+stop. It uses the zones of the stops along the way, so it can be used to
+analyze fare zones. The feed is read from a database filled by the store
+command, or from a local GTFS zip file or directory`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var result *core.FareQueryResult
+		var err error
+		FareQuery.Language = Language
+		if FeedPath != "" {
+			result, err = core.FindFaresForPath(FeedPath, FareQuery, LogLevel)
+		} else {
+			if DbPath == "" {
+				return errors.New("must provide db-path or path")
+			}
+			result, err = core.FindFaresForVersion(DbPath, Namespace, FeedVersion, FareQuery, LogLevel)
+		}
+		if err != nil {
+			return err
+		}
+		return printReport(result)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(faresCmd)
+
+	faresCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to a local SQLite database, or the postgres:// url of a PostgreSQL database")
+	faresCmd.Flags().StringVar(&FeedVersion, "version", "", "The stored feed version to use. Defaults to the feed active today")
+	faresCmd.Flags().StringVar(&Namespace, "namespace", "", "The namespace of the stored feed to use when --version isn't given, like a feed in a store --config")
+	faresCmd.Flags().StringVar(&FeedPath, "path", "", "A local GTFS zip file or directory to use instead of the database")
+	faresCmd.Flags().StringVar(&FareQuery.OriginStopId, "origin", "", "The stop_id where the ride starts")
+	faresCmd.MarkFlagRequired("origin")
+	faresCmd.Flags().StringVar(&FareQuery.DestinationStopId, "destination", "", "The stop_id where the ride ends")
+	faresCmd.MarkFlagRequired("destination")
+	faresCmd.Flags().StringVar(&FareQuery.RouteId, "route", "", "The route_id of the ride")
+	faresCmd.MarkFlagRequired("route")
+	faresCmd.Flags().StringVar(&OutputFormat, "format", "text", `Output format, "text" or "json"`)
+	faresCmd.Flags().StringVar(&Language, "lang", "", `Language of the stop and route names in the result, like "fr" or "es-MX", from translations.txt`)
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var ImportNamespace string
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import local GTFS files into the database",
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/samc1213/gtfs-analyze/core"
+	"github.com/spf13/cobra"
+)
+
+var importRtCmd = &cobra.Command{
+	Use:   "rt <file-or-folder>...",
+	Short: "Import GTFS-RT messages from local .pb files",
+	Long: `The rt command stores GTFS-RT vehicle positions, trip updates and alerts
+from local .pb files, or gzipped .pb.gz files, such as the snapshots of a
+feed archive. Folders are searched for them, including subfolders. Messages
+are stored in the order of their header timestamps, and messages that are
+already stored are skipped`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return core.ImportRtGtfs(DbPath, args, ImportNamespace, LogLevel)
+	},
+}
+
+func init() {
+	importCmd.AddCommand(importRtCmd)
+
+	importRtCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to a local SQLite database, or the postgres:// url of a PostgreSQL database")
+	importRtCmd.MarkFlagRequired("db-path")
+	importRtCmd.Flags().StringVar(&ImportNamespace, "namespace", "", "The namespace to store the messages under, like a feed in a store --config")
+}
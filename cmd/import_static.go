@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/samc1213/gtfs-analyze/core"
+	"github.com/spf13/cobra"
+)
+
+var importStaticCmd = &cobra.Command{
+	Use:   "static <path>...",
+	Short: "Import static GTFS feeds from local zip files or folders",
+	Long: `The static command stores static GTFS feeds from local zip files or
+folders, like the store command does from a url. Feed versions that are
+already stored are skipped`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		options := core.StaticParseOptions{ErrorMode: ParseErrorMode, Encoding: Encoding}
+		return core.ImportStaticGtfs(DbPath, args, ImportNamespace, options, LogLevel)
+	},
+}
+
+func init() {
+	importCmd.AddCommand(importStaticCmd)
+
+	importStaticCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to a local SQLite database, or the postgres:// url of a PostgreSQL database")
+	importStaticCmd.MarkFlagRequired("db-path")
+	importStaticCmd.Flags().StringVar(&ImportNamespace, "namespace", "", "The namespace to store the feeds under, like a feed in a store --config")
+	importStaticCmd.Flags().Var(&ParseErrorMode, "on-parse-error", `How to handle rows that can't be parsed: "strict" fails the import, "skip-row" drops the row, "default-field" keeps the row with the bad field defaulted`)
+	importStaticCmd.Flags().StringVar(&Encoding, "encoding", "", `The character encoding of the files, such as "windows-1252". Detected from each file by default`)
+}
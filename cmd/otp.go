@@ -12,6 +12,11 @@ import (
 var StartTime string
 var EndTime string
 var OnTimeThreshold time.Duration
+var OtpGroupBy core.GroupBy = core.TripId
+var OtpAgencyIds []string
+
+// The namespace of the stored feed to use, like a feed in a store --config
+var Namespace string
 
 func parseTime(timeString string) (time.Time, error) {
 	result, err := time.Parse(time.RFC3339, timeString)
@@ -33,7 +38,7 @@ var otpCmd = &cobra.Command{
 		if err != nil {
 			return errors.New("end-time must be in format " + time.RFC3339 + " or " + time.RFC822Z)
 		}
-		summary, err := core.CalculateOtpForTimeRange(DbPath, startTime, endTime, OnTimeThreshold, LogLevel)
+		summary, err := core.CalculateOtpForTimeRange(DbPath, Namespace, startTime, endTime, OnTimeThreshold, OtpGroupBy, OtpAgencyIds, Language, LogLevel)
 		if err != nil {
 			return err
 		}
@@ -45,9 +50,11 @@ var otpCmd = &cobra.Command{
 func init() {
 	calculateCmd.AddCommand(otpCmd)
 
-	otpCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to a local SQLite database for logging")
+	otpCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to a local SQLite database, or the postgres:// url of a PostgreSQL database")
 	otpCmd.MarkFlagRequired("db-path")
 
+	otpCmd.Flags().StringVar(&Namespace, "namespace", "", "The namespace of the feed and vehicle positions, like a feed in a store --config")
+
 	otpCmd.Flags().StringVar(&StartTime, "start-time", "", "When to start the OTP calculation")
 	otpCmd.MarkFlagRequired("start-time")
 
@@ -55,4 +62,7 @@ func init() {
 	otpCmd.MarkFlagRequired("end-time")
 
 	otpCmd.Flags().DurationVar(&OnTimeThreshold, "threshold", 7*time.Minute, "How close to expected arrival a vehicle must be to count as on-time")
+	otpCmd.Flags().Var(&OtpGroupBy, "group-by", `How to group the on-time performance: "trip", "stop", "station" to roll platforms up to their parent station, or "agency"`)
+	otpCmd.Flags().StringSliceVar(&OtpAgencyIds, "agency", nil, "Only include the trips of these agency_ids. Can be repeated or comma-separated")
+	otpCmd.Flags().StringVar(&Language, "lang", "", `Language of the stop, route and headsign names in the report, like "fr" or "es-MX", from translations.txt`)
 }
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var OutputFormat string
+
+type prettyPrinter interface {
+	PrettyPrint() string
+}
+
+// Prints a report either as human-readable text or as indented JSON, depending on
+// the --format flag
+func printReport(report prettyPrinter) error {
+	switch OutputFormat {
+	case "text":
+		fmt.Println(report.PrettyPrint())
+		return nil
+	case "json":
+		output, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	default:
+		return errors.New(`format must be one of "text" or "json"`)
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/core"
+	"github.com/spf13/cobra"
+)
+
+var JourneyLegs []string
+var FareMediaId string
+
+// Parses a leg in the format route_id,from_stop_id,to_stop_id,departure_time[,arrival_time]
+func parseJourneyLeg(legString string) (core.JourneyLeg, error) {
+	parts := strings.Split(legString, ",")
+	if len(parts) != 4 && len(parts) != 5 {
+		return core.JourneyLeg{}, fmt.Errorf("leg %s must be in format route_id,from_stop_id,to_stop_id,departure_time[,arrival_time]", legString)
+	}
+	leg := core.JourneyLeg{RouteId: parts[0], FromStopId: parts[1], ToStopId: parts[2]}
+	var err error
+	leg.DepartureTime, err = parseTime(parts[3])
+	if err != nil {
+		return leg, errors.New("leg departure_time must be in format " + time.RFC3339 + " or " + time.RFC822Z)
+	}
+	if len(parts) == 5 {
+		leg.ArrivalTime, err = parseTime(parts[4])
+		if err != nil {
+			return leg, errors.New("leg arrival_time must be in format " + time.RFC3339 + " or " + time.RFC822Z)
+		}
+	}
+	return leg, nil
+}
+
+var priceJourneyCmd = &cobra.Command{
+	Use:   "price-journey",
+	Short: "Price a multi-leg journey with the Fares v2 files of a feed",
+	Long: `The price-journey command prices a journey made of one or more legs, using
+fare_products.txt, fare_leg_rules.txt, fare_transfer_rules.txt and the files
+they refer to. Each leg is given as route_id,from_stop_id,to_stop_id,departure_time
+with an optional arrival_time at the end. The feed is read from a database
+filled by the store command, or from a local GTFS zip file or directory`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		legs := make([]core.JourneyLeg, 0, len(JourneyLegs))
+		for _, legString := range JourneyLegs {
+			leg, err := parseJourneyLeg(legString)
+			if err != nil {
+				return err
+			}
+			legs = append(legs, leg)
+		}
+
+		var price *core.JourneyPrice
+		var err error
+		if FeedPath != "" {
+			price, err = core.PriceJourneyForPath(FeedPath, legs, FareMediaId, LogLevel)
+		} else {
+			if DbPath == "" {
+				return errors.New("must provide db-path or path")
+			}
+			price, err = core.PriceJourneyForVersion(DbPath, Namespace, FeedVersion, legs, FareMediaId, LogLevel)
+		}
+		if err != nil {
+			return err
+		}
+		return printReport(price)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(priceJourneyCmd)
+
+	priceJourneyCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to a local SQLite database, or the postgres:// url of a PostgreSQL database")
+	priceJourneyCmd.Flags().StringVar(&FeedVersion, "version", "", "The stored feed version to use. Defaults to the feed active on the day of the first leg")
+	priceJourneyCmd.Flags().StringVar(&Namespace, "namespace", "", "The namespace of the stored feed to use when --version isn't given, like a feed in a store --config")
+	priceJourneyCmd.Flags().StringVar(&FeedPath, "path", "", "A local GTFS zip file or directory to use instead of the database")
+	priceJourneyCmd.Flags().StringArrayVar(&JourneyLegs, "leg", nil, "A leg of the journey, as route_id,from_stop_id,to_stop_id,departure_time[,arrival_time]. Repeat for each leg, in order")
+	priceJourneyCmd.MarkFlagRequired("leg")
+	priceJourneyCmd.Flags().StringVar(&FareMediaId, "fare-media", "", "Only use fare products sold on this fare_media_id")
+	priceJourneyCmd.Flags().StringVar(&OutputFormat, "format", "text", `Output format, "text" or "json"`)
+}
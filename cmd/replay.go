@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"github.com/samc1213/gtfs-analyze/core"
+	"github.com/spf13/cobra"
+)
+
+var ReplayFromDbPath string
+
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Stores archived GTFS-RT messages again into a new database",
+	Long: `The replay command reads the GTFS-RT messages archived by store with
+--archive-raw or --archive-dir, and stores them again, oldest first, into
+another database. Since the messages are converted with this version of
+gtfs-analyze, fields and fixes added since they were fetched apply to them`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return core.ReplayRawArchive(ReplayFromDbPath, ArchiveDir, DbPath, LogLevel)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().StringVar(&ReplayFromDbPath, "from", "", "The path to the SQLite database, or the postgres:// url of the PostgreSQL database, the messages were archived in")
+	replayCmd.MarkFlagRequired("from")
+	replayCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to the SQLite database, or the postgres:// url of the PostgreSQL database, to store the messages in")
+	replayCmd.MarkFlagRequired("db-path")
+	replayCmd.Flags().StringVar(&ArchiveDir, "archive-dir", "", "The directory the messages were archived in, if store was run with --archive-dir")
+}
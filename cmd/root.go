@@ -9,6 +9,9 @@ import (
 
 var LogLevel log.Level = log.Error
 
+// The language to translate names in reports to, for the commands with a --lang flag
+var Language string
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "gtfs-analyze",
@@ -1,7 +1,15 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/samc1213/gtfs-analyze/core"
+	"github.com/samc1213/gtfs-analyze/csv_parse"
 	"github.com/spf13/cobra"
 )
 
@@ -9,30 +17,110 @@ var DbPath string
 var StaticUrl string
 var RtUrl string
 var VehiclePositionUrl string
+var TripUpdatesUrl string
+var AlertsUrl string
+var StoreConfigPath string
+var ArchiveRaw bool
+var ArchiveDir string
+var FeedHeaders []string
+var FeedQueryParams []string
 var RtPollIntervalSecs uint
 var StaticPollIntervalMins uint
+var ParseErrorMode csv_parse.ErrorMode = csv_parse.Strict
+var Encoding string
 
 // storeCmd represents the log command
 var storeCmd = &cobra.Command{
 	Use:   "store",
 	Short: "Stores a GTFS feed to a database",
 	Long: `The store command saves all data from a GTFS feed into a database
-for further analysis. It currently supports SQLite databases and only saves
-static GTFS feeds`,
+for further analysis. It supports SQLite databases, and PostgreSQL databases
+with PostGIS, which several store commands can write to at once. With --config,
+it polls every feed listed in a YAML or TOML file into one database, keeping
+each feed's data apart by its namespace`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := core.Store(DbPath, StaticUrl, VehiclePositionUrl, StaticPollIntervalMins, RtPollIntervalSecs, LogLevel)
-		return err
+		config, err := getStoreConfig(cmd)
+		if err != nil {
+			return err
+		}
+		// Stop polling on Ctrl-C or a service manager's SIGTERM, letting writes in progress finish
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return core.StoreFeeds(ctx, config, LogLevel)
 	},
 }
 
+// Loads the config file, or makes a config of the one feed given by flags
+func getStoreConfig(cmd *cobra.Command) (*core.StoreConfig, error) {
+	var config *core.StoreConfig
+	if StoreConfigPath != "" {
+		for _, flag := range []string{"static-url", "vehicle-pos-url", "trip-updates-url", "alerts-url", "header", "query-param"} {
+			if cmd.Flags().Changed(flag) {
+				return nil, errors.New("--" + flag + " can't be used with --config. Add it to the config's feeds instead")
+			}
+		}
+		var err error
+		config, err = core.LoadStoreConfig(StoreConfigPath)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		headers, err := core.ParseNameValuePairs(FeedHeaders)
+		if err != nil {
+			return nil, err
+		}
+		queryParams, err := core.ParseNameValuePairs(FeedQueryParams)
+		if err != nil {
+			return nil, err
+		}
+		staticPollInterval := time.Duration(StaticPollIntervalMins) * time.Minute
+		rtPollInterval := time.Duration(RtPollIntervalSecs) * time.Second
+		config = &core.StoreConfig{Feeds: []core.FeedConfig{{
+			StaticUrl:           StaticUrl,
+			VehiclePositionsUrl: VehiclePositionUrl,
+			TripUpdatesUrl:      TripUpdatesUrl,
+			AlertsUrl:           AlertsUrl,
+			Headers:             headers,
+			QueryParams:         queryParams,
+			StaticPollInterval:  &staticPollInterval,
+			RtPollInterval:      &rtPollInterval,
+			OnParseError:        ParseErrorMode.String(),
+			Encoding:            Encoding,
+		}}}
+	}
+
+	// --db-path overrides the config's db_path
+	if DbPath != "" {
+		config.DbPath = DbPath
+	}
+	if config.DbPath == "" {
+		return nil, errors.New("must provide --db-path, or a config with a db_path")
+	}
+	if ArchiveRaw {
+		config.ArchiveRaw = true
+	}
+	if ArchiveDir != "" {
+		config.ArchiveDir = ArchiveDir
+	}
+	return config, nil
+}
+
 func init() {
 	rootCmd.AddCommand(storeCmd)
 
-	storeCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to a local SQLite database for logging")
-	storeCmd.MarkFlagRequired("db-path")
+	storeCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to a local SQLite database, or the postgres:// url of a PostgreSQL database. Overrides db_path in --config")
 	storeCmd.Flags().StringVar(&StaticUrl, "static-url", "", "The web url for a static GTFS feed")
 	storeCmd.Flags().StringVar(&VehiclePositionUrl, "vehicle-pos-url", "", "The web url for a GTFS-RT VehiclePosition protobuf update")
+	storeCmd.Flags().StringVar(&TripUpdatesUrl, "trip-updates-url", "", "The web url for a GTFS-RT TripUpdate protobuf update")
+	storeCmd.Flags().StringVar(&AlertsUrl, "alerts-url", "", "The web url for a GTFS-RT Alert protobuf update")
+	storeCmd.Flags().StringArrayVar(&FeedHeaders, "header", nil, `A header to send with every request, like "x-api-key=env:API_KEY". The value can be "env:NAME" or "file:PATH" to read a secret`)
+	storeCmd.Flags().StringArrayVar(&FeedQueryParams, "query-param", nil, `A query parameter to add to every request, like "api_key=env:API_KEY". The value can be "env:NAME" or "file:PATH" to read a secret`)
+	storeCmd.Flags().StringVar(&StoreConfigPath, "config", "", "The path to a YAML or TOML file listing the feeds to store")
+	storeCmd.Flags().BoolVar(&ArchiveRaw, "archive-raw", false, "Keep every GTFS-RT message exactly as it was fetched, compressed in the database, so it can be stored again with the replay command")
+	storeCmd.Flags().StringVar(&ArchiveDir, "archive-dir", "", "Keep every GTFS-RT message exactly as it was fetched, like --archive-raw, but with the compressed messages in this directory instead of the database")
 	storeCmd.Flags().UintVar(&RtPollIntervalSecs, "rt-poll-interval", 30, "How often to poll for GTFS-RT data, in seconds")
 	storeCmd.Flags().UintVar(&StaticPollIntervalMins, "static-poll-interval", 60, "How often to poll for static GTFS data, in minutes")
+	storeCmd.Flags().Var(&ParseErrorMode, "on-parse-error", `How to handle static GTFS rows that can't be parsed: "strict" fails the import, "skip-row" drops the row, "default-field" keeps the row with the bad field defaulted`)
+	storeCmd.Flags().StringVar(&Encoding, "encoding", "", `The character encoding of the static GTFS files, such as "windows-1252". Detected from each file by default`)
 
 }
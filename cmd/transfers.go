@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/core"
+	"github.com/spf13/cobra"
+)
+
+var transfersCmd = &cobra.Command{
+	Use:   "transfers",
+	Short: "Calculate how often transfers between routes were made",
+	Long: `The transfers command checks the transfers in transfers.txt between two
+routes or trips at a stop. For each scheduled connection in the time range, it
+uses the vehicle positions logged in the store command to decide whether the
+connecting vehicle left after the arriving vehicle got there, leaving at least
+min_transfer_time to make the transfer`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		startTime, err := parseTime(StartTime)
+		if err != nil {
+			return errors.New("start-time must be in format " + time.RFC3339 + " or " + time.RFC822Z)
+		}
+		endTime, err := parseTime(EndTime)
+		if err != nil {
+			return errors.New("end-time must be in format " + time.RFC3339 + " or " + time.RFC822Z)
+		}
+		summary, err := core.CalculateTransfersForTimeRange(DbPath, Namespace, startTime, endTime, LogLevel)
+		if err != nil {
+			return err
+		}
+		return printReport(summary)
+	},
+}
+
+func init() {
+	calculateCmd.AddCommand(transfersCmd)
+
+	transfersCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to a local SQLite database, or the postgres:// url of a PostgreSQL database")
+	transfersCmd.MarkFlagRequired("db-path")
+
+	transfersCmd.Flags().StringVar(&Namespace, "namespace", "", "The namespace of the feed and vehicle positions, like a feed in a store --config")
+
+	transfersCmd.Flags().StringVar(&StartTime, "start-time", "", "When to start the transfer calculation")
+	transfersCmd.MarkFlagRequired("start-time")
+
+	transfersCmd.Flags().StringVar(&EndTime, "end-time", "", "When to end the transfer calculation")
+	transfersCmd.MarkFlagRequired("end-time")
+
+	transfersCmd.Flags().StringVar(&OutputFormat, "format", "text", `Output format, "text" or "json"`)
+}
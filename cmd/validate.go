@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/samc1213/gtfs-analyze/core"
+	"github.com/spf13/cobra"
+)
+
+var FeedPath string
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate a static GTFS feed",
+	Long: `The validate command checks a local static GTFS zip file or directory
+against the GTFS reference. Rather than stopping at the first problem, it
+reports every finding with its file, line, severity and code. It exits with
+an error if any findings have error severity`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report, err := core.ValidateStaticGtfsFromPath(FeedPath)
+		if err != nil {
+			return err
+		}
+		err = printReport(report)
+		if err != nil {
+			return err
+		}
+		numErrors := report.CountBySeverity(core.SeverityError)
+		if numErrors > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("feed has %d validation errors", numErrors)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringVar(&FeedPath, "path", "", "The path to a local GTFS zip file or directory")
+	validateCmd.MarkFlagRequired("path")
+	validateCmd.Flags().StringVar(&OutputFormat, "format", "text", `Output format, "text" or "json"`)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/core"
+	"github.com/spf13/cobra"
+)
+
+var MaxDistanceFromTripMeters float64
+var MaxVehicleAge time.Duration
+
+var validateRtCmd = &cobra.Command{
+	Use:   "validate-rt [file.pb...]",
+	Short: "Validate GTFS-RT vehicle positions against the stored static feed",
+	Long: `The validate-rt command checks GTFS-RT VehiclePosition messages, fetched
+from a url or read from local .pb files, against the static feed version
+stored by the store command that was active when each message was generated.
+It reports unknown trips, stops and routes, vehicles far from their trip,
+stale vehicle timestamps, vehicles moving backwards along their trip, and
+missing header fields. It exits with an error if any findings have error severity`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if VehiclePositionUrl == "" && len(args) == 0 {
+			return errors.New("must provide vehicle-pos-url or at least one .pb file")
+		}
+		options := core.RtValidationOptions{MaxDistanceFromTripMeters: MaxDistanceFromTripMeters, MaxVehicleAge: MaxVehicleAge}
+		report, err := core.ValidateRtGtfs(DbPath, Namespace, VehiclePositionUrl, args, options, LogLevel)
+		if err != nil {
+			return err
+		}
+		err = printReport(report)
+		if err != nil {
+			return err
+		}
+		numErrors := report.CountBySeverity(core.SeverityError)
+		if numErrors > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("feed has %d validation errors", numErrors)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateRtCmd)
+
+	validateRtCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to a local SQLite database, or the postgres:// url of a PostgreSQL database")
+	validateRtCmd.MarkFlagRequired("db-path")
+
+	validateRtCmd.Flags().StringVar(&Namespace, "namespace", "", "The namespace of the stored static feeds to validate against, like a feed in a store --config")
+	validateRtCmd.Flags().StringVar(&VehiclePositionUrl, "vehicle-pos-url", "", "The web url for a GTFS-RT VehiclePosition protobuf update")
+	validateRtCmd.Flags().Float64Var(&MaxDistanceFromTripMeters, "max-distance", 1000, "How far, in meters, a vehicle can be from its trip's stops before it is reported")
+	validateRtCmd.Flags().DurationVar(&MaxVehicleAge, "max-vehicle-age", 5*time.Minute, "How much older than the message timestamp a vehicle timestamp can be before it is reported")
+	validateRtCmd.Flags().StringVar(&OutputFormat, "format", "text", `Output format, "text" or "json"`)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/samc1213/gtfs-analyze/core"
+	"github.com/spf13/cobra"
+)
+
+var StationId string
+
+var walkingTimesCmd = &cobra.Command{
+	Use:   "walking-times",
+	Short: "Find the walking times between the platforms of a station",
+	Long: `The walking-times command finds the fastest way to walk between every pair
+of platforms in a station, using pathways.txt. When a pathway has no
+traversal_time, it is estimated from the pathway's length or stair count.
+The feed is read from a database filled by the store command, or from a
+local GTFS zip file or directory`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var result *core.StationWalkingTimes
+		var err error
+		if FeedPath != "" {
+			result, err = core.GetStationWalkingTimesForPath(FeedPath, StationId, Language, LogLevel)
+		} else {
+			if DbPath == "" {
+				return errors.New("must provide db-path or path")
+			}
+			result, err = core.GetStationWalkingTimesForVersion(DbPath, Namespace, FeedVersion, StationId, Language, LogLevel)
+		}
+		if err != nil {
+			return err
+		}
+		return printReport(result)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(walkingTimesCmd)
+
+	walkingTimesCmd.Flags().StringVar(&DbPath, "db-path", "", "The path to a local SQLite database, or the postgres:// url of a PostgreSQL database")
+	walkingTimesCmd.Flags().StringVar(&FeedVersion, "version", "", "The stored feed version to use. Defaults to the feed active today")
+	walkingTimesCmd.Flags().StringVar(&Namespace, "namespace", "", "The namespace of the stored feed to use when --version isn't given, like a feed in a store --config")
+	walkingTimesCmd.Flags().StringVar(&FeedPath, "path", "", "A local GTFS zip file or directory to use instead of the database")
+	walkingTimesCmd.Flags().StringVar(&StationId, "station", "", "The stop_id of the station")
+	walkingTimesCmd.MarkFlagRequired("station")
+	walkingTimesCmd.Flags().StringVar(&OutputFormat, "format", "text", `Output format, "text" or "json"`)
+	walkingTimesCmd.Flags().StringVar(&Language, "lang", "", `Language of the station name in the report, like "fr" or "es-MX", from translations.txt`)
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// A kind of database that feeds can be stored in, chosen by the form of the database's DSN
+type databaseBackend interface {
+	name() string
+	dialector(dsn string) gorm.Dialector
+	configure(config *gorm.Config)
+	// Change the schema before and after AutoMigrate, for what gorm can't do itself
+	beforeMigrate(db *gorm.DB) error
+	afterMigrate(db *gorm.DB) error
+}
+
+// The settings a key=value PostgreSQL DSN, like "host=localhost dbname=gtfs", can start with
+var postgresDsnKeys = []string{"host", "hostaddr", "port", "dbname", "user", "password", "sslmode", "connect_timeout", "application_name"}
+
+// Finds the password in a key=value PostgreSQL DSN, which is quoted when it has spaces
+var postgresDsnPassword = regexp.MustCompile(`password\s*=\s*('(\\.|[^'])*'|\S+)`)
+
+// postgres:// and postgresql:// urls, and key=value DSNs like "host=localhost dbname=gtfs", are
+// PostgreSQL servers. Anything else is the path of a SQLite file
+func getDatabaseBackend(dsn string) databaseBackend {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") || isPostgresKeyValueDsn(dsn) {
+		return postgresBackend{}
+	}
+	return sqliteBackend{}
+}
+
+func isPostgresKeyValueDsn(dsn string) bool {
+	key, _, ok := strings.Cut(strings.TrimSpace(dsn), "=")
+	if !ok {
+		return false
+	}
+	for _, postgresKey := range postgresDsnKeys {
+		if strings.TrimSpace(key) == postgresKey {
+			return true
+		}
+	}
+	return false
+}
+
+// The DSN without its password, for logs
+func redactDsn(dsn string) string {
+	if isPostgresKeyValueDsn(dsn) {
+		return postgresDsnPassword.ReplaceAllString(dsn, "password=xxxxx")
+	}
+	parsedDsn, err := url.Parse(dsn)
+	if err != nil || parsedDsn.Scheme == "" {
+		return dsn
+	}
+	return parsedDsn.Redacted()
+}
+
+type sqliteBackend struct{}
+
+func (sqliteBackend) name() string {
+	return "SQLite"
+}
+
+func (sqliteBackend) dialector(dsn string) gorm.Dialector {
+	return sqlite.Open(dsn)
+}
+
+func (sqliteBackend) configure(config *gorm.Config) {}
+
+func (sqliteBackend) beforeMigrate(db *gorm.DB) error {
+	return migrateVehiclePositionsToNamespaces(db)
+}
+
+func (sqliteBackend) afterMigrate(db *gorm.DB) error {
+	return nil
+}
+
+// Stores feeds in a PostgreSQL server, which several store commands can write to at once. Stops,
+// shape points and vehicle positions get PostGIS geometry columns, computed from their coordinates
+type postgresBackend struct{}
+
+func (postgresBackend) name() string {
+	return "PostgreSQL"
+}
+
+func (postgresBackend) dialector(dsn string) gorm.Dialector {
+	return &postgresDialector{Dialector: postgres.Dialector{Config: &postgres.Config{DSN: dsn}}}
+}
+
+func (postgresBackend) configure(config *gorm.Config) {
+	// The models' foreign keys refer to part of a composite primary key, which PostgreSQL can't
+	// enforce. SQLite doesn't enforce them either
+	config.DisableForeignKeyConstraintWhenMigrating = true
+}
+
+func (postgresBackend) beforeMigrate(db *gorm.DB) error {
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS postgis").Error; err != nil {
+		return fmt.Errorf("PostGIS must be available on the PostgreSQL server: %w", err)
+	}
+	return nil
+}
+
+// The tables with latitude and longitude columns that get a PostGIS point column named geom
+var postgisPointTables = []string{"stops", "shape_points", "vehicle_positions"}
+
+func (postgresBackend) afterMigrate(db *gorm.DB) error {
+	var statements []string
+	for _, table := range postgisPointTables {
+		statements = append(statements,
+			"ALTER TABLE "+table+" ADD COLUMN IF NOT EXISTS geom geometry(Point, 4326) GENERATED ALWAYS AS (ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)) STORED",
+			"CREATE INDEX IF NOT EXISTS idx_"+table+"_geom ON "+table+" USING GIST (geom)",
+		)
+	}
+	// Each shape as a line through its points
+	statements = append(statements, "CREATE OR REPLACE VIEW shape_lines AS SELECT version, shape_id, ST_MakeLine(geom ORDER BY sequence) AS geom FROM shape_points GROUP BY version, shape_id")
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Changes the column types gorm's PostgreSQL driver picks for some fields
+type postgresDialector struct {
+	postgres.Dialector
+}
+
+func (dialector *postgresDialector) DataTypeOf(field *schema.Field) string {
+	switch field.DataType {
+	case schema.Float:
+		// Instead of decimal, which is read back as a string
+		if field.Precision == 0 {
+			return "double precision"
+		}
+	case schema.Int, schema.Uint:
+		// Types like csv_parse.Optional[int32] have no size, which would make them smallint
+		if field.Size == 0 {
+			return "bigint"
+		}
+	}
+	return dialector.Dialector.DataTypeOf(field)
+}
+
+// Like the driver's migrator, but with the column types of DataTypeOf
+func (dialector *postgresDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return postgres.Migrator{Migrator: migrator.Migrator{Config: migrator.Config{
+		DB:                          db,
+		Dialector:                   dialector,
+		CreateIndexAfterCreateTable: true,
+	}}}
+}
@@ -0,0 +1,406 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/infra"
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+)
+
+// One ride on a single route, as part of a journey
+type JourneyLeg struct {
+	RouteId       string
+	FromStopId    string
+	ToStopId      string
+	DepartureTime time.Time
+	ArrivalTime   time.Time // When zero, DepartureTime is used
+}
+
+type PricedLeg struct {
+	RouteId               string  `json:"route_id"`
+	FromStopId            string  `json:"from_stop_id"`
+	ToStopId              string  `json:"to_stop_id"`
+	LegGroupId            string  `json:"leg_group_id"`
+	FareProductId         string  `json:"fare_product_id"`
+	TransferFareProductId string  `json:"transfer_fare_product_id,omitempty"`
+	IsTransfer            bool    `json:"is_transfer"`
+	Amount                float64 `json:"amount"` // What is charged for this leg, after any transfer discount
+}
+
+type JourneyPrice struct {
+	Version     string      `json:"version"`
+	FareMediaId string      `json:"fare_media_id,omitempty"`
+	Legs        []PricedLeg `json:"legs"`
+	TotalAmount float64     `json:"total_amount"`
+	Currency    string      `json:"currency"`
+}
+
+// Prices a journey using a feed stored in the database. When version is empty, the feed of
+// namespace that is active on the day of the first leg is used
+func PriceJourneyForVersion(dbPath string, namespace string, version string, legs []JourneyLeg, fareMediaId string, logLevel log.Level) (*JourneyPrice, error) {
+	logger := log.New(logLevel)
+
+	db, err := openExistingDb(logger, dbPath, logLevel)
+	if err != nil {
+		return nil, err
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlDb.Close()
+
+	var feed *model.GtfsStaticFeed
+	if version == "" && len(legs) > 0 {
+		year, month, day := legs[0].DepartureTime.Date()
+		feed, err = GetFeedOnDate(namespace, year, month, day, db)
+	} else {
+		feed, err = GetFeedByVersion(version, db)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return PriceJourney(feed, legs, fareMediaId)
+}
+
+// Prices a journey using a local GTFS zip file or directory
+func PriceJourneyForPath(path string, legs []JourneyLeg, fareMediaId string, logLevel log.Level) (*JourneyPrice, error) {
+	logger := log.New(logLevel)
+
+	logger.Info("Parsing static GTFS from path: %s", path)
+	feed, err := ParseStaticGtfsFromPath(path, StaticParseOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return PriceJourney(feed, legs, fareMediaId)
+}
+
+// Prices a journey with the Fares v2 files of a feed. Each leg is matched to the cheapest fare
+// product of its fare leg rules, and then consecutive legs are combined with fare transfer rules.
+// When fareMediaId is set, only fare products sold on that fare media are used
+func PriceJourney(feed *model.GtfsStaticFeed, legs []JourneyLeg, fareMediaId string) (*JourneyPrice, error) {
+	if len(legs) == 0 {
+		return nil, fmt.Errorf("journey has no legs")
+	}
+	calculator, err := newFareCalculator(feed, fareMediaId)
+	if err != nil {
+		return nil, err
+	}
+
+	price := JourneyPrice{Version: feed.FeedInfo.Version, FareMediaId: fareMediaId, Legs: make([]PricedLeg, len(legs))}
+	legGroupIds := make([]string, len(legs))
+	subJourneyStart := 0
+	for i := range legs {
+		leg := &legs[i]
+		if leg.ArrivalTime.IsZero() {
+			leg.ArrivalTime = leg.DepartureTime
+		}
+		rule, product, err := calculator.getLegFare(leg)
+		if err != nil {
+			return nil, fmt.Errorf("leg %d: %w", i+1, err)
+		}
+		if price.Currency == "" {
+			price.Currency = product.Currency
+		} else if product.Currency != price.Currency {
+			return nil, fmt.Errorf("journey mixes fares in %s and %s", price.Currency, product.Currency)
+		}
+		legGroupIds[i] = rule.LegGroupId
+		pricedLeg := PricedLeg{RouteId: leg.RouteId, FromStopId: leg.FromStopId, ToStopId: leg.ToStopId, LegGroupId: rule.LegGroupId, FareProductId: product.Id, Amount: product.Amount}
+
+		if i > 0 {
+			transfer, ok := calculator.getTransfer(legGroupIds[i-1], rule.LegGroupId, i-subJourneyStart, &legs[i-1], leg)
+			if ok {
+				pricedLeg.IsTransfer = true
+				pricedLeg.TransferFareProductId = transfer.product.Id
+				switch transfer.rule.FareTransferType {
+				case model.FromLegPlusTransfer:
+					pricedLeg.Amount = transfer.product.Amount
+				case model.FromLegPlusTransferPlusToLeg:
+					pricedLeg.Amount = transfer.product.Amount + product.Amount
+				case model.TransferOnly:
+					// The transfer fare product covers the whole sub-journey
+					for j := subJourneyStart; j < i; j++ {
+						price.Legs[j].Amount = 0
+					}
+					pricedLeg.Amount = transfer.product.Amount
+				}
+			} else {
+				subJourneyStart = i
+			}
+		}
+		price.Legs[i] = pricedLeg
+	}
+
+	for _, pricedLeg := range price.Legs {
+		price.TotalAmount += pricedLeg.Amount
+	}
+	return &price, nil
+}
+
+type fareCalculator struct {
+	feed                  *model.GtfsStaticFeed
+	location              *time.Location
+	productsById          map[string][]*model.FareProduct
+	networkIdByRouteId    map[string]string
+	areaIdsByStopId       map[string][]string
+	timeframesByGroupId   map[string][]*model.Timeframe
+	calendarByServiceId   map[string]*model.Calendar
+	explicitNetworkIds    map[string]struct{}
+	explicitFromAreaIds   map[string]struct{}
+	explicitToAreaIds     map[string]struct{}
+	explicitFromLegGroups map[string]struct{}
+	explicitToLegGroups   map[string]struct{}
+	usesRulePriority      bool
+}
+
+// A fare transfer rule, and the fare product it charges. A transfer without a fare product is free
+type fareTransfer struct {
+	rule    *model.FareTransferRule
+	product model.FareProduct
+}
+
+func newFareCalculator(feed *model.GtfsStaticFeed, fareMediaId string) (*fareCalculator, error) {
+	location := time.UTC
+	if len(feed.Agency) > 0 {
+		var err error
+		location, err = time.LoadLocation(feed.Agency[0].Timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+	calculator := fareCalculator{
+		feed:                  feed,
+		location:              location,
+		productsById:          make(map[string][]*model.FareProduct),
+		networkIdByRouteId:    make(map[string]string),
+		areaIdsByStopId:       make(map[string][]string),
+		timeframesByGroupId:   make(map[string][]*model.Timeframe),
+		calendarByServiceId:   make(map[string]*model.Calendar),
+		explicitNetworkIds:    make(map[string]struct{}),
+		explicitFromAreaIds:   make(map[string]struct{}),
+		explicitToAreaIds:     make(map[string]struct{}),
+		explicitFromLegGroups: make(map[string]struct{}),
+		explicitToLegGroups:   make(map[string]struct{}),
+	}
+
+	for i := range feed.FareProduct {
+		product := &feed.FareProduct[i]
+		if fareMediaId == "" || product.FareMediaId == fareMediaId {
+			calculator.productsById[product.Id] = append(calculator.productsById[product.Id], product)
+		}
+	}
+	for _, route := range feed.Route {
+		if route.NetworkId != "" {
+			calculator.networkIdByRouteId[route.Id] = route.NetworkId
+		}
+	}
+	for _, routeNetwork := range feed.RouteNetwork {
+		calculator.networkIdByRouteId[routeNetwork.RouteId] = routeNetwork.NetworkId
+	}
+	for _, stopArea := range feed.StopArea {
+		calculator.areaIdsByStopId[stopArea.StopId] = append(calculator.areaIdsByStopId[stopArea.StopId], stopArea.AreaId)
+	}
+	// Stops are in the areas of their parent station too
+	for _, stop := range feed.Stop {
+		if stop.ParentStationId != "" {
+			calculator.areaIdsByStopId[stop.Id] = append(calculator.areaIdsByStopId[stop.Id], calculator.areaIdsByStopId[stop.ParentStationId]...)
+		}
+	}
+	for i := range feed.Timeframe {
+		groupId := feed.Timeframe[i].TimeframeGroupId
+		calculator.timeframesByGroupId[groupId] = append(calculator.timeframesByGroupId[groupId], &feed.Timeframe[i])
+	}
+	for i := range feed.Calendar {
+		calculator.calendarByServiceId[feed.Calendar[i].ServiceId] = &feed.Calendar[i]
+	}
+	for _, rule := range feed.FareLegRule {
+		addIfNotEmpty(calculator.explicitNetworkIds, rule.NetworkId)
+		addIfNotEmpty(calculator.explicitFromAreaIds, rule.FromAreaId)
+		addIfNotEmpty(calculator.explicitToAreaIds, rule.ToAreaId)
+		if rule.RulePriority.Valid {
+			calculator.usesRulePriority = true
+		}
+	}
+	for _, rule := range feed.FareTransferRule {
+		addIfNotEmpty(calculator.explicitFromLegGroups, rule.FromLegGroupId)
+		addIfNotEmpty(calculator.explicitToLegGroups, rule.ToLegGroupId)
+	}
+	return &calculator, nil
+}
+
+func addIfNotEmpty(set map[string]struct{}, value string) {
+	if value != "" {
+		set[value] = struct{}{}
+	}
+}
+
+// An empty value in a rule matches any value that isn't used explicitly by another rule. When rule
+// priorities are used, an empty value matches everything, and the highest priority rules win instead
+func (calculator *fareCalculator) matchesRuleValue(ruleValue string, legValues []string, explicitValues map[string]struct{}) bool {
+	if ruleValue != "" {
+		for _, legValue := range legValues {
+			if legValue == ruleValue {
+				return true
+			}
+		}
+		return false
+	}
+	if calculator.usesRulePriority {
+		return true
+	}
+	for _, legValue := range legValues {
+		if _, ok := explicitValues[legValue]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (calculator *fareCalculator) isInTimeframeGroup(groupId string, t time.Time) bool {
+	if groupId == "" {
+		return true
+	}
+	local := t.In(calculator.location)
+	date := infra.Date{Year: local.Year(), Month: local.Month(), Day: local.Day()}
+	secondsAfterMidnight := model.ArrivalDepartureTime(local.Hour()*HOURS_TO_SECONDS + local.Minute()*model.MINUTES_TO_SECONDS + local.Second())
+	for _, timeframe := range calculator.timeframesByGroupId[groupId] {
+		calendar, ok := calculator.calendarByServiceId[timeframe.ServiceId]
+		if !ok || !isCalendarActiveOnDate(calendar, date) {
+			continue
+		}
+		if secondsAfterMidnight >= timeframe.StartTime && secondsAfterMidnight < timeframe.EndTime {
+			return true
+		}
+	}
+	return false
+}
+
+const HOURS_TO_SECONDS = model.HOURS_TO_MINUTES * model.MINUTES_TO_SECONDS
+
+func isCalendarActiveOnDate(calendar *model.Calendar, date infra.Date) bool {
+	day := time.Date(date.Year, date.Month, date.Day, 0, 0, 0, 0, time.UTC)
+	if day.Before(calendar.StartDate) || day.After(calendar.EndDate) {
+		return false
+	}
+	return isServiceAvailableOnWeekday(date.Weekday(), calendar)
+}
+
+// Finds the cheapest fare product among the fare leg rules that match the leg
+func (calculator *fareCalculator) getLegFare(leg *JourneyLeg) (*model.FareLegRule, *model.FareProduct, error) {
+	var networkIds []string
+	if networkId, ok := calculator.networkIdByRouteId[leg.RouteId]; ok {
+		networkIds = append(networkIds, networkId)
+	}
+	fromAreaIds := calculator.areaIdsByStopId[leg.FromStopId]
+	toAreaIds := calculator.areaIdsByStopId[leg.ToStopId]
+
+	var matchedRules []*model.FareLegRule
+	highestPriority := int32(0)
+	for i := range calculator.feed.FareLegRule {
+		rule := &calculator.feed.FareLegRule[i]
+		if !calculator.matchesRuleValue(rule.NetworkId, networkIds, calculator.explicitNetworkIds) ||
+			!calculator.matchesRuleValue(rule.FromAreaId, fromAreaIds, calculator.explicitFromAreaIds) ||
+			!calculator.matchesRuleValue(rule.ToAreaId, toAreaIds, calculator.explicitToAreaIds) ||
+			!calculator.isInTimeframeGroup(rule.FromTimeframeGroupId, leg.DepartureTime) ||
+			!calculator.isInTimeframeGroup(rule.ToTimeframeGroupId, leg.ArrivalTime) {
+			continue
+		}
+		if len(matchedRules) == 0 || rule.RulePriority.Get(0) > highestPriority {
+			highestPriority = rule.RulePriority.Get(0)
+		}
+		matchedRules = append(matchedRules, rule)
+	}
+
+	var cheapestRule *model.FareLegRule
+	var cheapestProduct *model.FareProduct
+	for _, rule := range matchedRules {
+		if rule.RulePriority.Get(0) < highestPriority {
+			continue
+		}
+		for _, product := range calculator.productsById[rule.FareProductId] {
+			if cheapestProduct == nil || product.Amount < cheapestProduct.Amount {
+				cheapestRule = rule
+				cheapestProduct = product
+			}
+		}
+	}
+	if cheapestProduct == nil {
+		return nil, nil, fmt.Errorf("no fare applies to route %s from stop %s to stop %s", leg.RouteId, leg.FromStopId, leg.ToStopId)
+	}
+	return cheapestRule, cheapestProduct, nil
+}
+
+// Finds the cheapest fare transfer rule that applies between two legs. transferNumber counts
+// the transfers so far in the sub-journey, including this one
+func (calculator *fareCalculator) getTransfer(fromLegGroupId string, toLegGroupId string, transferNumber int, fromLeg *JourneyLeg, toLeg *JourneyLeg) (fareTransfer, bool) {
+	var cheapest fareTransfer
+	found := false
+	for i := range calculator.feed.FareTransferRule {
+		rule := &calculator.feed.FareTransferRule[i]
+		if !calculator.matchesRuleValue(rule.FromLegGroupId, []string{fromLegGroupId}, calculator.explicitFromLegGroups) ||
+			!calculator.matchesRuleValue(rule.ToLegGroupId, []string{toLegGroupId}, calculator.explicitToLegGroups) {
+			continue
+		}
+		if rule.TransferCount.Valid && rule.TransferCount.V != -1 && int32(transferNumber) > rule.TransferCount.V {
+			continue
+		}
+		if rule.DurationLimit.Valid && getTransferDuration(rule.DurationLimitType.Get(model.DepartureToArrival), fromLeg, toLeg) > time.Duration(rule.DurationLimit.V)*time.Second {
+			continue
+		}
+
+		transfer := fareTransfer{rule: rule}
+		if rule.FareProductId != "" {
+			products := calculator.productsById[rule.FareProductId]
+			if len(products) == 0 {
+				continue
+			}
+			transfer.product = *products[0]
+			for _, product := range products[1:] {
+				if product.Amount < transfer.product.Amount {
+					transfer.product = *product
+				}
+			}
+		}
+		if !found || transfer.product.Amount < cheapest.product.Amount {
+			cheapest = transfer
+			found = true
+		}
+	}
+	return cheapest, found
+}
+
+func getTransferDuration(limitType model.DurationLimitType, fromLeg *JourneyLeg, toLeg *JourneyLeg) time.Duration {
+	switch limitType {
+	case model.DepartureToDeparture:
+		return toLeg.DepartureTime.Sub(fromLeg.DepartureTime)
+	case model.ArrivalToDeparture:
+		return toLeg.DepartureTime.Sub(fromLeg.ArrivalTime)
+	case model.ArrivalToArrival:
+		return toLeg.ArrivalTime.Sub(fromLeg.ArrivalTime)
+	default:
+		return toLeg.ArrivalTime.Sub(fromLeg.DepartureTime)
+	}
+}
+
+func (price *JourneyPrice) PrettyPrint() string {
+	builder := strings.Builder{}
+	writer := tabwriter.NewWriter(&builder, 0, 0, 0, ' ', tabwriter.Debug)
+	fmt.Fprintf(writer, "Leg\tRouteId\tFrom\tTo\tFareProduct\tTransfer\tAmount\n")
+	for i, leg := range price.Legs {
+		transfer := ""
+		if leg.IsTransfer {
+			transfer = "yes"
+			if leg.TransferFareProductId != "" {
+				transfer = leg.TransferFareProductId
+			}
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\t%.2f\n", i+1, leg.RouteId, leg.FromStopId, leg.ToStopId, leg.FareProductId, transfer, leg.Amount)
+	}
+	writer.Flush()
+	fmt.Fprintf(&builder, "Total: %.2f %s\n", price.TotalAmount, price.Currency)
+	return builder.String()
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFaresV2(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getGtfsFilesWith(t, "fares_v2")), StaticParseOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(feed.FareMedia))
+	assert.Equal(t, 4, len(feed.FareProduct))
+	assert.Equal(t, 4, len(feed.FareLegRule))
+	assert.Equal(t, 2, len(feed.FareTransferRule))
+	assert.Equal(t, 2, len(feed.Area))
+	assert.Equal(t, 2, len(feed.StopArea))
+	assert.Equal(t, 2, len(feed.Network))
+	assert.Equal(t, 2, len(feed.RouteNetwork))
+	assert.False(t, feed.FareTransferRule[1].TransferCount.Valid)
+	assert.Equal(t, int32(5400), feed.FareTransferRule[1].DurationLimit.V)
+}
+
+func TestPriceJourney(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getGtfsFilesWith(t, "fares_v2")), StaticParseOptions{})
+	assert.NoError(t, err)
+	denver, err := time.LoadLocation("America/Denver")
+	assert.NoError(t, err)
+	at := func(hour int, min int) time.Time { return time.Date(2023, time.May, 15, hour, min, 0, 0, denver) }
+
+	// One free transfer between local legs, then a full fare
+	price, err := PriceJourney(feed, []JourneyLeg{
+		{RouteId: "route15", FromStopId: "stop1", ToStopId: "stop2", DepartureTime: at(8, 0), ArrivalTime: at(8, 15)},
+		{RouteId: "route15", FromStopId: "stop2", ToStopId: "stop1", DepartureTime: at(8, 30), ArrivalTime: at(8, 45)},
+		{RouteId: "route15", FromStopId: "stop1", ToStopId: "stop2", DepartureTime: at(9, 0), ArrivalTime: at(9, 15)},
+	}, "card")
+	assert.NoError(t, err)
+	assert.Equal(t, []PricedLeg{
+		{RouteId: "route15", FromStopId: "stop1", ToStopId: "stop2", LegGroupId: "local", FareProductId: "local_fare", Amount: 3},
+		{RouteId: "route15", FromStopId: "stop2", ToStopId: "stop1", LegGroupId: "local", FareProductId: "local_fare", IsTransfer: true, Amount: 0},
+		{RouteId: "route15", FromStopId: "stop1", ToStopId: "stop2", LegGroupId: "local", FareProductId: "local_fare", Amount: 3},
+	}, price.Legs)
+	assert.Equal(t, 6.0, price.TotalAmount)
+	assert.Equal(t, "USD", price.Currency)
+
+	// The rail leg is in the airport leg group, because stop1 is in the downtown area of its parent station
+	price, err = PriceJourney(feed, []JourneyLeg{
+		{RouteId: "route15", FromStopId: "stop2", ToStopId: "stop1", DepartureTime: at(8, 0), ArrivalTime: at(8, 15)},
+		{RouteId: "routeA", FromStopId: "stop1", ToStopId: "stop3", DepartureTime: at(8, 30), ArrivalTime: at(9, 10)},
+	}, "card")
+	assert.NoError(t, err)
+	assert.Equal(t, "airport", price.Legs[1].LegGroupId)
+	assert.Equal(t, "airport_upgrade", price.Legs[1].TransferFareProductId)
+	assert.Equal(t, 4.5, price.TotalAmount)
+	assert.Contains(t, price.PrettyPrint(), "Total: 4.50 USD")
+
+	// Too long between departures for the upgrade
+	price, err = PriceJourney(feed, []JourneyLeg{
+		{RouteId: "route15", FromStopId: "stop2", ToStopId: "stop1", DepartureTime: at(8, 0)},
+		{RouteId: "routeA", FromStopId: "stop1", ToStopId: "stop3", DepartureTime: at(10, 0)},
+	}, "card")
+	assert.NoError(t, err)
+	assert.Equal(t, 13.0, price.TotalAmount)
+
+	// Without a fare media, the cheapest one is used, which is the app for local fares
+	price, err = PriceJourney(feed, []JourneyLeg{{RouteId: "route15", FromStopId: "stop1", ToStopId: "stop2", DepartureTime: at(8, 0)}}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2.75, price.TotalAmount)
+	price, err = PriceJourney(feed, []JourneyLeg{{RouteId: "route15", FromStopId: "stop1", ToStopId: "stop2", DepartureTime: at(8, 0)}}, "card")
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, price.TotalAmount)
+}
+
+func TestPriceJourneyPriorityAndTimeframes(t *testing.T) {
+	files := getGtfsFilesWith(t, "fares_v2")
+	files["fare_products.txt"] += "\npeak_fare,Peak,card,4.00,USD\nday_pass,Day Pass,card,5.00,USD"
+	files["timeframes.txt"] = "timeframe_group_id,start_time,end_time,service_id\npeak,07:00:00,09:00:00,wkdayService"
+	files["fare_leg_rules.txt"] = "leg_group_id,network_id,from_area_id,to_area_id,from_timeframe_group_id,fare_product_id,rule_priority\n" +
+		"local,,,,,local_fare,0\n" +
+		"peak,bus,,,peak,peak_fare,1"
+	files["fare_transfer_rules.txt"] = "from_leg_group_id,to_leg_group_id,fare_transfer_type,fare_product_id\npeak,local,2,day_pass"
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(files), StaticParseOptions{})
+	assert.NoError(t, err)
+	denver, err := time.LoadLocation("America/Denver")
+	assert.NoError(t, err)
+
+	// The higher priority peak rule wins during the peak, on weekdays
+	price, err := PriceJourney(feed, []JourneyLeg{{RouteId: "route15", FromStopId: "stop1", ToStopId: "stop2", DepartureTime: time.Date(2023, time.May, 15, 8, 0, 0, 0, denver)}}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "peak_fare", price.Legs[0].FareProductId)
+	price, err = PriceJourney(feed, []JourneyLeg{{RouteId: "route15", FromStopId: "stop1", ToStopId: "stop2", DepartureTime: time.Date(2023, time.May, 15, 9, 0, 0, 0, denver)}}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "local_fare", price.Legs[0].FareProductId)
+	price, err = PriceJourney(feed, []JourneyLeg{{RouteId: "route15", FromStopId: "stop1", ToStopId: "stop2", DepartureTime: time.Date(2023, time.May, 13, 8, 0, 0, 0, denver)}}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "local_fare", price.Legs[0].FareProductId)
+
+	// The day pass replaces both fares
+	price, err = PriceJourney(feed, []JourneyLeg{
+		{RouteId: "route15", FromStopId: "stop1", ToStopId: "stop2", DepartureTime: time.Date(2023, time.May, 15, 8, 0, 0, 0, denver)},
+		{RouteId: "routeA", FromStopId: "stop2", ToStopId: "stop3", DepartureTime: time.Date(2023, time.May, 15, 9, 30, 0, 0, denver)},
+	}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, price.Legs[0].Amount)
+	assert.Equal(t, 5.0, price.Legs[1].Amount)
+	assert.Equal(t, 5.0, price.TotalAmount)
+}
+
+func TestPriceJourneyErrors(t *testing.T) {
+	files := getGtfsFilesWith(t, "fares_v2")
+	files["fare_products.txt"] += "\neuro_fare,Euro,card,2.00,EUR"
+	files["fare_leg_rules.txt"] += "\neuro,rail,,downtown,euro_fare"
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(files), StaticParseOptions{})
+	assert.NoError(t, err)
+	departure := time.Date(2023, time.May, 15, 8, 0, 0, 0, time.UTC)
+
+	_, err = PriceJourney(feed, nil, "")
+	assert.EqualError(t, err, "journey has no legs")
+	// stop3 is in the airport area, which only has a rule from downtown
+	_, err = PriceJourney(feed, []JourneyLeg{{RouteId: "routeA", FromStopId: "stop2", ToStopId: "stop3", DepartureTime: departure}}, "")
+	assert.EqualError(t, err, "leg 1: no fare applies to route routeA from stop stop2 to stop stop3")
+	_, err = PriceJourney(feed, []JourneyLeg{
+		{RouteId: "route15", FromStopId: "stop1", ToStopId: "stop2", DepartureTime: departure},
+		{RouteId: "routeA", FromStopId: "stop2", ToStopId: "stop1", DepartureTime: departure},
+	}, "")
+	assert.EqualError(t, err, "journey mixes fares in USD and EUR")
+}
@@ -0,0 +1,260 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/csv_parse"
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+)
+
+type FareQuery struct {
+	OriginStopId      string
+	DestinationStopId string
+	RouteId           string
+	Language          string // The language of the stop and route names in the result
+}
+
+type ApplicableFare struct {
+	FareId           string                                  `json:"fare_id"`
+	Price            float64                                 `json:"price"`
+	CurrencyType     string                                  `json:"currency_type"`
+	PaymentMethod    model.PaymentMethod                     `json:"payment_method"`
+	Transfers        csv_parse.Optional[model.FareTransfers] `json:"transfers"`
+	TransferDuration csv_parse.Optional[int32]               `json:"transfer_duration"`
+}
+
+type FareQueryResult struct {
+	Version             string           `json:"version"`
+	OriginStopId        string           `json:"origin_stop_id"`
+	OriginStopName      string           `json:"origin_stop_name"`
+	DestinationStopId   string           `json:"destination_stop_id"`
+	DestinationStopName string           `json:"destination_stop_name"`
+	RouteId             string           `json:"route_id"`
+	RouteName           string           `json:"route_name"`
+	OriginZoneId        string           `json:"origin_zone_id"`
+	DestinationZoneId   string           `json:"destination_zone_id"`
+	ContainsZoneIds     []string         `json:"contains_zone_ids"`
+	Fares               []ApplicableFare `json:"fares"`
+}
+
+// Finds the fares for a ride in a feed stored in the database. When version is empty, the feed of
+// namespace that is active today is used
+func FindFaresForVersion(dbPath string, namespace string, version string, query FareQuery, logLevel log.Level) (*FareQueryResult, error) {
+	logger := log.New(logLevel)
+
+	db, err := openExistingDb(logger, dbPath, logLevel)
+	if err != nil {
+		return nil, err
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlDb.Close()
+
+	var feed *model.GtfsStaticFeed
+	if version == "" {
+		year, month, day := time.Now().Date()
+		feed, err = GetFeedOnDate(namespace, year, month, day, db)
+	} else {
+		feed, err = GetFeedByVersion(version, db)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return FindFares(feed, query)
+}
+
+// Finds the fares for a ride in a local GTFS zip file or directory
+func FindFaresForPath(path string, query FareQuery, logLevel log.Level) (*FareQueryResult, error) {
+	logger := log.New(logLevel)
+
+	logger.Info("Parsing static GTFS from path: %s", path)
+	feed, err := ParseStaticGtfsFromPath(path, StaticParseOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return FindFares(feed, query)
+}
+
+// Finds the fares v1 fares that apply to a ride on a single route, from the origin stop to the
+// destination stop. A fare applies if it has no fare rules, if one of its rules without a
+// contains_id matches the route and the zones of the origin and destination, or if the
+// contains_ids of its matching rules are exactly the zones the ride passes through
+func FindFares(feed *model.GtfsStaticFeed, query FareQuery) (*FareQueryResult, error) {
+	stopsById := make(map[string]*model.Stop, len(feed.Stop))
+	for i := range feed.Stop {
+		stopsById[feed.Stop[i].Id] = &feed.Stop[i]
+	}
+	originStop, ok := stopsById[query.OriginStopId]
+	if !ok {
+		return nil, fmt.Errorf("stop %s does not exist", query.OriginStopId)
+	}
+	destinationStop, ok := stopsById[query.DestinationStopId]
+	if !ok {
+		return nil, fmt.Errorf("stop %s does not exist", query.DestinationStopId)
+	}
+	var route *model.Route
+	for i := range feed.Route {
+		if feed.Route[i].Id == query.RouteId {
+			route = &feed.Route[i]
+		}
+	}
+	if route == nil {
+		return nil, fmt.Errorf("route %s does not exist", query.RouteId)
+	}
+
+	containsZoneIds, err := getZonesOnRoute(feed, stopsById, query)
+	if err != nil {
+		return nil, err
+	}
+
+	translator := NewTranslator(feed, query.Language)
+	result := FareQueryResult{
+		Version:             feed.FeedInfo.Version,
+		OriginStopId:        query.OriginStopId,
+		OriginStopName:      translator.StopName(originStop),
+		DestinationStopId:   query.DestinationStopId,
+		DestinationStopName: translator.StopName(destinationStop),
+		RouteId:             query.RouteId,
+		RouteName:           translator.RouteName(route),
+		OriginZoneId:        originStop.ZoneId,
+		DestinationZoneId:   destinationStop.ZoneId,
+		ContainsZoneIds:     containsZoneIds,
+	}
+
+	rulesByFareId := make(map[string][]*model.FareRule)
+	for i := range feed.FareRule {
+		rulesByFareId[feed.FareRule[i].FareId] = append(rulesByFareId[feed.FareRule[i].FareId], &feed.FareRule[i])
+	}
+	for _, fare := range feed.FareAttribute {
+		if fare.AgencyId != "" && route.AgencyId != "" && fare.AgencyId != route.AgencyId {
+			continue
+		}
+		if doesFareApply(rulesByFareId[fare.Id], &result) {
+			result.Fares = append(result.Fares, ApplicableFare{
+				FareId:           fare.Id,
+				Price:            fare.Price,
+				CurrencyType:     fare.CurrencyType,
+				PaymentMethod:    fare.PaymentMethod,
+				Transfers:        fare.Transfers,
+				TransferDuration: fare.TransferDuration,
+			})
+		}
+	}
+	sort.SliceStable(result.Fares, func(i, j int) bool {
+		if result.Fares[i].Price != result.Fares[j].Price {
+			return result.Fares[i].Price < result.Fares[j].Price
+		}
+		return result.Fares[i].FareId < result.Fares[j].FareId
+	})
+	return &result, nil
+}
+
+// Returns the sorted zones of the stops from the origin to the destination, on the first trip of
+// the route that serves the origin before the destination
+func getZonesOnRoute(feed *model.GtfsStaticFeed, stopsById map[string]*model.Stop, query FareQuery) ([]string, error) {
+	tripIds := make(map[string]struct{})
+	for _, trip := range feed.Trip {
+		if trip.RouteId == query.RouteId {
+			tripIds[trip.Id] = struct{}{}
+		}
+	}
+	stopTimesByTripId := make(map[string][]*model.StopTime)
+	var sortedTripIds []string
+	for i := range feed.StopTime {
+		tripId := feed.StopTime[i].TripId
+		if _, ok := tripIds[tripId]; !ok {
+			continue
+		}
+		if _, ok := stopTimesByTripId[tripId]; !ok {
+			sortedTripIds = append(sortedTripIds, tripId)
+		}
+		stopTimesByTripId[tripId] = append(stopTimesByTripId[tripId], &feed.StopTime[i])
+	}
+	sort.Strings(sortedTripIds)
+
+	for _, tripId := range sortedTripIds {
+		stopTimes := stopTimesByTripId[tripId]
+		sort.Slice(stopTimes, func(i, j int) bool { return stopTimes[i].StopSequence < stopTimes[j].StopSequence })
+		originIdx := -1
+		for i, stopTime := range stopTimes {
+			if stopTime.StopId == query.OriginStopId && originIdx == -1 {
+				originIdx = i
+			} else if stopTime.StopId == query.DestinationStopId && originIdx != -1 {
+				zoneIds := make(map[string]struct{})
+				for _, rideStopTime := range stopTimes[originIdx : i+1] {
+					if stop, ok := stopsById[rideStopTime.StopId]; ok && stop.ZoneId != "" {
+						zoneIds[stop.ZoneId] = struct{}{}
+					}
+				}
+				return sortedKeys(zoneIds), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no trip on route %s goes from stop %s to stop %s", query.RouteId, query.OriginStopId, query.DestinationStopId)
+}
+
+func doesFareApply(rules []*model.FareRule, ride *FareQueryResult) bool {
+	// A fare without any rules applies to every ride
+	if len(rules) == 0 {
+		return true
+	}
+	containsZoneIds := make(map[string]struct{})
+	for _, rule := range rules {
+		if (rule.RouteId != "" && rule.RouteId != ride.RouteId) ||
+			(rule.OriginId != "" && rule.OriginId != ride.OriginZoneId) ||
+			(rule.DestinationId != "" && rule.DestinationId != ride.DestinationZoneId) {
+			continue
+		}
+		if rule.ContainsId == "" {
+			return true
+		}
+		containsZoneIds[rule.ContainsId] = struct{}{}
+	}
+	return len(containsZoneIds) > 0 && strings.Join(sortedKeys(containsZoneIds), ",") == strings.Join(ride.ContainsZoneIds, ",")
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (result *FareQueryResult) PrettyPrint() string {
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "Fares on route %s (%s) from stop %s (%s, zone %s) to stop %s (%s, zone %s)\n", result.RouteId, result.RouteName,
+		result.OriginStopId, result.OriginStopName, result.OriginZoneId, result.DestinationStopId, result.DestinationStopName, result.DestinationZoneId)
+	if len(result.Fares) == 0 {
+		fmt.Fprintf(&builder, "No fares apply\n")
+		return builder.String()
+	}
+	writer := tabwriter.NewWriter(&builder, 0, 0, 0, ' ', tabwriter.Debug)
+	fmt.Fprintf(writer, "FareId\tPrice\tCurrency\tPayment\tTransfers\tTransferDuration\n")
+	for _, fare := range result.Fares {
+		payment := "on board"
+		if fare.PaymentMethod == model.PaidBeforeBoarding {
+			payment = "before boarding"
+		}
+		transfers := "unlimited"
+		if fare.Transfers.Valid {
+			transfers = strconv.Itoa(int(fare.Transfers.V))
+		}
+		transferDuration := ""
+		if fare.TransferDuration.Valid {
+			transferDuration = (time.Duration(fare.TransferDuration.V) * time.Second).String()
+		}
+		fmt.Fprintf(writer, "%s\t%.2f\t%s\t%s\t%s\t%s\n", fare.FareId, fare.Price, fare.CurrencyType, payment, transfers, transferDuration)
+	}
+	writer.Flush()
+	return builder.String()
+}
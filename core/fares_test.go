@@ -0,0 +1,70 @@
+package core
+
+import (
+	"path"
+	"testing"
+
+	"github.com/samc1213/gtfs-analyze/csv_parse"
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindFares(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getGtfsFilesWith(t, "fares")), StaticParseOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(feed.FareAttribute))
+	assert.Equal(t, 5, len(feed.FareRule))
+
+	result, err := FindFares(feed, FareQuery{OriginStopId: "stop1", DestinationStopId: "stop2", RouteId: "route15"})
+	assert.NoError(t, err)
+	assert.Equal(t, "A", result.OriginZoneId)
+	assert.Equal(t, "B", result.DestinationZoneId)
+	assert.Equal(t, []string{"A", "B"}, result.ContainsZoneIds)
+	assert.Equal(t, []ApplicableFare{
+		{FareId: "a_to_b", Price: 2.5, CurrencyType: "USD", PaymentMethod: model.PaidBeforeBoarding, Transfers: csv_parse.NewOptional(model.NoTransfers)},
+		{FareId: "local", Price: 3, CurrencyType: "USD", PaymentMethod: model.PaidOnBoard, TransferDuration: csv_parse.NewOptional(int32(5400))},
+		{FareId: "flat", Price: 10, CurrencyType: "USD", PaymentMethod: model.PaidOnBoard},
+	}, result.Fares)
+
+	// Riding through every zone matches the contains_id rules
+	result, err = FindFares(feed, FareQuery{OriginStopId: "stop1", DestinationStopId: "stop3", RouteId: "route15"})
+	assert.NoError(t, err)
+	fareIds := []string{}
+	for _, fare := range result.Fares {
+		fareIds = append(fareIds, fare.FareId)
+	}
+	assert.Equal(t, []string{"local", "through_abc", "flat"}, fareIds)
+	assert.Contains(t, result.PrettyPrint(), "through_abc|4.00 |USD     |before boarding|1")
+}
+
+func TestFindFaresErrors(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getGtfsFilesWith(t, "fares")), StaticParseOptions{})
+	assert.NoError(t, err)
+
+	_, err = FindFares(feed, FareQuery{OriginStopId: "stop9", DestinationStopId: "stop2", RouteId: "route15"})
+	assert.EqualError(t, err, "stop stop9 does not exist")
+	// The trip goes the other way
+	_, err = FindFares(feed, FareQuery{OriginStopId: "stop3", DestinationStopId: "stop1", RouteId: "route15"})
+	assert.EqualError(t, err, "no trip on route route15 goes from stop stop3 to stop stop1")
+
+	dbPath := path.Join(t.TempDir(), "gtfs.db")
+	_, err = FindFaresForVersion(dbPath, "", "", FareQuery{OriginStopId: "stop1", DestinationStopId: "stop2"}, log.Silent)
+	assert.EqualError(t, err, "no database found at "+dbPath)
+	assert.NoFileExists(t, dbPath)
+}
+
+func TestValidateFares(t *testing.T) {
+	files := getGtfsFilesWith(t, "fares")
+	files["fare_attributes.txt"] += "\nlocal,-1,USD,2,,"
+	files["fare_rules.txt"] += "\nmissing,route9,Z,,"
+	report := ValidateStaticGtfsFiles(createGtfsFiles(files))
+	assert.ElementsMatch(t, []ValidationFinding{
+		{File: "fare_attributes.txt", Line: 6, Severity: SeverityError, Code: DuplicateKey, Message: "duplicate fare_id local"},
+		{File: "fare_attributes.txt", Line: 6, Severity: SeverityError, Code: InvalidRow, Message: "price of fare local is negative"},
+		{File: "fare_attributes.txt", Line: 6, Severity: SeverityError, Code: InvalidEnumValue, Message: "payment_method must be between 0 and 1, found 2"},
+		{File: "fare_rules.txt", Line: 7, Severity: SeverityError, Code: ForeignKeyViolation, Message: "fare_id missing does not exist in fare_attributes.txt"},
+		{File: "fare_rules.txt", Line: 7, Severity: SeverityError, Code: ForeignKeyViolation, Message: "route_id route9 does not exist in routes.txt"},
+		{File: "fare_rules.txt", Line: 7, Severity: SeverityError, Code: ForeignKeyViolation, Message: "origin_id Z is not the zone_id of any stop"},
+	}, report.Findings)
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// How a feed authenticates, besides its headers and query parameters
+type AuthConfig struct {
+	Type     string `yaml:"type" toml:"type"` // "basic" or "bearer"
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+	Token    string `yaml:"token" toml:"token"`
+}
+
+// The resolved headers and query parameters to send with every request of a feed. These hold secrets,
+// so they are only kept in memory
+type feedCredentials struct {
+	headers     map[string]string
+	queryParams map[string]string
+}
+
+// Looks up a value that may be a secret. "env:NAME" is the value of an environment variable and
+// "file:PATH" is the contents of a file, like a mounted secret, without surrounding whitespace.
+// Anything else is the value itself
+func resolveSecret(value string) (string, error) {
+	if strings.HasPrefix(value, "env:") {
+		name := strings.TrimPrefix(value, "env:")
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	}
+	if strings.HasPrefix(value, "file:") {
+		secret, err := os.ReadFile(strings.TrimPrefix(value, "file:"))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(secret)), nil
+	}
+	return value, nil
+}
+
+func resolveSecrets(kind string, values map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(values))
+	for name, value := range values {
+		secret, err := resolveSecret(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", kind, name, err)
+		}
+		resolved[name] = secret
+	}
+	return resolved, nil
+}
+
+func (auth *AuthConfig) validate() error {
+	switch auth.Type {
+	case "":
+		if *auth != (AuthConfig{}) {
+			return errors.New(`auth type must be "basic" or "bearer"`)
+		}
+	case "basic":
+		if auth.Username == "" {
+			return errors.New("auth username is required for basic auth")
+		}
+	case "bearer":
+		if auth.Token == "" {
+			return errors.New("auth token is required for bearer auth")
+		}
+	default:
+		return fmt.Errorf(`auth type must be "basic" or "bearer", found %q`, auth.Type)
+	}
+	return nil
+}
+
+// Resolves the secrets of the feed's headers, query parameters and auth
+func (feed *FeedConfig) credentials() (feedCredentials, error) {
+	headers, err := resolveSecrets("header", feed.Headers)
+	if err != nil {
+		return feedCredentials{}, err
+	}
+	queryParams, err := resolveSecrets("query param", feed.QueryParams)
+	if err != nil {
+		return feedCredentials{}, err
+	}
+
+	switch feed.Auth.Type {
+	case "basic":
+		username, err := resolveSecret(feed.Auth.Username)
+		if err != nil {
+			return feedCredentials{}, fmt.Errorf("auth username: %w", err)
+		}
+		password, err := resolveSecret(feed.Auth.Password)
+		if err != nil {
+			return feedCredentials{}, fmt.Errorf("auth password: %w", err)
+		}
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+	case "bearer":
+		token, err := resolveSecret(feed.Auth.Token)
+		if err != nil {
+			return feedCredentials{}, fmt.Errorf("auth token: %w", err)
+		}
+		headers["Authorization"] = "Bearer " + token
+	}
+	return feedCredentials{headers: headers, queryParams: queryParams}, nil
+}
+
+// Parses flags like "x-api-key=env:API_KEY" into names and values
+func ParseNameValuePairs(pairs []string) (map[string]string, error) {
+	values := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%q must look like name=value", pair)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
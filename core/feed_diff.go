@@ -0,0 +1,295 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/csv_parse"
+	"github.com/samc1213/gtfs-analyze/infra"
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+)
+
+type ModifiedEntity struct {
+	Id            string   `json:"id"`
+	ChangedFields []string `json:"changed_fields"`
+}
+
+type EntityDiff struct {
+	Added    []string         `json:"added"`
+	Removed  []string         `json:"removed"`
+	Modified []ModifiedEntity `json:"modified"`
+}
+
+type StopMove struct {
+	StopId         string  `json:"stop_id"`
+	DistanceMeters float64 `json:"distance_meters"`
+}
+
+type TripCountChange struct {
+	RouteId   string `json:"route_id"`
+	Weekday   string `json:"weekday"`
+	FromCount int    `json:"from_count"`
+	ToCount   int    `json:"to_count"`
+}
+
+type FeedDiff struct {
+	FromVersion      string            `json:"from_version"`
+	ToVersion        string            `json:"to_version"`
+	Stops            EntityDiff        `json:"stops"`
+	Routes           EntityDiff        `json:"routes"`
+	Trips            EntityDiff        `json:"trips"`
+	Calendars        EntityDiff        `json:"calendars"`
+	StopMoves        []StopMove        `json:"stop_moves"`
+	TripCountChanges []TripCountChange `json:"trip_count_changes"`
+}
+
+// Compares two feed versions stored in the database
+func DiffFeedVersions(dbPath string, fromVersion string, toVersion string, stopMoveThresholdMeters float64, logLevel log.Level) (*FeedDiff, error) {
+	logger := log.New(logLevel)
+
+	db, err := openExistingDb(logger, dbPath, logLevel)
+	if err != nil {
+		return nil, err
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlDb.Close()
+
+	fromFeed, err := GetFeedByVersion(fromVersion, db)
+	if err != nil {
+		return nil, err
+	}
+	toFeed, err := GetFeedByVersion(toVersion, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffStaticFeeds(fromFeed, toFeed, stopMoveThresholdMeters), nil
+}
+
+// Compares two local GTFS feeds, each either a zip file or an unzipped directory
+func DiffFeedPaths(fromPath string, toPath string, stopMoveThresholdMeters float64, logLevel log.Level) (*FeedDiff, error) {
+	logger := log.New(logLevel)
+
+	logger.Info("Parsing static GTFS from path: %s", fromPath)
+	fromFeed, err := ParseStaticGtfsFromPath(fromPath, StaticParseOptions{})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Parsing static GTFS from path: %s", toPath)
+	toFeed, err := ParseStaticGtfsFromPath(toPath, StaticParseOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffStaticFeeds(fromFeed, toFeed, stopMoveThresholdMeters), nil
+}
+
+// Reports what changed between two feeds. Stops that moved further than stopMoveThresholdMeters
+// are reported in StopMoves, in addition to being listed as modified
+func DiffStaticFeeds(from *model.GtfsStaticFeed, to *model.GtfsStaticFeed, stopMoveThresholdMeters float64) *FeedDiff {
+	diff := FeedDiff{FromVersion: from.FeedInfo.Version, ToVersion: to.FeedInfo.Version}
+
+	diff.Stops = diffEntities(from.Stop, to.Stop, func(stop *model.Stop) string { return stop.Id })
+	diff.Routes = diffEntities(from.Route, to.Route, func(route *model.Route) string { return route.Id })
+	diff.Trips = diffEntities(from.Trip, to.Trip, func(trip *model.Trip) string { return trip.Id })
+	diff.Calendars = diffEntities(from.Calendar, to.Calendar, func(calendar *model.Calendar) string { return calendar.ServiceId })
+
+	fromStops := make(map[string]*model.Stop, len(from.Stop))
+	for i := range from.Stop {
+		fromStops[from.Stop[i].Id] = &from.Stop[i]
+	}
+	for i := range to.Stop {
+		toStop := &to.Stop[i]
+		fromStop, ok := fromStops[toStop.Id]
+		if !ok || !hasCoordinates(fromStop) || !hasCoordinates(toStop) {
+			continue
+		}
+		distance := infra.DistanceMeters(fromStop.Latitude.V, fromStop.Longitude.V, toStop.Latitude.V, toStop.Longitude.V)
+		if distance > stopMoveThresholdMeters {
+			diff.StopMoves = append(diff.StopMoves, StopMove{StopId: toStop.Id, DistanceMeters: distance})
+		}
+	}
+	sort.Slice(diff.StopMoves, func(i, j int) bool { return diff.StopMoves[i].StopId < diff.StopMoves[j].StopId })
+
+	diff.TripCountChanges = diffTripCounts(countTripsByRouteAndWeekday(from), countTripsByRouteAndWeekday(to))
+
+	return &diff
+}
+
+func hasCoordinates(stop *model.Stop) bool {
+	return stop.Latitude.Valid && stop.Longitude.Valid
+}
+
+func diffEntities[T any](from []T, to []T, getId func(*T) string) EntityDiff {
+	var diff EntityDiff
+	fromById := make(map[string]*T, len(from))
+	for i := range from {
+		fromById[getId(&from[i])] = &from[i]
+	}
+	toIds := make(map[string]struct{}, len(to))
+	for i := range to {
+		id := getId(&to[i])
+		toIds[id] = struct{}{}
+		fromEntity, ok := fromById[id]
+		if !ok {
+			diff.Added = append(diff.Added, id)
+			continue
+		}
+		changedFields := getChangedFields(fromEntity, &to[i])
+		if len(changedFields) > 0 {
+			diff.Modified = append(diff.Modified, ModifiedEntity{Id: id, ChangedFields: changedFields})
+		}
+	}
+	for id := range fromById {
+		if _, ok := toIds[id]; !ok {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.Modified, func(i, j int) bool { return diff.Modified[i].Id < diff.Modified[j].Id })
+	return diff
+}
+
+// Lists the GTFS columns that differ between two entities. The Version and associations
+// are not columns in the GTFS file, so they are ignored
+func getChangedFields[T any](from *T, to *T) []string {
+	var changedFields []string
+	fromValue := reflect.ValueOf(from).Elem()
+	toValue := reflect.ValueOf(to).Elem()
+	for i := 0; i < fromValue.NumField(); i++ {
+		csvName := strings.Split(fromValue.Type().Field(i).Tag.Get("csv_parse"), ";")[0]
+		if csvName == "" {
+			continue
+		}
+		if csvName == csv_parse.ExtraColumnsTag {
+			changedFields = append(changedFields, getChangedExtraColumns(fromValue.Field(i).Interface().(map[string]string), toValue.Field(i).Interface().(map[string]string))...)
+		} else if !reflect.DeepEqual(fromValue.Field(i).Interface(), toValue.Field(i).Interface()) {
+			changedFields = append(changedFields, csvName)
+		}
+	}
+	return changedFields
+}
+
+func getChangedExtraColumns(from map[string]string, to map[string]string) []string {
+	var changedColumns []string
+	for columnName, fromValue := range from {
+		if toValue, ok := to[columnName]; !ok || toValue != fromValue {
+			changedColumns = append(changedColumns, columnName)
+		}
+	}
+	for columnName := range to {
+		if _, ok := from[columnName]; !ok {
+			changedColumns = append(changedColumns, columnName)
+		}
+	}
+	sort.Strings(changedColumns)
+	return changedColumns
+}
+
+type routeWeekday struct {
+	routeId string
+	weekday time.Weekday
+}
+
+func countTripsByRouteAndWeekday(feed *model.GtfsStaticFeed) map[routeWeekday]int {
+	calendarByServiceId := make(map[string]*model.Calendar, len(feed.Calendar))
+	for i := range feed.Calendar {
+		calendarByServiceId[feed.Calendar[i].ServiceId] = &feed.Calendar[i]
+	}
+
+	counts := make(map[routeWeekday]int)
+	for _, trip := range feed.Trip {
+		calendar, ok := calendarByServiceId[trip.ServiceId]
+		if !ok {
+			continue
+		}
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if isServiceAvailableOnWeekday(weekday, calendar) {
+				counts[routeWeekday{routeId: trip.RouteId, weekday: weekday}] += 1
+			}
+		}
+	}
+	return counts
+}
+
+func diffTripCounts(fromCounts map[routeWeekday]int, toCounts map[routeWeekday]int) []TripCountChange {
+	keys := make(map[routeWeekday]struct{})
+	for key := range fromCounts {
+		keys[key] = struct{}{}
+	}
+	for key := range toCounts {
+		keys[key] = struct{}{}
+	}
+
+	var sortedKeys []routeWeekday
+	for key := range keys {
+		if fromCounts[key] != toCounts[key] {
+			sortedKeys = append(sortedKeys, key)
+		}
+	}
+	sort.Slice(sortedKeys, func(i, j int) bool {
+		if sortedKeys[i].routeId != sortedKeys[j].routeId {
+			return sortedKeys[i].routeId < sortedKeys[j].routeId
+		}
+		return sortedKeys[i].weekday < sortedKeys[j].weekday
+	})
+
+	changes := make([]TripCountChange, len(sortedKeys))
+	for i, key := range sortedKeys {
+		changes[i] = TripCountChange{RouteId: key.routeId, Weekday: key.weekday.String(), FromCount: fromCounts[key], ToCount: toCounts[key]}
+	}
+	return changes
+}
+
+func (diff *FeedDiff) PrettyPrint() string {
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "Changes from version %s to version %s\n", diff.FromVersion, diff.ToVersion)
+	prettyPrintEntityDiff(&builder, "Stops", &diff.Stops)
+	prettyPrintEntityDiff(&builder, "Routes", &diff.Routes)
+	prettyPrintEntityDiff(&builder, "Trips", &diff.Trips)
+	prettyPrintEntityDiff(&builder, "Calendars", &diff.Calendars)
+
+	fmt.Fprintf(&builder, "\nStop moves (%d)\n", len(diff.StopMoves))
+	if len(diff.StopMoves) > 0 {
+		writer := tabwriter.NewWriter(&builder, 0, 0, 0, ' ', tabwriter.Debug)
+		fmt.Fprintf(writer, "StopId\tMeters\n")
+		for _, move := range diff.StopMoves {
+			fmt.Fprintf(writer, "%s\t%.1f\n", move.StopId, move.DistanceMeters)
+		}
+		writer.Flush()
+	}
+
+	fmt.Fprintf(&builder, "\nScheduled trip count changes (%d)\n", len(diff.TripCountChanges))
+	if len(diff.TripCountChanges) > 0 {
+		writer := tabwriter.NewWriter(&builder, 0, 0, 0, ' ', tabwriter.Debug)
+		fmt.Fprintf(writer, "RouteId\tWeekday\tFrom\tTo\n")
+		for _, change := range diff.TripCountChanges {
+			fmt.Fprintf(writer, "%s\t%s\t%d\t%d\n", change.RouteId, change.Weekday, change.FromCount, change.ToCount)
+		}
+		writer.Flush()
+	}
+	return builder.String()
+}
+
+func prettyPrintEntityDiff(builder *strings.Builder, name string, diff *EntityDiff) {
+	fmt.Fprintf(builder, "\n%s: %d added, %d removed, %d modified\n", name, len(diff.Added), len(diff.Removed), len(diff.Modified))
+	for _, id := range diff.Added {
+		fmt.Fprintf(builder, "  + %s\n", id)
+	}
+	for _, id := range diff.Removed {
+		fmt.Fprintf(builder, "  - %s\n", id)
+	}
+	for _, modified := range diff.Modified {
+		fmt.Fprintf(builder, "  ~ %s (%s)\n", modified.Id, strings.Join(modified.ChangedFields, ", "))
+	}
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"path"
+	"testing"
+
+	"github.com/samc1213/gtfs-analyze/csv_parse"
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffStaticFeeds(t *testing.T) {
+	from, tripOneId, stopOneId, stopTwoId, _ := createStaticFeed()
+	from.FeedInfo.Version = "v1"
+	from.Stop = []model.Stop{
+		{Id: stopOneId, Name: "Union Station", Latitude: csv_parse.NewOptional(39.7525), Longitude: csv_parse.NewOptional(-105.0001)},
+		{Id: stopTwoId, Name: "Civic Center", Latitude: csv_parse.NewOptional(39.7392), Longitude: csv_parse.NewOptional(-104.9875)},
+	}
+
+	to, _, _, _, _ := createStaticFeed()
+	to.FeedInfo.Version = "v2"
+	to.Stop = []model.Stop{
+		// Moved roughly 111 meters north, and renamed
+		{Id: stopOneId, Name: "Denver Union Station", Latitude: csv_parse.NewOptional(39.7535), Longitude: csv_parse.NewOptional(-105.0001), Extra: map[string]string{"platform_code": "A"}},
+		{Id: "stop3", Name: "Colfax", Latitude: csv_parse.NewOptional(39.7400), Longitude: csv_parse.NewOptional(-104.9800)},
+	}
+	to.Trip = append(to.Trip, model.Trip{Id: "trip2", RouteId: "route15", ServiceId: to.Calendar[0].ServiceId})
+	to.Calendar[0].Saturday = model.ServiceIsAvailable
+
+	diff := DiffStaticFeeds(from, to, 50)
+	assert.Equal(t, "v1", diff.FromVersion)
+	assert.Equal(t, "v2", diff.ToVersion)
+
+	assert.Equal(t, []string{"stop3"}, diff.Stops.Added)
+	assert.Equal(t, []string{stopTwoId}, diff.Stops.Removed)
+	assert.Equal(t, []ModifiedEntity{{Id: stopOneId, ChangedFields: []string{"stop_name", "stop_lat", "platform_code"}}}, diff.Stops.Modified)
+
+	assert.Equal(t, []string{"trip2"}, diff.Trips.Added)
+	assert.Empty(t, diff.Trips.Removed)
+	assert.Empty(t, diff.Trips.Modified)
+	assert.Empty(t, diff.Routes.Added)
+	assert.NotContains(t, diff.Trips.Added, tripOneId)
+
+	assert.Equal(t, []ModifiedEntity{{Id: "wkdayService", ChangedFields: []string{"saturday"}}}, diff.Calendars.Modified)
+
+	assert.Equal(t, 1, len(diff.StopMoves))
+	assert.Equal(t, stopOneId, diff.StopMoves[0].StopId)
+	assert.InDelta(t, 111, diff.StopMoves[0].DistanceMeters, 1)
+
+	// Weekdays went from 1 to 2 trips, and Saturday from 0 to 2
+	assert.Equal(t, 6, len(diff.TripCountChanges))
+	assert.Contains(t, diff.TripCountChanges, TripCountChange{RouteId: "route15", Weekday: "Monday", FromCount: 1, ToCount: 2})
+	assert.Contains(t, diff.TripCountChanges, TripCountChange{RouteId: "route15", Weekday: "Saturday", FromCount: 0, ToCount: 2})
+	assert.NotContains(t, diff.TripCountChanges, TripCountChange{RouteId: "route15", Weekday: "Sunday", FromCount: 0, ToCount: 0})
+
+	assert.Contains(t, diff.PrettyPrint(), "Stops: 1 added, 1 removed, 1 modified")
+}
+
+func TestDiffMissingDatabase(t *testing.T) {
+	dbPath := path.Join(t.TempDir(), "gtfs.db")
+	_, err := DiffFeedVersions(dbPath, "v1", "v2", 100, log.Silent)
+	assert.EqualError(t, err, "no database found at "+dbPath)
+	assert.NoFileExists(t, dbPath)
+}
@@ -0,0 +1,196 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// How a fetch is retried after a network error or a 429 or 5xx response. Each retry waits a random
+// time up to a backoff that doubles after every attempt, so pollers of one server don't retry in step
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+var noRetries = retryPolicy{maxAttempts: 1}
+
+// The backoff before the retry after attempt, which starts at 1, without jitter
+func (policy *retryPolicy) backoff(attempt int) time.Duration {
+	backoff := policy.initialBackoff
+	for i := 1; i < attempt && backoff < policy.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > policy.maxBackoff {
+		return policy.maxBackoff
+	}
+	return backoff
+}
+
+// Downloads feeds over HTTP, sending the same credentials, like an API key, with every request
+type feedFetcher struct {
+	client      *http.Client
+	credentials feedCredentials
+	retry       retryPolicy
+}
+
+func newFeedFetcher(timeout time.Duration, credentials feedCredentials) *feedFetcher {
+	return &feedFetcher{client: &http.Client{Timeout: timeout}, credentials: credentials, retry: noRetries}
+}
+
+// A fetch that failed, either without a response or with a response other than 200 OK
+type httpFetchError struct {
+	url        string
+	statusCode int // 0 when there was no response
+	err        error
+}
+
+func (err *httpFetchError) Error() string {
+	if err.statusCode == 0 {
+		return err.err.Error()
+	}
+	return fmt.Sprintf("Non-successful response from uri %s: %s", err.url, http.StatusText(err.statusCode))
+}
+
+func (err *httpFetchError) Unwrap() error {
+	return err.err
+}
+
+// Whether the same request could succeed later. Other 4xx responses, like 404, won't fix themselves
+func (err *httpFetchError) isRetryable() bool {
+	return err.statusCode == 0 || err.statusCode == http.StatusTooManyRequests || err.statusCode >= 500
+}
+
+// The validators of a response, which a later request can send to only download the feed if it changed
+type cacheValidators struct {
+	etag         string
+	lastModified string
+}
+
+type fetchResponse struct {
+	body        []byte
+	validators  cacheValidators
+	notModified bool // The server responded 304 Not Modified, so there is no body
+}
+
+type fetchStats struct {
+	statusCode int // Of the last attempt
+	attempts   int
+}
+
+// Downloads url. Cancelling ctx abandons the download
+func (fetcher *feedFetcher) fetch(ctx context.Context, url string) ([]byte, error) {
+	body, _, err := fetcher.fetchWithStats(ctx, url)
+	return body, err
+}
+
+// Downloads url, retrying by the fetcher's retry policy. Failures are *httpFetchError
+func (fetcher *feedFetcher) fetchWithStats(ctx context.Context, url string) ([]byte, fetchStats, error) {
+	response, stats, err := fetcher.fetchIfModified(ctx, url, cacheValidators{})
+	return response.body, stats, err
+}
+
+// Downloads url unless it hasn't changed since the response with these validators. With empty
+// validators, always downloads
+func (fetcher *feedFetcher) fetchIfModified(ctx context.Context, url string, validators cacheValidators) (fetchResponse, fetchStats, error) {
+	var stats fetchStats
+	for {
+		stats.attempts++
+		response, err := fetcher.fetchOnce(ctx, url, validators)
+		if err == nil {
+			stats.statusCode = http.StatusOK
+			if response.notModified {
+				stats.statusCode = http.StatusNotModified
+			}
+			return response, stats, nil
+		}
+		stats.statusCode = err.statusCode
+		if !err.isRetryable() || stats.attempts >= fetcher.retry.maxAttempts || ctx.Err() != nil {
+			return fetchResponse{}, stats, err
+		}
+
+		backoff := fetcher.retry.backoff(stats.attempts)
+		wait := time.Duration(rand.Int63n(int64(backoff) + 1))
+		select {
+		case <-ctx.Done():
+			return fetchResponse{}, stats, err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Adds the query parameters of the credentials to url. The result holds secrets, so only url is
+// ever logged or stored
+func (fetcher *feedFetcher) getRequestUrl(feedUrl string) (string, error) {
+	if len(fetcher.credentials.queryParams) == 0 {
+		return feedUrl, nil
+	}
+	parsedUrl, err := url.Parse(feedUrl)
+	if err != nil {
+		return "", err
+	}
+	query := parsedUrl.Query()
+	for name, value := range fetcher.credentials.queryParams {
+		query.Set(name, value)
+	}
+	parsedUrl.RawQuery = query.Encode()
+	return parsedUrl.String(), nil
+}
+
+func (fetcher *feedFetcher) fetchOnce(ctx context.Context, feedUrl string, validators cacheValidators) (fetchResponse, *httpFetchError) {
+	requestUrl, err := fetcher.getRequestUrl(feedUrl)
+	if err != nil {
+		return fetchResponse{}, &httpFetchError{url: feedUrl, err: err}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return fetchResponse{}, &httpFetchError{url: feedUrl, err: err}
+	}
+	for name, value := range fetcher.credentials.headers {
+		req.Header.Set(name, value)
+	}
+	if validators.etag != "" {
+		req.Header.Set("If-None-Match", validators.etag)
+	}
+	if validators.lastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.lastModified)
+	}
+
+	resp, err := fetcher.client.Do(req)
+	if err != nil {
+		// The error includes the url it requested, which can have secrets in its query
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = feedUrl
+		}
+		return fetchResponse{}, &httpFetchError{url: feedUrl, err: err}
+	}
+	defer resp.Body.Close()
+
+	response := fetchResponse{validators: cacheValidators{etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}}
+	if resp.StatusCode == http.StatusNotModified && validators != (cacheValidators{}) {
+		response.notModified = true
+		// A 304 can leave out validators that haven't changed
+		if response.validators.etag == "" {
+			response.validators.etag = validators.etag
+		}
+		if response.validators.lastModified == "" {
+			response.validators.lastModified = validators.lastModified
+		}
+		return response, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fetchResponse{}, &httpFetchError{url: feedUrl, statusCode: resp.StatusCode}
+	}
+	response.body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return fetchResponse{}, &httpFetchError{url: feedUrl, err: err}
+	}
+	return response, nil
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case r.URL.Path == "/missing.pb":
+			w.WriteHeader(http.StatusNotFound)
+		case requests <= 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("feed"))
+		}
+	}))
+	defer server.Close()
+
+	fetcher := newFeedFetcher(time.Second, feedCredentials{})
+	fetcher.retry = retryPolicy{maxAttempts: 3, initialBackoff: time.Millisecond, maxBackoff: time.Millisecond}
+	body, stats, err := fetcher.fetchWithStats(context.Background(), server.URL+"/feed.pb")
+	assert.NoError(t, err)
+	assert.Equal(t, "feed", string(body))
+	assert.Equal(t, fetchStats{statusCode: http.StatusOK, attempts: 3}, stats)
+
+	// A missing feed isn't retried
+	_, stats, err = fetcher.fetchWithStats(context.Background(), server.URL+"/missing.pb")
+	assert.EqualError(t, err, "Non-successful response from uri "+server.URL+"/missing.pb: Not Found")
+	assert.Equal(t, fetchStats{statusCode: http.StatusNotFound, attempts: 1}, stats)
+
+	// Gives up after the last attempt
+	requests = 0
+	fetcher.retry.maxAttempts = 2
+	_, stats, err = fetcher.fetchWithStats(context.Background(), server.URL+"/feed.pb")
+	assert.Error(t, err)
+	assert.Equal(t, fetchStats{statusCode: http.StatusServiceUnavailable, attempts: 2}, stats)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := retryPolicy{maxAttempts: 10, initialBackoff: time.Second, maxBackoff: 30 * time.Second}
+	assert.Equal(t, time.Second, policy.backoff(1))
+	assert.Equal(t, 2*time.Second, policy.backoff(2))
+	assert.Equal(t, 16*time.Second, policy.backoff(5))
+	assert.Equal(t, 30*time.Second, policy.backoff(6))
+	assert.Equal(t, 30*time.Second, policy.backoff(100))
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"path"
+	"testing"
+
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteRealtimeUpdatesReplacesEntities(t *testing.T) {
+	db, err := InitializeSqliteDatabase(path.Join(t.TempDir(), "gtfs.db"), log.Silent)
+	assert.NoError(t, err)
+
+	// Entity ids are often reused by later messages, like one id per vehicle
+	for _, namespace := range []string{"", "rtd"} {
+		for _, messageTimestamp := range []uint64{1685973600, 1685973630} {
+			assert.NoError(t, WriteRealTimePositionUpdateToDatabase([]model.VehiclePosition{{Namespace: namespace, Id: "bus1", MessageTimestamp: messageTimestamp}}, db))
+			assert.NoError(t, WriteTripUpdatesToDatabase([]model.TripUpdate{{Namespace: namespace, Id: "trip1", MessageTimestamp: messageTimestamp}}, db))
+			assert.NoError(t, WriteAlertsToDatabase([]model.Alert{{Namespace: namespace, Id: "alert1", MessageTimestamp: messageTimestamp}}, db))
+		}
+	}
+
+	var vehiclePositions []model.VehiclePosition
+	assert.NoError(t, db.Order("namespace").Find(&vehiclePositions).Error)
+	assert.Equal(t, 2, len(vehiclePositions))
+	assert.EqualValues(t, 1685973630, vehiclePositions[0].MessageTimestamp)
+	var count int64
+	assert.NoError(t, db.Model(&model.TripUpdate{}).Count(&count).Error)
+	assert.EqualValues(t, 2, count)
+	assert.NoError(t, db.Model(&model.Alert{}).Count(&count).Error)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestWriteShapes(t *testing.T) {
+	files := getValidGtfsFiles()
+	files["shapes.txt"] = "shape_id,shape_pt_lat,shape_pt_lon,shape_pt_sequence,shape_dist_traveled\n" +
+		"shape15,39.7392,-104.9875,2,1.8\n" +
+		"shape15,39.7525,-105.0001,1,0"
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(files), StaticParseOptions{})
+	assert.NoError(t, err)
+	db, err := InitializeSqliteDatabase(path.Join(t.TempDir(), "gtfs.db"), log.Silent)
+	assert.NoError(t, err)
+	assert.NoError(t, WriteStaticGtfsFeedToDatabase(feed, db))
+
+	storedFeed, err := GetFeedByVersion(feed.FeedInfo.Version, db)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(storedFeed.ShapePoint))
+	// In the order of the shape
+	assert.EqualValues(t, 1, storedFeed.ShapePoint[0].Sequence)
+	assert.Equal(t, 39.7525, storedFeed.ShapePoint[0].Latitude)
+	assert.Equal(t, 1.8, storedFeed.ShapePoint[1].DistTraveled.V)
+}
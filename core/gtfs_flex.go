@@ -0,0 +1,114 @@
+package core
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/samc1213/gtfs-analyze/model"
+)
+
+type geoJsonFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJsonFeature `json:"features"`
+}
+
+type geoJsonFeature struct {
+	Type       string                     `json:"type"`
+	Id         json.RawMessage            `json:"id,omitempty"`
+	Properties map[string]json.RawMessage `json:"properties"`
+	Geometry   json.RawMessage            `json:"geometry"`
+}
+
+// Parses the zones of locations.geojson. Features without an id are kept with an empty id, so the
+// validator can report them
+func decodeLocationsGeoJson(fileObj io.Reader) ([]model.Location, error) {
+	var collection geoJsonFeatureCollection
+	if err := json.NewDecoder(fileObj).Decode(&collection); err != nil {
+		return nil, err
+	}
+	if collection.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("type must be FeatureCollection, found %q", collection.Type)
+	}
+
+	locations := make([]model.Location, 0, len(collection.Features))
+	for _, feature := range collection.Features {
+		location := model.Location{Geometry: string(feature.Geometry)}
+		// GeoJSON allows numeric ids, which are kept as their text
+		if len(feature.Id) > 0 && json.Unmarshal(feature.Id, &location.Id) != nil {
+			location.Id = string(feature.Id)
+		}
+		for name, value := range feature.Properties {
+			switch name {
+			case "stop_name":
+				json.Unmarshal(value, &location.Name)
+			case "stop_desc":
+				json.Unmarshal(value, &location.Description)
+			default:
+				if location.Extra == nil {
+					location.Extra = make(map[string]json.RawMessage)
+				}
+				location.Extra[name] = value
+			}
+		}
+		locations = append(locations, location)
+	}
+	return locations, nil
+}
+
+func parseLocationsGeoJson(name string, fileObj io.Reader) ([]model.Location, error) {
+	locations, err := decodeLocationsGeoJson(fileObj)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	for i, location := range locations {
+		if location.Id == "" {
+			return nil, fmt.Errorf("%s: feature %d has no id", name, i+1)
+		}
+	}
+	return locations, nil
+}
+
+// Returns the type of a GeoJSON geometry, like Polygon, or an empty string if it isn't a geometry
+func getGeometryType(geometry string) string {
+	var typed struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal([]byte(geometry), &typed) != nil {
+		return ""
+	}
+	return typed.Type
+}
+
+func writeLocationsGeoJson(archive *zip.Writer, locations []model.Location) error {
+	collection := geoJsonFeatureCollection{Type: "FeatureCollection", Features: make([]geoJsonFeature, 0, len(locations))}
+	for _, location := range locations {
+		id, err := json.Marshal(location.Id)
+		if err != nil {
+			return err
+		}
+		properties := make(map[string]json.RawMessage, len(location.Extra)+2)
+		for name, value := range location.Extra {
+			properties[name] = value
+		}
+		if location.Name != "" {
+			properties["stop_name"], _ = json.Marshal(location.Name)
+		}
+		if location.Description != "" {
+			properties["stop_desc"], _ = json.Marshal(location.Description)
+		}
+		collection.Features = append(collection.Features, geoJsonFeature{
+			Type:       "Feature",
+			Id:         id,
+			Properties: properties,
+			Geometry:   json.RawMessage(location.Geometry),
+		})
+	}
+
+	fileWriter, err := archive.Create("locations.geojson")
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(fileWriter).Encode(collection)
+}
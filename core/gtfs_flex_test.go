@@ -0,0 +1,110 @@
+package core
+
+import (
+	"encoding/json"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/csv_parse"
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// The geometry of the downtown zone in test_files/flex/locations.geojson
+const flexZoneGeometry = `{"type":"Polygon","coordinates":[[[-105.01,39.75],[-104.98,39.75],[-104.98,39.73],[-105.01,39.73],[-105.01,39.75]]]}`
+
+func TestParseFlex(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getGtfsFilesWith(t, "flex")), StaticParseOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Location{{
+		Version:  feed.FeedInfo.Version,
+		Id:       "downtown",
+		Name:     "Downtown Denver",
+		Geometry: flexZoneGeometry,
+		Extra:    map[string]json.RawMessage{"zone_color": json.RawMessage(`"blue"`)},
+	}}, feed.Location)
+	assert.Equal(t, 1, len(feed.LocationGroup))
+	assert.Equal(t, 2, len(feed.LocationGroupStop))
+	assert.Equal(t, model.PriorDaysBooking, feed.BookingRule[0].BookingType)
+	assert.Equal(t, csv_parse.NewOptional(model.ArrivalDepartureTime(17*60*60)), feed.BookingRule[0].PriorNoticeLastTime)
+
+	flexStopTime := feed.StopTime[3]
+	assert.Equal(t, "", flexStopTime.StopId)
+	assert.Equal(t, "downtown", flexStopTime.LocationId)
+	assert.Equal(t, csv_parse.NewOptional(model.ArrivalDepartureTime(7*60*60)), flexStopTime.StartPickupDropOffWindow)
+	assert.Equal(t, csv_parse.NewOptional(model.ArrivalDepartureTime(19*60*60)), flexStopTime.EndPickupDropOffWindow)
+	assert.Equal(t, "next_day", flexStopTime.DropOffBookingRuleId)
+	assert.Empty(t, ValidateStaticGtfsFiles(createGtfsFiles(getGtfsFilesWith(t, "flex"))).Findings)
+
+	// A feature without an id can't be stored
+	files := getGtfsFilesWith(t, "flex")
+	files["locations.geojson"] = `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":` + flexZoneGeometry + `}]}`
+	_, err = parseStaticGtfsFromFiles(createGtfsFiles(files), StaticParseOptions{})
+	assert.EqualError(t, err, "locations.geojson: feature 1 has no id")
+}
+
+func TestExportFlexRoundTrip(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getGtfsFilesWith(t, "flex")), StaticParseOptions{})
+	assert.NoError(t, err)
+
+	dbPath := path.Join(t.TempDir(), "gtfs.db")
+	db, err := InitializeSqliteDatabase(dbPath, log.Silent)
+	assert.NoError(t, err)
+	assert.NoError(t, WriteStaticGtfsFeedToDatabase(feed, db))
+
+	zipPath := path.Join(t.TempDir(), "google_transit.zip")
+	assert.NoError(t, ExportStaticGtfsToPath(dbPath, feed.FeedInfo.Version, zipPath, log.Silent))
+	exported, err := ParseStaticGtfsFromPath(zipPath, StaticParseOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "downtown", exported.Location[0].Id)
+	assert.Equal(t, "Downtown Denver", exported.Location[0].Name)
+	assert.JSONEq(t, flexZoneGeometry, exported.Location[0].Geometry)
+	assert.Equal(t, feed.Location[0].Extra, exported.Location[0].Extra)
+	assert.Equal(t, feed.LocationGroupStop[1].StopId, exported.LocationGroupStop[1].StopId)
+	assert.Equal(t, feed.BookingRule[0].PhoneNumber, exported.BookingRule[0].PhoneNumber)
+	assert.ElementsMatch(t, []string{"", "", "downtown_stops", ""}, []string{
+		exported.StopTime[0].LocationGroupId, exported.StopTime[1].LocationGroupId, exported.StopTime[2].LocationGroupId, exported.StopTime[3].LocationGroupId,
+	})
+}
+
+func TestOtpSkipsDemandResponsiveTrips(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getGtfsFilesWith(t, "flex")), StaticParseOptions{})
+	assert.NoError(t, err)
+	calculation, err := CreateOtpCalculation(feed)
+	assert.NoError(t, err)
+	logger := log.New(log.Info)
+	tripDate := time.Date(2023, 6, 5, 0, 0, 0, 0, calculation.Location)
+
+	simulateStop(tripDate, 8*time.Hour+31*time.Minute, "trip1", "stop1", calculation, logger)
+	simulateStop(tripDate, 8*time.Hour+31*time.Minute, "flex1", "stop1", calculation, logger)
+	summary := calculation.SummarizeOnTimePerformanceByTrip(7*time.Minute, tripDate, tripDate.Add(24*time.Hour), logger)
+	assert.Equal(t, []OtpSummaryEntry{{Name: "trip1", OnTimePerformance: 0.5}}, summary.OtpSummaries)
+}
+
+func TestValidateFlex(t *testing.T) {
+	files := getGtfsFilesWith(t, "flex")
+	files["stop_times.txt"] += "\n" +
+		"flex1,,,stop1,downtown_stops,,3,07:00:00,19:00:00,,\n" +
+		"flex1,,,,,downtown,4,,,,\n" +
+		"flex1,08:00:00,,,,uptown,5,19:00:00,07:00:00,,same_day"
+	files["booking_rules.txt"] += "\nsame_day_rule,1,,,"
+	files["location_group_stops.txt"] += "\ndowntown_stops,stop9"
+	files["locations.geojson"] = `{"type":"FeatureCollection","features":[` +
+		`{"type":"Feature","id":"downtown","properties":{},"geometry":` + flexZoneGeometry + `},` +
+		`{"type":"Feature","id":"stop1","properties":{},"geometry":{"type":"Point","coordinates":[-105.0,39.7]}}]}`
+	report := ValidateStaticGtfsFiles(createGtfsFiles(files))
+	assert.ElementsMatch(t, []ValidationFinding{
+		{File: "booking_rules.txt", Line: 3, Severity: SeverityError, Code: MissingRequiredValue, Message: "prior_notice_duration_min is required for same-day booking"},
+		{File: "location_group_stops.txt", Line: 4, Severity: SeverityError, Code: ForeignKeyViolation, Message: "stop_id stop9 does not exist in stops.txt"},
+		{File: "locations.geojson", EntityId: "stop1", Severity: SeverityError, Code: DuplicateKey, Message: "id stop1 is also a stop_id in stops.txt"},
+		{File: "locations.geojson", EntityId: "stop1", Severity: SeverityError, Code: InvalidRow, Message: `geometry must be a Polygon or MultiPolygon, found "Point"`},
+		{File: "stop_times.txt", Line: 6, Severity: SeverityError, Code: InvalidRow, Message: "only one of stop_id, location_group_id or location_id can be set"},
+		{File: "stop_times.txt", Line: 7, Severity: SeverityError, Code: MissingRequiredValue, Message: "start_pickup_drop_off_window and end_pickup_drop_off_window are required for a location group or location"},
+		{File: "stop_times.txt", Line: 8, Severity: SeverityError, Code: ForeignKeyViolation, Message: "location_id uptown does not exist in locations.geojson"},
+		{File: "stop_times.txt", Line: 8, Severity: SeverityError, Code: ForeignKeyViolation, Message: "drop_off_booking_rule_id same_day does not exist in booking_rules.txt"},
+		{File: "stop_times.txt", Line: 8, Severity: SeverityError, Code: InvalidRow, Message: "end_pickup_drop_off_window is before start_pickup_drop_off_window for trip flex1"},
+		{File: "stop_times.txt", Line: 8, Severity: SeverityError, Code: InvalidRow, Message: "arrival_time and departure_time must be empty when there is a pickup and drop off window"},
+	}, report.Findings)
+}
@@ -1,9 +1,8 @@
 package core
 
 import (
+	"context"
 	"errors"
-	"io"
-	"net/http"
 	"time"
 
 	"github.com/samc1213/gtfs-analyze/gtfs_realtime"
@@ -12,8 +11,7 @@ import (
 )
 
 func ParseRtGtfsFromUrl(vehiclePositionUrl string) ([]model.VehiclePosition, error) {
-	client := http.Client{Timeout: 15 * time.Second}
-	protoBytes, err := parseProtoFromUrl(&client, vehiclePositionUrl)
+	protoBytes, err := newFeedFetcher(rtFetchTimeout, feedCredentials{}).fetch(context.Background(), vehiclePositionUrl)
 	if err != nil {
 		return nil, err
 	}
@@ -25,18 +23,41 @@ func ParseRtGtfsFromUrl(vehiclePositionUrl string) ([]model.VehiclePosition, err
 	return vehiclePosition, nil
 }
 
+const rtFetchTimeout = 15 * time.Second
+
 func convertVehiclePositionProtoToModel(protoBytes []byte) ([]model.VehiclePosition, error) {
-	var vehiclePositionProto gtfs_realtime.FeedMessage
-	proto.Unmarshal(protoBytes, &vehiclePositionProto)
+	vehiclePositionProto, err := unmarshalFeedMessage(protoBytes)
+	if err != nil {
+		return nil, err
+	}
+	return convertFeedMessageToModel(vehiclePositionProto)
+}
 
-	vehiclePositions := make([]model.VehiclePosition, len(vehiclePositionProto.Entity))
+func unmarshalFeedMessage(protoBytes []byte) (*gtfs_realtime.FeedMessage, error) {
+	var feedMessage gtfs_realtime.FeedMessage
+	err := proto.Unmarshal(protoBytes, &feedMessage)
+	if err != nil {
+		return nil, err
+	}
+	return &feedMessage, nil
+}
+
+// Converts the vehicle positions of a feed message. Entities of other types are skipped, like they
+// are by convertTripUpdatesToModel
+func convertFeedMessageToModel(vehiclePositionProto *gtfs_realtime.FeedMessage) ([]model.VehiclePosition, error) {
+	if vehiclePositionProto.Header == nil {
+		return nil, errors.New("feed message is missing its header")
+	}
 
-	for i, entity := range vehiclePositionProto.Entity {
+	vehiclePositions := make([]model.VehiclePosition, 0, len(vehiclePositionProto.Entity))
+
+	for _, entity := range vehiclePositionProto.Entity {
 		if entity.Vehicle == nil {
-			return nil, errors.New("vehicle should not be null for vehicle position update")
+			continue
 		}
-		vehiclePosition := &vehiclePositions[i]
-		vehiclePosition.MessageTimestamp = *vehiclePositionProto.Header.Timestamp
+		vehiclePositions = append(vehiclePositions, model.VehiclePosition{})
+		vehiclePosition := &vehiclePositions[len(vehiclePositions)-1]
+		vehiclePosition.MessageTimestamp = vehiclePositionProto.Header.GetTimestamp()
 
 		vehicle := entity.Vehicle
 
@@ -77,17 +98,124 @@ func convertVehiclePositionProtoToModel(protoBytes []byte) ([]model.VehiclePosit
 	return vehiclePositions, nil
 }
 
-func parseProtoFromUrl(client *http.Client, url string) ([]byte, error) {
-	protoResp, err := client.Get(url)
+func convertTripUpdateProtoToModel(protoBytes []byte) ([]model.TripUpdate, error) {
+	feedMessage, err := unmarshalFeedMessage(protoBytes)
 	if err != nil {
 		return nil, err
 	}
-	defer protoResp.Body.Close()
+	return convertTripUpdatesToModel(feedMessage)
+}
 
-	protoBytes, err := io.ReadAll(protoResp.Body)
+// Converts the trip updates of a feed message. Entities of other types are skipped, since some
+// producers publish every entity type in one feed
+func convertTripUpdatesToModel(feedMessage *gtfs_realtime.FeedMessage) ([]model.TripUpdate, error) {
+	if feedMessage.Header == nil {
+		return nil, errors.New("feed message is missing its header")
+	}
+
+	var tripUpdates []model.TripUpdate
+	for _, entity := range feedMessage.Entity {
+		update := entity.GetTripUpdate()
+		if update == nil {
+			continue
+		}
+		tripUpdate := model.TripUpdate{
+			Id:                   entity.GetId(),
+			MessageTimestamp:     feedMessage.Header.GetTimestamp(),
+			TripId:               update.Trip.GetTripId(),
+			RouteId:              update.Trip.GetRouteId(),
+			DirectionId:          model.DirectionId(update.Trip.GetDirectionId()),
+			ScheduleRelationship: model.ScheduleRelationship(update.Trip.GetScheduleRelationship().Number()),
+			VehicleId:            update.Vehicle.GetId(),
+			Timestamp:            update.GetTimestamp(),
+			Delay:                update.GetDelay(),
+		}
+		tripUpdate.StartTime.ConvertFromCsv(update.Trip.GetStartTime())
+		if update.Trip.GetStartDate() != "" {
+			startDate, err := time.Parse("20060102", update.Trip.GetStartDate())
+			if err != nil {
+				return nil, err
+			}
+			tripUpdate.StartDate = startDate
+		}
+
+		for _, stopTimeUpdate := range update.GetStopTimeUpdate() {
+			tripUpdate.StopTimeUpdates = append(tripUpdate.StopTimeUpdates, model.StopTimeUpdate{
+				StopSequence:         stopTimeUpdate.GetStopSequence(),
+				StopId:               stopTimeUpdate.GetStopId(),
+				Arrival:              convertStopTimeEventToModel(stopTimeUpdate.GetArrival()),
+				Departure:            convertStopTimeEventToModel(stopTimeUpdate.GetDeparture()),
+				ScheduleRelationship: model.StopTimeUpdateScheduleRelationship(stopTimeUpdate.GetScheduleRelationship().Number()),
+			})
+		}
+		tripUpdates = append(tripUpdates, tripUpdate)
+	}
+	return tripUpdates, nil
+}
+
+func convertStopTimeEventToModel(event *gtfs_realtime.TripUpdate_StopTimeEvent) *model.StopTimeEvent {
+	if event == nil {
+		return nil
+	}
+	return &model.StopTimeEvent{Delay: event.GetDelay(), Time: event.GetTime(), Uncertainty: event.GetUncertainty()}
+}
+
+func convertAlertProtoToModel(protoBytes []byte) ([]model.Alert, error) {
+	feedMessage, err := unmarshalFeedMessage(protoBytes)
 	if err != nil {
 		return nil, err
 	}
+	return convertAlertsToModel(feedMessage)
+}
+
+// Converts the alerts of a feed message. Entities of other types are skipped
+func convertAlertsToModel(feedMessage *gtfs_realtime.FeedMessage) ([]model.Alert, error) {
+	if feedMessage.Header == nil {
+		return nil, errors.New("feed message is missing its header")
+	}
+
+	var alerts []model.Alert
+	for _, entity := range feedMessage.Entity {
+		alertProto := entity.GetAlert()
+		if alertProto == nil {
+			continue
+		}
+		alert := model.Alert{
+			Id:               entity.GetId(),
+			MessageTimestamp: feedMessage.Header.GetTimestamp(),
+			Cause:            model.AlertCause(alertProto.GetCause().Number()),
+			Effect:           model.AlertEffect(alertProto.GetEffect().Number()),
+			SeverityLevel:    model.AlertSeverityLevel(alertProto.GetSeverityLevel().Number()),
+			Url:              convertTranslatedStringToModel(alertProto.GetUrl()),
+			HeaderText:       convertTranslatedStringToModel(alertProto.GetHeaderText()),
+			DescriptionText:  convertTranslatedStringToModel(alertProto.GetDescriptionText()),
+		}
+		for _, period := range alertProto.GetActivePeriod() {
+			alert.ActivePeriods = append(alert.ActivePeriods, model.AlertActivePeriod{Start: period.GetStart(), End: period.GetEnd()})
+		}
+		for _, informedEntity := range alertProto.GetInformedEntity() {
+			alert.InformedEntities = append(alert.InformedEntities, model.AlertInformedEntity{
+				AgencyId:  informedEntity.GetAgencyId(),
+				RouteId:   informedEntity.GetRouteId(),
+				RouteType: informedEntity.RouteType,
+				TripId:    informedEntity.GetTrip().GetTripId(),
+				StopId:    informedEntity.GetStopId(),
+			})
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
 
-	return protoBytes, nil
+// Returns the texts by language, or nil when there are none
+func convertTranslatedStringToModel(translatedString *gtfs_realtime.TranslatedString) map[string]string {
+	translations := translatedString.GetTranslation()
+	if len(translations) == 0 {
+		return nil
+	}
+	textsByLanguage := make(map[string]string, len(translations))
+	for _, translation := range translations {
+		textsByLanguage[translation.GetLanguage()] = translation.GetText()
+	}
+	return textsByLanguage
 }
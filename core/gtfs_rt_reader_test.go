@@ -5,8 +5,12 @@ import (
 	"os"
 	"path"
 	"testing"
+	"time"
 
+	"github.com/samc1213/gtfs-analyze/gtfs_realtime"
+	"github.com/samc1213/gtfs-analyze/model"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestParseRtdRtVehiclePosition(t *testing.T) {
@@ -25,3 +29,66 @@ func TestParseRtdRtVehiclePosition(t *testing.T) {
 		updateIds[update.Id] = struct{}{}
 	}
 }
+
+func getTripUpdateAndAlertMessage() *gtfs_realtime.FeedMessage {
+	return &gtfs_realtime.FeedMessage{
+		Header: &gtfs_realtime.FeedHeader{GtfsRealtimeVersion: proto.String("2.0"), Timestamp: proto.Uint64(1685973600)},
+		Entity: []*gtfs_realtime.FeedEntity{
+			{
+				Id: proto.String("update1"),
+				TripUpdate: &gtfs_realtime.TripUpdate{
+					Trip:  &gtfs_realtime.TripDescriptor{TripId: proto.String("trip1"), StartDate: proto.String("20230605")},
+					Delay: proto.Int32(60),
+					StopTimeUpdate: []*gtfs_realtime.TripUpdate_StopTimeUpdate{
+						{StopSequence: proto.Uint32(1), Arrival: &gtfs_realtime.TripUpdate_StopTimeEvent{Delay: proto.Int32(120)}},
+						{StopId: proto.String("stop2"), ScheduleRelationship: gtfs_realtime.TripUpdate_StopTimeUpdate_SKIPPED.Enum()},
+					},
+				},
+			},
+			{
+				Id: proto.String("alert1"),
+				Alert: &gtfs_realtime.Alert{
+					ActivePeriod:   []*gtfs_realtime.TimeRange{{Start: proto.Uint64(1685970000)}},
+					InformedEntity: []*gtfs_realtime.EntitySelector{{RouteId: proto.String("route15")}, {RouteType: proto.Int32(3)}},
+					Cause:          gtfs_realtime.Alert_CONSTRUCTION.Enum(),
+					Effect:         gtfs_realtime.Alert_DETOUR.Enum(),
+					HeaderText: &gtfs_realtime.TranslatedString{Translation: []*gtfs_realtime.TranslatedString_Translation{
+						{Text: proto.String("Route 15 detour")},
+						{Text: proto.String("Desvío de la ruta 15"), Language: proto.String("es")},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestConvertTripUpdatesAndAlerts(t *testing.T) {
+	message := getTripUpdateAndAlertMessage()
+	tripUpdates, err := convertTripUpdatesToModel(message)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.TripUpdate{{
+		Id:               "update1",
+		MessageTimestamp: 1685973600,
+		TripId:           "trip1",
+		StartDate:        time.Date(2023, 6, 5, 0, 0, 0, 0, time.UTC),
+		Delay:            60,
+		StopTimeUpdates: []model.StopTimeUpdate{
+			{StopSequence: 1, Arrival: &model.StopTimeEvent{Delay: 120}},
+			{StopId: "stop2", ScheduleRelationship: model.SkippedStop},
+		},
+	}}, tripUpdates)
+
+	routeType := int32(3)
+	alerts, err := convertAlertsToModel(message)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Alert{{
+		Id:               "alert1",
+		MessageTimestamp: 1685973600,
+		ActivePeriods:    []model.AlertActivePeriod{{Start: 1685970000}},
+		InformedEntities: []model.AlertInformedEntity{{RouteId: "route15"}, {RouteType: &routeType}},
+		Cause:            model.Construction,
+		Effect:           model.Detour,
+		SeverityLevel:    model.UnknownSeverity,
+		HeaderText:       map[string]string{"": "Route 15 detour", "es": "Desvío de la ruta 15"},
+	}}, alerts)
+}
@@ -0,0 +1,290 @@
+package core
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/gtfs_realtime"
+	"github.com/samc1213/gtfs-analyze/infra"
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+)
+
+const (
+	InvalidProtobuf       FindingCode = "invalid_protobuf"
+	MissingHeaderField    FindingCode = "missing_header_field"
+	NoStaticFeed          FindingCode = "no_static_feed"
+	UnknownTripId         FindingCode = "unknown_trip_id"
+	UnknownStopId         FindingCode = "unknown_stop_id"
+	UnknownRouteId        FindingCode = "unknown_route_id"
+	RouteTripMismatch     FindingCode = "route_trip_mismatch"
+	StopNotOnTrip         FindingCode = "stop_not_on_trip"
+	PositionFarFromTrip   FindingCode = "position_far_from_trip"
+	StaleVehicleTimestamp FindingCode = "stale_vehicle_timestamp"
+	StopSequenceBackwards FindingCode = "stop_sequence_backwards"
+)
+
+type RtValidationOptions struct {
+	// Vehicles further than this from the path between their trip's stops are reported
+	MaxDistanceFromTripMeters float64
+	// Vehicle timestamps older than this, relative to the message timestamp, are reported
+	MaxVehicleAge time.Duration
+}
+
+// A GTFS-RT message to validate, along with where it came from
+type rtMessage struct {
+	source  string
+	message *gtfs_realtime.FeedMessage
+}
+
+// The parts of a static feed needed to look up what a vehicle position refers to
+type staticFeedLookup struct {
+	tripsById         map[string]*model.Trip
+	routeIds          map[string]struct{}
+	stopsById         map[string]*model.Stop
+	stopTimesByTripId map[string][]*model.StopTime
+}
+
+type vehicleProgress struct {
+	tripId       string
+	stopSequence int32
+}
+
+type rtValidator struct {
+	options       RtValidationOptions
+	report        *ValidationReport
+	getFeedOnDate func(date infra.Date) (*model.GtfsStaticFeed, error)
+	lookupsByDate map[infra.Date]*staticFeedLookup
+	progressByKey map[string]vehicleProgress
+	logger        log.Interface
+}
+
+// Validates GTFS-RT VehiclePosition messages, from a url and/or local .pb files, against the static
+// feed stored in the database that was active when each message was generated. Messages are
+// checked in header timestamp order, so that vehicles' progress along their trips can be followed
+// from one message to the next. The static feeds are those stored under namespace
+func ValidateRtGtfs(dbPath string, namespace string, vehiclePositionUrl string, paths []string, options RtValidationOptions, logLevel log.Level) (*ValidationReport, error) {
+	logger := log.New(logLevel)
+
+	db, err := openExistingDb(logger, dbPath, logLevel)
+	if err != nil {
+		return nil, err
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlDb.Close()
+
+	validator := newRtValidator(options, func(date infra.Date) (*model.GtfsStaticFeed, error) {
+		return GetFeedOnDate(namespace, date.Year, date.Month, date.Day, db)
+	}, logger)
+
+	var messages []rtMessage
+	if vehiclePositionUrl != "" {
+		protoBytes, err := newFeedFetcher(rtFetchTimeout, feedCredentials{}).fetch(context.Background(), vehiclePositionUrl)
+		if err != nil {
+			return nil, err
+		}
+		messages = validator.addMessage(messages, vehiclePositionUrl, protoBytes)
+	}
+	for _, path := range paths {
+		protoBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		messages = validator.addMessage(messages, path, protoBytes)
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].message.GetHeader().GetTimestamp() < messages[j].message.GetHeader().GetTimestamp()
+	})
+	for _, message := range messages {
+		validator.validateMessage(message.source, message.message)
+	}
+
+	return validator.report, nil
+}
+
+func newRtValidator(options RtValidationOptions, getFeedOnDate func(date infra.Date) (*model.GtfsStaticFeed, error), logger log.Interface) *rtValidator {
+	return &rtValidator{
+		options:       options,
+		report:        &ValidationReport{},
+		getFeedOnDate: getFeedOnDate,
+		lookupsByDate: make(map[infra.Date]*staticFeedLookup),
+		progressByKey: make(map[string]vehicleProgress),
+		logger:        logger,
+	}
+}
+
+func (validator *rtValidator) addMessage(messages []rtMessage, source string, protoBytes []byte) []rtMessage {
+	message, err := unmarshalFeedMessage(protoBytes)
+	if err != nil {
+		validator.report.Findings = append(validator.report.Findings, ValidationFinding{File: source, Severity: SeverityError, Code: InvalidProtobuf, Message: err.Error()})
+		return messages
+	}
+	return append(messages, rtMessage{source: source, message: message})
+}
+
+func (validator *rtValidator) add(source string, entityId string, severity Severity, code FindingCode, format string, v ...any) {
+	validator.report.add(source, 0, severity, code, format, v...)
+	validator.report.Findings[len(validator.report.Findings)-1].EntityId = entityId
+}
+
+func (validator *rtValidator) validateMessage(source string, message *gtfs_realtime.FeedMessage) {
+	if message.Header == nil {
+		validator.add(source, "", SeverityError, MissingHeaderField, "feed message has no header")
+		return
+	}
+	if message.Header.GetGtfsRealtimeVersion() == "" {
+		validator.add(source, "", SeverityError, MissingHeaderField, "header is missing gtfs_realtime_version")
+	}
+	if message.Header.GetTimestamp() == 0 {
+		// Without a timestamp there is no way to find the static feed the message refers to
+		validator.add(source, "", SeverityError, MissingHeaderField, "header is missing timestamp")
+		return
+	}
+
+	messageTime := time.Unix(int64(message.Header.GetTimestamp()), 0).UTC()
+	lookup, err := validator.getLookup(messageTime)
+	if err != nil {
+		validator.add(source, "", SeverityError, NoStaticFeed, "no static feed found for %s: %s", messageTime.Format(time.RFC3339), err.Error())
+		return
+	}
+
+	positions, err := convertFeedMessageToModel(message)
+	if err != nil {
+		validator.add(source, "", SeverityError, InvalidProtobuf, "%s", err.Error())
+		return
+	}
+	for i := range positions {
+		validator.validatePosition(source, &positions[i], lookup)
+	}
+}
+
+func (validator *rtValidator) getLookup(messageTime time.Time) (*staticFeedLookup, error) {
+	year, month, day := messageTime.Date()
+	date := infra.Date{Year: year, Month: month, Day: day}
+	if lookup, ok := validator.lookupsByDate[date]; ok {
+		return lookup, nil
+	}
+
+	feed, err := validator.getFeedOnDate(date)
+	if err != nil {
+		return nil, err
+	}
+	validator.logger.Debug("Validating GTFS-RT messages on %s against static feed version %s", date.String(), feed.FeedInfo.Version)
+
+	lookup := &staticFeedLookup{
+		tripsById:         make(map[string]*model.Trip, len(feed.Trip)),
+		routeIds:          make(map[string]struct{}, len(feed.Route)),
+		stopsById:         make(map[string]*model.Stop, len(feed.Stop)),
+		stopTimesByTripId: make(map[string][]*model.StopTime),
+	}
+	for i := range feed.Trip {
+		lookup.tripsById[feed.Trip[i].Id] = &feed.Trip[i]
+	}
+	for i := range feed.Route {
+		lookup.routeIds[feed.Route[i].Id] = struct{}{}
+	}
+	for i := range feed.Stop {
+		lookup.stopsById[feed.Stop[i].Id] = &feed.Stop[i]
+	}
+	for i := range feed.StopTime {
+		tripId := feed.StopTime[i].TripId
+		lookup.stopTimesByTripId[tripId] = append(lookup.stopTimesByTripId[tripId], &feed.StopTime[i])
+	}
+	for tripId := range lookup.stopTimesByTripId {
+		stopTimes := lookup.stopTimesByTripId[tripId]
+		sort.Slice(stopTimes, func(i, j int) bool { return stopTimes[i].StopSequence < stopTimes[j].StopSequence })
+	}
+
+	validator.lookupsByDate[date] = lookup
+	return lookup, nil
+}
+
+func (validator *rtValidator) validatePosition(source string, position *model.VehiclePosition, lookup *staticFeedLookup) {
+	if position.PositionTimestamp != 0 {
+		age := time.Duration(int64(position.MessageTimestamp)-int64(position.PositionTimestamp)) * time.Second
+		if age > validator.options.MaxVehicleAge {
+			validator.add(source, position.Id, SeverityWarning, StaleVehicleTimestamp, "vehicle timestamp is %s older than the message timestamp", age.String())
+		} else if age < 0 {
+			validator.add(source, position.Id, SeverityWarning, StaleVehicleTimestamp, "vehicle timestamp is %s after the message timestamp", (-age).String())
+		}
+	}
+
+	if position.StopId != "" {
+		if _, ok := lookup.stopsById[position.StopId]; !ok {
+			validator.add(source, position.Id, SeverityError, UnknownStopId, "stop_id %s does not exist in the static feed", position.StopId)
+		}
+	}
+
+	if position.RouteId != "" {
+		if _, ok := lookup.routeIds[position.RouteId]; !ok {
+			validator.add(source, position.Id, SeverityError, UnknownRouteId, "route_id %s does not exist in the static feed", position.RouteId)
+		}
+	}
+
+	// Added and unscheduled trips aren't expected to be in the static feed
+	if position.TripId == "" || position.ScheduleRelationship == model.Added || position.ScheduleRelationship == model.Unscheduled {
+		return
+	}
+	trip, ok := lookup.tripsById[position.TripId]
+	if !ok {
+		validator.add(source, position.Id, SeverityError, UnknownTripId, "trip_id %s does not exist in the static feed", position.TripId)
+		return
+	}
+	if position.RouteId != "" && position.RouteId != trip.RouteId {
+		validator.add(source, position.Id, SeverityError, RouteTripMismatch, "route_id %s does not match route_id %s of trip %s", position.RouteId, trip.RouteId, trip.Id)
+	}
+
+	stopTimes := lookup.stopTimesByTripId[trip.Id]
+	if position.StopId != "" && !isStopOnTrip(position.StopId, stopTimes) {
+		validator.add(source, position.Id, SeverityWarning, StopNotOnTrip, "stop_id %s is not served by trip %s", position.StopId, trip.Id)
+	}
+
+	// A position of (0, 0) means the optional position was left out
+	if position.Latitude != 0 || position.Longitude != 0 {
+		tripPath := make([]infra.Coordinate, 0, len(stopTimes))
+		for _, stopTime := range stopTimes {
+			if stop, ok := lookup.stopsById[stopTime.StopId]; ok && hasCoordinates(stop) {
+				tripPath = append(tripPath, infra.Coordinate{Latitude: stop.Latitude.V, Longitude: stop.Longitude.V})
+			}
+		}
+		if len(tripPath) > 0 {
+			distance := infra.DistanceToPathMeters(infra.Coordinate{Latitude: position.Latitude, Longitude: position.Longitude}, tripPath)
+			if distance > validator.options.MaxDistanceFromTripMeters {
+				validator.add(source, position.Id, SeverityWarning, PositionFarFromTrip, "vehicle is %.0f meters from the stops of trip %s", distance, trip.Id)
+			}
+		}
+	}
+
+	validator.validateProgress(source, position)
+}
+
+// Checks that a vehicle doesn't move backwards along its trip between messages
+func (validator *rtValidator) validateProgress(source string, position *model.VehiclePosition) {
+	if position.CurrentStopSequence == 0 {
+		return
+	}
+	key := position.VehicleId
+	if key == "" {
+		key = position.Id
+	}
+	previous, ok := validator.progressByKey[key]
+	if ok && previous.tripId == position.TripId && position.CurrentStopSequence < previous.stopSequence {
+		validator.add(source, position.Id, SeverityError, StopSequenceBackwards, "current_stop_sequence went from %d to %d on trip %s", previous.stopSequence, position.CurrentStopSequence, position.TripId)
+	}
+	validator.progressByKey[key] = vehicleProgress{tripId: position.TripId, stopSequence: position.CurrentStopSequence}
+}
+
+func isStopOnTrip(stopId string, stopTimes []*model.StopTime) bool {
+	for _, stopTime := range stopTimes {
+		if stopTime.StopId == stopId {
+			return true
+		}
+	}
+	return false
+}
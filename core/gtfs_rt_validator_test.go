@@ -0,0 +1,96 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/csv_parse"
+	"github.com/samc1213/gtfs-analyze/gtfs_realtime"
+	"github.com/samc1213/gtfs-analyze/infra"
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func createVehiclePositionEntity(id string, tripId string, routeId string, stopId string, stopSequence uint32, latitude float32, longitude float32, timestamp uint64) *gtfs_realtime.FeedEntity {
+	return &gtfs_realtime.FeedEntity{
+		Id: proto.String(id),
+		Vehicle: &gtfs_realtime.VehiclePosition{
+			Trip:                &gtfs_realtime.TripDescriptor{TripId: proto.String(tripId), RouteId: proto.String(routeId)},
+			Vehicle:             &gtfs_realtime.VehicleDescriptor{Id: proto.String("bus" + id)},
+			Position:            &gtfs_realtime.Position{Latitude: proto.Float32(latitude), Longitude: proto.Float32(longitude)},
+			StopId:              proto.String(stopId),
+			CurrentStopSequence: proto.Uint32(stopSequence),
+			Timestamp:           proto.Uint64(timestamp),
+		},
+	}
+}
+
+func createRtValidator() *rtValidator {
+	feed, _, stopOneId, stopTwoId, _ := createStaticFeed()
+	feed.Stop = []model.Stop{
+		{Id: stopOneId, Latitude: csv_parse.NewOptional(39.7525), Longitude: csv_parse.NewOptional(-105.0001)},
+		{Id: stopTwoId, Latitude: csv_parse.NewOptional(39.7392), Longitude: csv_parse.NewOptional(-104.9875)},
+	}
+	feed.StopTime[0].StopSequence = 1
+	feed.StopTime[1].StopSequence = 2
+	options := RtValidationOptions{MaxDistanceFromTripMeters: 500, MaxVehicleAge: 2 * time.Minute}
+	return newRtValidator(options, func(date infra.Date) (*model.GtfsStaticFeed, error) { return feed, nil }, log.New(log.Silent))
+}
+
+func TestValidateRtMessage(t *testing.T) {
+	validator := createRtValidator()
+	messageTimestamp := uint64(1686236400)
+	message := &gtfs_realtime.FeedMessage{
+		Header: &gtfs_realtime.FeedHeader{GtfsRealtimeVersion: proto.String("2.0"), Timestamp: proto.Uint64(messageTimestamp)},
+		Entity: []*gtfs_realtime.FeedEntity{
+			// Halfway between stop1 and stop2, on time
+			createVehiclePositionEntity("1", "trip1", "route15", "stop2", 2, 39.7458, -104.9938, messageTimestamp-30),
+			createVehiclePositionEntity("2", "trip9", "route15", "stop1", 1, 39.7525, -105.0001, messageTimestamp),
+			createVehiclePositionEntity("3", "trip1", "route16", "stop9", 1, 39.7525, -105.0001, messageTimestamp),
+			// About 5 km north of the trip, with an old timestamp
+			createVehiclePositionEntity("4", "trip1", "route15", "stop1", 1, 39.7975, -105.0001, messageTimestamp-600),
+		},
+	}
+	validator.validateMessage("VehiclePosition.pb", message)
+	findings := validator.report.Findings
+
+	assert.Contains(t, findings, ValidationFinding{File: "VehiclePosition.pb", EntityId: "2", Severity: SeverityError, Code: UnknownTripId, Message: "trip_id trip9 does not exist in the static feed"})
+	assert.Contains(t, findings, ValidationFinding{File: "VehiclePosition.pb", EntityId: "3", Severity: SeverityError, Code: UnknownStopId, Message: "stop_id stop9 does not exist in the static feed"})
+	assert.Contains(t, findings, ValidationFinding{File: "VehiclePosition.pb", EntityId: "3", Severity: SeverityError, Code: UnknownRouteId, Message: "route_id route16 does not exist in the static feed"})
+	assert.Contains(t, findings, ValidationFinding{File: "VehiclePosition.pb", EntityId: "3", Severity: SeverityError, Code: RouteTripMismatch, Message: "route_id route16 does not match route_id route15 of trip trip1"})
+	assert.Contains(t, findings, ValidationFinding{File: "VehiclePosition.pb", EntityId: "4", Severity: SeverityWarning, Code: StaleVehicleTimestamp, Message: "vehicle timestamp is 10m0s older than the message timestamp"})
+	numFarFromTrip := 0
+	for _, finding := range findings {
+		assert.NotEqual(t, "1", finding.EntityId)
+		if finding.Code == PositionFarFromTrip {
+			assert.Equal(t, "4", finding.EntityId)
+			numFarFromTrip++
+		}
+	}
+	assert.Equal(t, 1, numFarFromTrip)
+}
+
+func TestValidateRtStopSequenceBackwards(t *testing.T) {
+	validator := createRtValidator()
+	header := &gtfs_realtime.FeedHeader{GtfsRealtimeVersion: proto.String("2.0"), Timestamp: proto.Uint64(1686236400)}
+	validator.validateMessage("first.pb", &gtfs_realtime.FeedMessage{Header: header, Entity: []*gtfs_realtime.FeedEntity{
+		createVehiclePositionEntity("1", "trip1", "route15", "stop2", 2, 39.7392, -104.9875, 1686236400),
+	}})
+	validator.validateMessage("second.pb", &gtfs_realtime.FeedMessage{Header: header, Entity: []*gtfs_realtime.FeedEntity{
+		createVehiclePositionEntity("1", "trip1", "route15", "stop1", 1, 39.7525, -105.0001, 1686236400),
+	}})
+	assert.Equal(t, []ValidationFinding{{File: "second.pb", EntityId: "1", Severity: SeverityError, Code: StopSequenceBackwards, Message: "current_stop_sequence went from 2 to 1 on trip trip1"}}, validator.report.Findings)
+}
+
+func TestValidateRtMissingHeaderFields(t *testing.T) {
+	validator := createRtValidator()
+	validator.validateMessage("VehiclePosition.pb", &gtfs_realtime.FeedMessage{Header: &gtfs_realtime.FeedHeader{}})
+	assert.Equal(t, 2, len(validator.report.Findings))
+	assert.Equal(t, MissingHeaderField, validator.report.Findings[0].Code)
+	assert.Equal(t, "header is missing timestamp", validator.report.Findings[1].Message)
+
+	validator.addMessage(nil, "garbage.pb", []byte("not a protobuf"))
+	assert.Equal(t, InvalidProtobuf, validator.report.Findings[2].Code)
+}
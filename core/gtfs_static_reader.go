@@ -3,12 +3,13 @@ package core
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"hash"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
@@ -20,92 +21,95 @@ import (
 	"gorm.io/gorm"
 )
 
-func ParseStaticGtfsFromUrl(url string) (*model.GtfsStaticFeed, error) {
-	resp, err := http.Get(url)
+// Controls how rows that can't be parsed are handled when reading a static feed
+type StaticParseOptions struct {
+	ErrorMode csv_parse.ErrorMode
+	// Called for every row or field that is skipped or defaulted, along with the name of its file
+	OnError func(fileName string, err *csv_parse.ParseError)
+	// Character encoding of the feed's files. When empty, it is detected from each file
+	Encoding string
+}
+
+func ParseStaticGtfsFromUrl(url string, options StaticParseOptions) (*model.GtfsStaticFeed, error) {
+	// No timeout, since static feeds can be large
+	zipBytes, err := newFeedFetcher(0, feedCredentials{}).fetch(context.Background(), url)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("Non-successful response from uri " + url)
-	}
+	return parseStaticGtfsFromZipBytes(zipBytes, url, options)
+}
 
-	tempFile, err := os.CreateTemp(os.TempDir(), "google_transit*.zip")
+func parseStaticGtfsFromZipBytes(zipBytes []byte, url string, options StaticParseOptions) (*model.GtfsStaticFeed, error) {
+	archive, err := zip.NewReader(bytes.NewReader(zipBytes), int64(len(zipBytes)))
 	if err != nil {
-		return nil, err
+		return nil, errors.New("Unable to read zip file from " + url)
 	}
-	defer tempFile.Close()
-	defer os.Remove(tempFile.Name())
-
-	_, err = io.Copy(tempFile, resp.Body)
+	gtfsFiles, err := getGtfsFilesFromZipReader(archive)
 	if err != nil {
 		return nil, err
 	}
 
-	gtfsFiles, err := getGtfsFilesFromZip(tempFile.Name())
+	return parseStaticGtfsFromFiles(gtfsFiles, options)
+}
+
+// Parses a static GTFS feed into a struct. Handles a local folder, or local zipped file
+func ParseStaticGtfsFromPath(path string, options StaticParseOptions) (*model.GtfsStaticFeed, error) {
+	gtfsFiles, err := getGtfsFilesFromPath(path)
 	if err != nil {
 		return nil, err
 	}
 
-	result, err := parseStaticGtfsFromFiles(gtfsFiles)
-
+	result, err := parseStaticGtfsFromFiles(gtfsFiles, options)
 	if err != nil {
 		return nil, err
 	}
-
 	return result, nil
 }
 
-// Parses a static GTFS feed into a struct. Handles a local folder, or local zipped file
-func ParseStaticGtfsFromPath(path string) (*model.GtfsStaticFeed, error) {
+// Gets file names and objects for each file in a local folder, or local zipped file
+func getGtfsFilesFromPath(path string) (*GtfsFileCollection, error) {
 	fileInfo, err := os.Stat(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var gtfsFiles *GtfsFileCollection
-
-	if fileInfo.IsDir() {
-		files, err := os.ReadDir(path)
-		if err != nil {
-			return nil, err
-		}
-		var gtfsFilesList []GtfsFile
-		for _, f := range files {
-
-			fileObj, err := os.Open(filepath.Join(path, f.Name()))
-			if err != nil {
-				return nil, err
-			}
-			defer fileObj.Close()
+	if !fileInfo.IsDir() {
+		return getGtfsFilesFromZip(path)
+	}
 
-			gtfsFilesList = append(gtfsFilesList, GtfsFile{Name: f.Name(), FileObj: fileObj})
+	files, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var gtfsFilesList []GtfsFile
+	for _, f := range files {
+		if f.IsDir() {
+			continue
 		}
-
-		gtfsFiles = &GtfsFileCollection{GtfsFiles: gtfsFilesList}
-	} else {
-		gtfsFiles, err = getGtfsFilesFromZip(path)
+		// Read into memory, like getGtfsFilesFromZip, so no files are left open
+		fileBytes, err := os.ReadFile(filepath.Join(path, f.Name()))
 		if err != nil {
 			return nil, err
 		}
-	}
 
-	result, err := parseStaticGtfsFromFiles(gtfsFiles)
-	if err != nil {
-		return nil, err
+		gtfsFilesList = append(gtfsFilesList, GtfsFile{Name: f.Name(), FileObj: bytes.NewReader(fileBytes)})
 	}
-	return result, nil
+
+	return &GtfsFileCollection{GtfsFiles: gtfsFilesList}, nil
 }
 
 // Opens a zip file and gets file names and objects for each file in the zip file. Leaves files open
 func getGtfsFilesFromZip(path string) (*GtfsFileCollection, error) {
-	var gtfsFiles []GtfsFile
 	archive, err := zip.OpenReader(path)
 	if err != nil {
 		return nil, errors.New("Unable to read zip file at " + path + ". Must provide an unzipped directory with GTFS txt files, or a zipped google_transit.zip file")
 	}
+	defer archive.Close()
+	return getGtfsFilesFromZipReader(&archive.Reader)
+}
 
+func getGtfsFilesFromZipReader(archive *zip.Reader) (*GtfsFileCollection, error) {
+	var gtfsFiles []GtfsFile
 	for _, f := range archive.File {
 		readerCloser, err := f.Open()
 		if err != nil {
@@ -134,7 +138,7 @@ type GtfsFile struct {
 	FileObj io.ReadSeeker
 }
 
-func parseStaticGtfsFromFiles(files *GtfsFileCollection) (*model.GtfsStaticFeed, error) {
+func parseStaticGtfsFromFiles(files *GtfsFileCollection, options StaticParseOptions) (*model.GtfsStaticFeed, error) {
 	var result model.GtfsStaticFeed
 
 	hash := md5.New()
@@ -143,7 +147,7 @@ func parseStaticGtfsFromFiles(files *GtfsFileCollection) (*model.GtfsStaticFeed,
 
 	for _, f := range files.GtfsFiles {
 		if strings.ToLower(f.Name) == "agency.txt" {
-			agencies, err := parseSingleStaticFile[model.Agency](f.FileObj)
+			agencies, err := parseSingleStaticFile[model.Agency](f.Name, f.FileObj, options)
 			if err != nil {
 				return &result, err
 			}
@@ -154,7 +158,7 @@ func parseStaticGtfsFromFiles(files *GtfsFileCollection) (*model.GtfsStaticFeed,
 			result.Agency = agencies
 		}
 		if strings.ToLower(f.Name) == "stops.txt" {
-			stops, err := parseSingleStaticFile[model.Stop](f.FileObj)
+			stops, err := parseSingleStaticFile[model.Stop](f.Name, f.FileObj, options)
 			if err != nil {
 				return &result, err
 			}
@@ -165,7 +169,7 @@ func parseStaticGtfsFromFiles(files *GtfsFileCollection) (*model.GtfsStaticFeed,
 			result.Stop = stops
 		}
 		if strings.ToLower(f.Name) == "routes.txt" {
-			routes, err := parseSingleStaticFile[model.Route](f.FileObj)
+			routes, err := parseSingleStaticFile[model.Route](f.Name, f.FileObj, options)
 			if err != nil {
 				return &result, err
 			}
@@ -176,7 +180,7 @@ func parseStaticGtfsFromFiles(files *GtfsFileCollection) (*model.GtfsStaticFeed,
 			result.Route = routes
 		}
 		if strings.ToLower(f.Name) == "trips.txt" {
-			trips, err := parseSingleStaticFile[model.Trip](f.FileObj)
+			trips, err := parseSingleStaticFile[model.Trip](f.Name, f.FileObj, options)
 			if err != nil {
 				return &result, err
 			}
@@ -187,7 +191,7 @@ func parseStaticGtfsFromFiles(files *GtfsFileCollection) (*model.GtfsStaticFeed,
 			result.Trip = trips
 		}
 		if strings.ToLower(f.Name) == "stop_times.txt" {
-			stopTimes, err := parseSingleStaticFile[model.StopTime](f.FileObj)
+			stopTimes, err := parseSingleStaticFile[model.StopTime](f.Name, f.FileObj, options)
 			if err != nil {
 				return &result, err
 			}
@@ -197,8 +201,19 @@ func parseStaticGtfsFromFiles(files *GtfsFileCollection) (*model.GtfsStaticFeed,
 			}
 			result.StopTime = stopTimes
 		}
+		if strings.ToLower(f.Name) == "shapes.txt" {
+			shapePoints, err := parseSingleStaticFile[model.ShapePoint](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.ShapePoint = shapePoints
+		}
 		if strings.ToLower(f.Name) == "calendar.txt" {
-			calendars, err := parseSingleStaticFile[model.Calendar](f.FileObj)
+			calendars, err := parseSingleStaticFile[model.Calendar](f.Name, f.FileObj, options)
 			if err != nil {
 				return &result, err
 			}
@@ -208,8 +223,228 @@ func parseStaticGtfsFromFiles(files *GtfsFileCollection) (*model.GtfsStaticFeed,
 			}
 			result.Calendar = calendars
 		}
+		if strings.ToLower(f.Name) == "transfers.txt" {
+			transfers, err := parseSingleStaticFile[model.Transfer](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.Transfer = transfers
+		}
+		if strings.ToLower(f.Name) == "levels.txt" {
+			levels, err := parseSingleStaticFile[model.Level](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.Level = levels
+		}
+		if strings.ToLower(f.Name) == "pathways.txt" {
+			pathways, err := parseSingleStaticFile[model.Pathway](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.Pathway = pathways
+		}
+		if strings.ToLower(f.Name) == "translations.txt" {
+			translations, err := parseSingleStaticFile[model.Translation](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.Translation = translations
+		}
+		if strings.ToLower(f.Name) == "attributions.txt" {
+			attributions, err := parseSingleStaticFile[model.Attribution](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.Attribution = attributions
+		}
+		if strings.ToLower(f.Name) == "location_groups.txt" {
+			locationGroups, err := parseSingleStaticFile[model.LocationGroup](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.LocationGroup = locationGroups
+		}
+		if strings.ToLower(f.Name) == "location_group_stops.txt" {
+			locationGroupStops, err := parseSingleStaticFile[model.LocationGroupStop](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.LocationGroupStop = locationGroupStops
+		}
+		if strings.ToLower(f.Name) == "booking_rules.txt" {
+			bookingRules, err := parseSingleStaticFile[model.BookingRule](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.BookingRule = bookingRules
+		}
+		if strings.ToLower(f.Name) == "locations.geojson" {
+			locations, err := parseLocationsGeoJson(f.Name, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.Location = locations
+		}
+		if strings.ToLower(f.Name) == "fare_attributes.txt" {
+			fareAttributes, err := parseSingleStaticFile[model.FareAttribute](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.FareAttribute = fareAttributes
+		}
+		if strings.ToLower(f.Name) == "fare_rules.txt" {
+			fareRules, err := parseSingleStaticFile[model.FareRule](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.FareRule = fareRules
+		}
+		if strings.ToLower(f.Name) == "fare_media.txt" {
+			fareMedia, err := parseSingleStaticFile[model.FareMedia](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.FareMedia = fareMedia
+		}
+		if strings.ToLower(f.Name) == "fare_products.txt" {
+			fareProducts, err := parseSingleStaticFile[model.FareProduct](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.FareProduct = fareProducts
+		}
+		if strings.ToLower(f.Name) == "fare_leg_rules.txt" {
+			fareLegRules, err := parseSingleStaticFile[model.FareLegRule](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.FareLegRule = fareLegRules
+		}
+		if strings.ToLower(f.Name) == "fare_transfer_rules.txt" {
+			fareTransferRules, err := parseSingleStaticFile[model.FareTransferRule](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.FareTransferRule = fareTransferRules
+		}
+		if strings.ToLower(f.Name) == "areas.txt" {
+			areas, err := parseSingleStaticFile[model.Area](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.Area = areas
+		}
+		if strings.ToLower(f.Name) == "stop_areas.txt" {
+			stopAreas, err := parseSingleStaticFile[model.StopArea](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.StopArea = stopAreas
+		}
+		if strings.ToLower(f.Name) == "timeframes.txt" {
+			timeframes, err := parseSingleStaticFile[model.Timeframe](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.Timeframe = timeframes
+		}
+		if strings.ToLower(f.Name) == "networks.txt" {
+			networks, err := parseSingleStaticFile[model.Network](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.Network = networks
+		}
+		if strings.ToLower(f.Name) == "route_networks.txt" {
+			routeNetworks, err := parseSingleStaticFile[model.RouteNetwork](f.Name, f.FileObj, options)
+			if err != nil {
+				return &result, err
+			}
+			err = updateHash(hash, f.FileObj)
+			if err != nil {
+				return &result, err
+			}
+			result.RouteNetwork = routeNetworks
+		}
 		if strings.ToLower(f.Name) == "feed_info.txt" {
-			feedInfos, err := parseSingleStaticFile[model.FeedInfo](f.FileObj)
+			feedInfos, err := parseSingleStaticFile[model.FeedInfo](f.Name, f.FileObj, options)
 			if err != nil {
 				return &result, err
 			}
@@ -234,13 +469,10 @@ func parseStaticGtfsFromFiles(files *GtfsFileCollection) (*model.GtfsStaticFeed,
 // If a feed_info file with a version is not provided, use the md5 hash of the included
 // GTFS files to generate a fake FeedInfo object
 func updateVersion(feed *model.GtfsStaticFeed, hash string) error {
-	var defaultFeedInfo model.FeedInfo
-	if feed.FeedInfo == defaultFeedInfo {
-		feed.FeedInfo = model.FeedInfo{Version: hash}
-		addVersionToAllObjects(feed, hash)
-	} else {
-		addVersionToAllObjects(feed, feed.FeedInfo.Version)
+	if feed.FeedInfo.Version == "" {
+		feed.FeedInfo.Version = hash
 	}
+	addVersionToAllObjects(feed, feed.FeedInfo.Version)
 
 	feed.FeedInfo.DownloadTime = time.Now()
 
@@ -263,9 +495,72 @@ func addVersionToAllObjects(feed *model.GtfsStaticFeed, version string) {
 	for i := range feed.StopTime {
 		feed.StopTime[i].Version = version
 	}
+	for i := range feed.ShapePoint {
+		feed.ShapePoint[i].Version = version
+	}
 	for i := range feed.Calendar {
 		feed.Calendar[i].Version = version
 	}
+	for i := range feed.Transfer {
+		feed.Transfer[i].Version = version
+	}
+	for i := range feed.Level {
+		feed.Level[i].Version = version
+	}
+	for i := range feed.Pathway {
+		feed.Pathway[i].Version = version
+	}
+	for i := range feed.Translation {
+		feed.Translation[i].Version = version
+	}
+	for i := range feed.Attribution {
+		feed.Attribution[i].Version = version
+	}
+	for i := range feed.LocationGroup {
+		feed.LocationGroup[i].Version = version
+	}
+	for i := range feed.LocationGroupStop {
+		feed.LocationGroupStop[i].Version = version
+	}
+	for i := range feed.BookingRule {
+		feed.BookingRule[i].Version = version
+	}
+	for i := range feed.Location {
+		feed.Location[i].Version = version
+	}
+	for i := range feed.FareAttribute {
+		feed.FareAttribute[i].Version = version
+	}
+	for i := range feed.FareRule {
+		feed.FareRule[i].Version = version
+	}
+	for i := range feed.FareMedia {
+		feed.FareMedia[i].Version = version
+	}
+	for i := range feed.FareProduct {
+		feed.FareProduct[i].Version = version
+	}
+	for i := range feed.FareLegRule {
+		feed.FareLegRule[i].Version = version
+	}
+	for i := range feed.FareTransferRule {
+		feed.FareTransferRule[i].Version = version
+	}
+	for i := range feed.Area {
+		feed.Area[i].Version = version
+	}
+	for i := range feed.StopArea {
+		feed.StopArea[i].Version = version
+	}
+	for i := range feed.Timeframe {
+		feed.Timeframe[i].Version = version
+	}
+	for i := range feed.Network {
+		feed.Network[i].Version = version
+	}
+	for i := range feed.RouteNetwork {
+		feed.RouteNetwork[i].Version = version
+	}
 }
 
 func updateHash(hash hash.Hash, fileObj io.ReadSeeker) error {
@@ -276,9 +571,14 @@ func updateHash(hash hash.Hash, fileObj io.ReadSeeker) error {
 	return nil
 }
 
-func parseSingleStaticFile[T any](path io.ReadSeeker) ([]T, error) {
+func parseSingleStaticFile[T any](name string, path io.ReadSeeker, options StaticParseOptions) ([]T, error) {
 	elements := make([]T, 0)
-	recordProvider, err := csv_parse.BeginParseCsv[T](path)
+	// Feeds from smaller agencies often have padded values and rows with missing trailing commas
+	csvOptions := csv_parse.Options{ErrorMode: options.ErrorMode, Encoding: options.Encoding, TrimValues: true, AllowRaggedRows: true}
+	if options.OnError != nil {
+		csvOptions.OnError = func(err *csv_parse.ParseError) { options.OnError(name, err) }
+	}
+	recordProvider, err := csv_parse.BeginParseCsvWithOptions[T](path, csvOptions)
 	if err != nil {
 		return elements, err
 	}
@@ -289,7 +589,7 @@ func parseSingleStaticFile[T any](path io.ReadSeeker) ([]T, error) {
 			if err == csv_parse.EOF {
 				break
 			}
-			return elements, err
+			return elements, fmt.Errorf("%s: %w", name, err)
 		}
 		elements = append(elements, element)
 	}
@@ -297,17 +597,22 @@ func parseSingleStaticFile[T any](path io.ReadSeeker) ([]T, error) {
 	return elements, nil
 }
 
-func GetFeedOnDate(year int, month time.Month, day int, db *gorm.DB) (*model.GtfsStaticFeed, error) {
-	var feedInfo model.FeedInfo
-	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
-	tx := db.Where("start_date <= ? and end_date >= ? and cast(download_time as date) <= ?", date, date, date).Order("download_time DESC").First(&feedInfo)
-	if tx.Error != nil {
-		return nil, tx.Error
-	}
+// Loads the most recently downloaded feed of a namespace in db that is in effect on a date, and was
+// downloaded by then
+func GetFeedOnDate(namespace string, year int, month time.Month, day int, db *gorm.DB) (*model.GtfsStaticFeed, error) {
+	return NewGormRepository(db).GetFeedOnDate(namespace, year, month, day)
+}
+
+// Loads every entity stored for the given feed version
+func GetFeedByVersion(version string, db *gorm.DB) (*model.GtfsStaticFeed, error) {
+	return NewGormRepository(db).GetFeedByVersion(version)
+}
+
+func getFeedForFeedInfo(feedInfo model.FeedInfo, db *gorm.DB) (*model.GtfsStaticFeed, error) {
 	var feed model.GtfsStaticFeed
 	feed.FeedInfo = feedInfo
 	// TODO: Put this all in the same transaction?
-	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.Agency)
+	tx := db.Where("version = ?", feedInfo.Version).Find(&feed.Agency)
 	if tx.Error != nil {
 		return nil, tx.Error
 	}
@@ -327,7 +632,12 @@ func GetFeedOnDate(year int, month time.Month, day int, db *gorm.DB) (*model.Gtf
 		return nil, tx.Error
 	}
 
-	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.StopTime)
+	tx = db.Where("version = ?", feedInfo.Version).Order("trip_id, stop_sequence").Find(&feed.StopTime)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Order("shape_id, sequence").Find(&feed.ShapePoint)
 	if tx.Error != nil {
 		return nil, tx.Error
 	}
@@ -337,5 +647,105 @@ func GetFeedOnDate(year int, month time.Month, day int, db *gorm.DB) (*model.Gtf
 		return nil, tx.Error
 	}
 
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.Transfer)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.Level)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.Pathway)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.Translation)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.Attribution)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.LocationGroup)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.LocationGroupStop)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.BookingRule)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.Location)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.FareAttribute)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.FareRule)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.FareMedia)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.FareProduct)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.FareLegRule)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.FareTransferRule)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.Area)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.StopArea)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.Timeframe)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.Network)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	tx = db.Where("version = ?", feedInfo.Version).Find(&feed.RouteNetwork)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
 	return &feed, nil
 }
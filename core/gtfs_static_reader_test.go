@@ -5,6 +5,7 @@ import (
 	"runtime"
 	"testing"
 
+	"github.com/samc1213/gtfs-analyze/csv_parse"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,10 +16,49 @@ func getTestFilesPath() string {
 
 // This test takes nearly 30 seconds to run - TODO: figure out what's slow in parsing and improve parsing performance if possible
 func TestParseRtdStatic(t *testing.T) {
-	staticFeed, err := ParseStaticGtfsFromPath(path.Join(getTestFilesPath(), "google_transit_rtd_2023_05_12.zip"))
+	staticFeed, err := ParseStaticGtfsFromPath(path.Join(getTestFilesPath(), "google_transit_rtd_2023_05_12.zip"), StaticParseOptions{})
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(staticFeed.Agency))
 	assert.Equal(t, "Regional Transportation District", staticFeed.Agency[0].Name)
 	assert.Equal(t, 19, len(staticFeed.Calendar))
 	assert.Equal(t, "ca084dac096878a7d8fbf6f3f7dc1203", staticFeed.FeedInfo.Version)
 }
+
+func TestParseStaticGtfsErrorModes(t *testing.T) {
+	files := getValidGtfsFiles()
+	files["stops.txt"] = "stop_id,stop_name,stop_lat,stop_lon\nstop1,Union Station,39.7525,-105.0001\nstop2,Civic Center,north,-104.9875"
+
+	_, err := parseStaticGtfsFromFiles(createGtfsFiles(files), StaticParseOptions{})
+	assert.EqualError(t, err, `stops.txt: line 3, column stop_lat: strconv.ParseFloat: parsing "north": invalid syntax`)
+
+	var errorFiles []string
+	options := StaticParseOptions{ErrorMode: csv_parse.SkipRow, OnError: func(fileName string, err *csv_parse.ParseError) {
+		errorFiles = append(errorFiles, fileName)
+	}}
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(files), options)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(feed.Stop))
+	assert.Equal(t, []string{"stops.txt"}, errorFiles)
+
+	options.ErrorMode = csv_parse.DefaultField
+	feed, err = parseStaticGtfsFromFiles(createGtfsFiles(files), options)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(feed.Stop))
+	assert.False(t, feed.Stop[1].Latitude.Valid)
+	assert.Equal(t, csv_parse.NewOptional(-104.9875), feed.Stop[1].Longitude)
+}
+
+func TestParseStaticGtfsDialects(t *testing.T) {
+	files := getValidGtfsFiles()
+	// A BOM and padded, capitalized column names, with a padded value and a missing trailing value
+	files["stops.txt"] = "\ufeffStop_Id, stop_name ,stop_lat,stop_lon,location_type\nstop1, Union Station ,39.7525,-105.0001\nstop2,Civic Center,39.7392,-104.9875,0"
+	// Windows-1252 text
+	files["agency.txt"] = "agency_id,agency_name,agency_url,agency_timezone\nstm,Soci\xe9t\xe9 de transport de Montr\xe9al,https://www.stm.info,America/Montreal"
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(files), StaticParseOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(feed.Stop))
+	assert.Equal(t, "stop1", feed.Stop[0].Id)
+	assert.Equal(t, "Union Station", feed.Stop[0].Name)
+	assert.Equal(t, csv_parse.NewOptional(39.7525), feed.Stop[0].Latitude)
+	assert.Equal(t, "Société de transport de Montréal", feed.Agency[0].Name)
+}
@@ -0,0 +1,748 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/samc1213/gtfs-analyze/csv_parse"
+	"github.com/samc1213/gtfs-analyze/model"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+type FindingCode string
+
+const (
+	MissingRequiredFile       FindingCode = "missing_required_file"
+	MissingRequiredColumn     FindingCode = "missing_required_column"
+	MissingRequiredValue      FindingCode = "missing_required_value"
+	InvalidRow                FindingCode = "invalid_row"
+	InvalidEnumValue          FindingCode = "invalid_enum_value"
+	DuplicateKey              FindingCode = "duplicate_key"
+	ForeignKeyViolation       FindingCode = "foreign_key_violation"
+	NonIncreasingStopSequence FindingCode = "non_increasing_stop_sequence"
+	DepartureBeforeArrival    FindingCode = "departure_before_arrival"
+	DecreasingStopTime        FindingCode = "decreasing_stop_time"
+	InvalidDateRange          FindingCode = "invalid_date_range"
+	InvalidCoordinates        FindingCode = "invalid_coordinates"
+	SuspiciousCoordinates     FindingCode = "suspicious_coordinates"
+)
+
+type ValidationFinding struct {
+	File     string      `json:"file"`
+	Line     int         `json:"line,omitempty"`      // 0 when the finding applies to the whole file
+	EntityId string      `json:"entity_id,omitempty"` // Set instead of Line for GTFS-RT and locations.geojson findings
+	Severity Severity    `json:"severity"`
+	Code     FindingCode `json:"code"`
+	Message  string      `json:"message"`
+}
+
+type ValidationReport struct {
+	Findings []ValidationFinding `json:"findings"`
+}
+
+func (report *ValidationReport) add(file string, line int, severity Severity, code FindingCode, format string, v ...any) {
+	report.Findings = append(report.Findings, ValidationFinding{File: file, Line: line, Severity: severity, Code: code, Message: fmt.Sprintf(format, v...)})
+}
+
+func (report *ValidationReport) addForEntity(file string, entityId string, severity Severity, code FindingCode, format string, v ...any) {
+	report.Findings = append(report.Findings, ValidationFinding{File: file, EntityId: entityId, Severity: severity, Code: code, Message: fmt.Sprintf(format, v...)})
+}
+
+func (report *ValidationReport) CountBySeverity(severity Severity) int {
+	count := 0
+	for _, finding := range report.Findings {
+		if finding.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+func (report *ValidationReport) PrettyPrint() string {
+	builder := strings.Builder{}
+	writer := tabwriter.NewWriter(&builder, 0, 0, 0, ' ', tabwriter.Debug)
+	fmt.Fprintf(writer, "Severity\tCode\tFile\tLocation\tMessage\n")
+	for _, finding := range report.Findings {
+		location := ""
+		if finding.Line != 0 {
+			location = fmt.Sprint("line ", finding.Line)
+		} else if finding.EntityId != "" {
+			location = "entity " + finding.EntityId
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", finding.Severity, finding.Code, finding.File, location, finding.Message)
+	}
+	writer.Flush()
+	fmt.Fprintf(&builder, "%d errors, %d warnings\n", report.CountBySeverity(SeverityError), report.CountBySeverity(SeverityWarning))
+	return builder.String()
+}
+
+// A parsed CSV row, along with the line it was found on
+type lineRecord[T any] struct {
+	Line   int
+	Record T
+}
+
+// Only the service ids are needed from calendar_dates.txt, to resolve trips' service ids
+type calendarDateServiceId struct {
+	ServiceId string `csv_parse:"service_id"`
+}
+
+// Validates a static GTFS feed in a local folder or zip file. Unlike ParseStaticGtfsFromPath,
+// problems in the feed do not stop validation. Every problem found is reported as a finding,
+// and an error is only returned if the feed could not be read at all
+func ValidateStaticGtfsFromPath(path string) (*ValidationReport, error) {
+	gtfsFiles, err := getGtfsFilesFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return ValidateStaticGtfsFiles(gtfsFiles), nil
+}
+
+func ValidateStaticGtfsFiles(files *GtfsFileCollection) *ValidationReport {
+	report := &ValidationReport{}
+	filesByName := make(map[string]GtfsFile, len(files.GtfsFiles))
+	for _, f := range files.GtfsFiles {
+		filesByName[strings.ToLower(f.Name)] = f
+	}
+
+	agencies := validateSingleStaticFile[model.Agency](report, filesByName, "agency.txt", true, "agency_name", "agency_url", "agency_timezone")
+	stops := validateSingleStaticFile[model.Stop](report, filesByName, "stops.txt", true, "stop_id")
+	routes := validateSingleStaticFile[model.Route](report, filesByName, "routes.txt", true, "route_id", "route_type")
+	trips := validateSingleStaticFile[model.Trip](report, filesByName, "trips.txt", true, "route_id", "service_id", "trip_id")
+	// stop_id is only required when there are no GTFS-Flex location groups or locations
+	stopTimes := validateSingleStaticFile[model.StopTime](report, filesByName, "stop_times.txt", true, "trip_id", "stop_sequence")
+	calendars := validateSingleStaticFile[model.Calendar](report, filesByName, "calendar.txt", false,
+		"service_id", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday", "start_date", "end_date")
+	calendarDates := validateSingleStaticFile[calendarDateServiceId](report, filesByName, "calendar_dates.txt", false, "service_id")
+	fareAttributes := validateSingleStaticFile[model.FareAttribute](report, filesByName, "fare_attributes.txt", false, "fare_id", "price", "currency_type", "payment_method", "transfers")
+	fareRules := validateSingleStaticFile[model.FareRule](report, filesByName, "fare_rules.txt", false, "fare_id")
+	transfers := validateSingleStaticFile[model.Transfer](report, filesByName, "transfers.txt", false, "transfer_type")
+	levels := validateSingleStaticFile[model.Level](report, filesByName, "levels.txt", false, "level_id", "level_index")
+	pathways := validateSingleStaticFile[model.Pathway](report, filesByName, "pathways.txt", false, "pathway_id", "from_stop_id", "to_stop_id", "pathway_mode", "is_bidirectional")
+	translations := validateSingleStaticFile[model.Translation](report, filesByName, "translations.txt", false, "table_name", "field_name", "language", "translation")
+	attributions := validateSingleStaticFile[model.Attribution](report, filesByName, "attributions.txt", false, "organization_name")
+	locationGroups := validateSingleStaticFile[model.LocationGroup](report, filesByName, "location_groups.txt", false, "location_group_id")
+	locationGroupStops := validateSingleStaticFile[model.LocationGroupStop](report, filesByName, "location_group_stops.txt", false, "location_group_id", "stop_id")
+	bookingRules := validateSingleStaticFile[model.BookingRule](report, filesByName, "booking_rules.txt", false, "booking_rule_id", "booking_type")
+	validateSingleStaticFile[model.FeedInfo](report, filesByName, "feed_info.txt", false, "feed_publisher_name", "feed_publisher_url", "feed_lang")
+
+	_, hasCalendar := filesByName["calendar.txt"]
+	_, hasCalendarDates := filesByName["calendar_dates.txt"]
+	if !hasCalendar && !hasCalendarDates {
+		report.add("calendar.txt", 0, SeverityError, MissingRequiredFile, "one of calendar.txt or calendar_dates.txt is required")
+	}
+
+	agencyIds := validateAgencies(report, agencies)
+	stopIds := validateStops(report, stops)
+	routeIds := validateRoutes(report, routes, agencyIds, len(agencies))
+	serviceIds := validateCalendars(report, calendars)
+	for _, calendarDate := range calendarDates {
+		serviceIds[calendarDate.Record.ServiceId] = struct{}{}
+	}
+	tripIds := validateTrips(report, trips, routeIds, serviceIds)
+	locationIds := validateLocations(report, filesByName, stopIds)
+	locationGroupIds := validateLocationGroups(report, locationGroups, locationGroupStops, stopIds, locationIds)
+	bookingRuleIds := validateBookingRules(report, bookingRules, serviceIds)
+	validateStopTimes(report, stopTimes, tripIds, stopIds, locationGroupIds, locationIds, bookingRuleIds)
+	fareIds := validateFareAttributes(report, fareAttributes, agencyIds)
+	validateFareRules(report, fareRules, fareIds, routeIds, getZoneIds(stops))
+	validateTransfers(report, transfers, stopIds, routeIds, tripIds)
+	validateLevels(report, levels, stops)
+	validatePathways(report, pathways, stops)
+	validateTranslations(report, translations)
+	validateAttributions(report, attributions, agencyIds, routeIds, tripIds)
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		if report.Findings[i].File != report.Findings[j].File {
+			return report.Findings[i].File < report.Findings[j].File
+		}
+		return report.Findings[i].Line < report.Findings[j].Line
+	})
+	return report
+}
+
+// Parses every row of a file that can be parsed, adding a finding for every row that can't
+func validateSingleStaticFile[T any](report *ValidationReport, filesByName map[string]GtfsFile, name string, required bool, requiredColumns ...string) []lineRecord[T] {
+	var records []lineRecord[T]
+	f, ok := filesByName[name]
+	if !ok {
+		if required {
+			report.add(name, 0, SeverityError, MissingRequiredFile, "required file %s is missing", name)
+		}
+		return records
+	}
+
+	f.FileObj.Seek(0, io.SeekStart)
+	recordProvider, err := csv_parse.BeginParseCsv[T](f.FileObj)
+	if err != nil {
+		if err == csv_parse.EOF {
+			report.add(name, 0, SeverityError, MissingRequiredColumn, "file is empty")
+		} else {
+			report.add(name, 1, SeverityError, InvalidRow, "unable to read header: %s", err.Error())
+		}
+		return records
+	}
+
+	for _, column := range requiredColumns {
+		if !recordProvider.HasColumn(column) {
+			report.add(name, 1, SeverityError, MissingRequiredColumn, "required column %s is missing", column)
+		}
+	}
+
+	for {
+		record, err := recordProvider.FetchNext()
+		if err == csv_parse.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv_parse.ParseError
+			if errors.As(err, &parseErr) && parseErr.Column != "" {
+				report.add(name, parseErr.Line, SeverityError, InvalidRow, "column %s: %s", parseErr.Column, parseErr.Err.Error())
+			} else {
+				report.add(name, recordProvider.Line(), SeverityError, InvalidRow, "%s", err.Error())
+			}
+			continue
+		}
+		records = append(records, lineRecord[T]{Line: recordProvider.Line(), Record: record})
+	}
+	return records
+}
+
+func validateEnum[T ~int8](report *ValidationReport, file string, line int, column string, value T, min T, max T) {
+	if value < min || value > max {
+		report.add(file, line, SeverityError, InvalidEnumValue, "%s must be between %d and %d, found %d", column, min, max, value)
+	}
+}
+
+// Tracks ids seen in a file, reporting any duplicates
+func addUniqueId(report *ValidationReport, ids map[string]struct{}, file string, line int, column string, id string) {
+	if _, ok := ids[id]; ok {
+		report.add(file, line, SeverityError, DuplicateKey, "duplicate %s %s", column, id)
+	}
+	ids[id] = struct{}{}
+}
+
+func validateAgencies(report *ValidationReport, agencies []lineRecord[model.Agency]) map[string]struct{} {
+	agencyIds := make(map[string]struct{}, len(agencies))
+	for _, agency := range agencies {
+		if agency.Record.Id == "" {
+			if len(agencies) > 1 {
+				report.add("agency.txt", agency.Line, SeverityError, MissingRequiredColumn, "agency_id is required when there are multiple agencies")
+			}
+			continue
+		}
+		addUniqueId(report, agencyIds, "agency.txt", agency.Line, "agency_id", agency.Record.Id)
+	}
+	return agencyIds
+}
+
+func validateStops(report *ValidationReport, stops []lineRecord[model.Stop]) map[string]struct{} {
+	stopIds := make(map[string]struct{}, len(stops))
+	for _, stop := range stops {
+		addUniqueId(report, stopIds, "stops.txt", stop.Line, "stop_id", stop.Record.Id)
+	}
+	for _, stop := range stops {
+		validateEnum(report, "stops.txt", stop.Line, "location_type", stop.Record.LocationType, model.StopLocationType, model.BoardingArea)
+		validateEnum(report, "stops.txt", stop.Line, "wheelchair_boarding", stop.Record.WheelchairBoarding, 0, 2)
+
+		// Coordinates are optional for generic nodes and boarding areas
+		latitude, longitude := stop.Record.Latitude, stop.Record.Longitude
+		if !latitude.Valid || !longitude.Valid {
+			if stop.Record.LocationType <= model.EntranceExit {
+				report.add("stops.txt", stop.Line, SeverityError, MissingRequiredValue, "stop %s is missing stop_lat or stop_lon", stop.Record.Id)
+			}
+		} else if latitude.V < -90 || latitude.V > 90 || longitude.V < -180 || longitude.V > 180 {
+			report.add("stops.txt", stop.Line, SeverityError, InvalidCoordinates, "stop %s has out of range coordinates (%f, %f)", stop.Record.Id, latitude.V, longitude.V)
+		} else if latitude.V == 0 && longitude.V == 0 && stop.Record.LocationType <= model.EntranceExit {
+			report.add("stops.txt", stop.Line, SeverityWarning, SuspiciousCoordinates, "stop %s is located at (0, 0)", stop.Record.Id)
+		}
+
+		if stop.Record.ParentStationId != "" {
+			if _, ok := stopIds[stop.Record.ParentStationId]; !ok {
+				report.add("stops.txt", stop.Line, SeverityError, ForeignKeyViolation, "parent_station %s does not exist in stops.txt", stop.Record.ParentStationId)
+			}
+		}
+	}
+	return stopIds
+}
+
+func validateRoutes(report *ValidationReport, routes []lineRecord[model.Route], agencyIds map[string]struct{}, numAgencies int) map[string]struct{} {
+	routeIds := make(map[string]struct{}, len(routes))
+	for _, route := range routes {
+		addUniqueId(report, routeIds, "routes.txt", route.Line, "route_id", route.Record.Id)
+
+		if !isValidRouteType(route.Record.Type) {
+			report.add("routes.txt", route.Line, SeverityError, InvalidEnumValue, "route_type %d is not a valid route type", route.Record.Type)
+		}
+		validateEnum(report, "routes.txt", route.Line, "continuous_pickup", route.Record.ContinuousPickup, model.ContinuousStopping, model.MustCoordinateWithDriver)
+		validateEnum(report, "routes.txt", route.Line, "continuous_drop_off", route.Record.ContinuousDropoff, model.ContinuousStopping, model.MustCoordinateWithDriver)
+
+		if route.Record.AgencyId == "" {
+			if numAgencies > 1 {
+				report.add("routes.txt", route.Line, SeverityError, MissingRequiredColumn, "agency_id is required when there are multiple agencies")
+			}
+		} else if _, ok := agencyIds[route.Record.AgencyId]; !ok {
+			report.add("routes.txt", route.Line, SeverityError, ForeignKeyViolation, "agency_id %s does not exist in agency.txt", route.Record.AgencyId)
+		}
+	}
+	return routeIds
+}
+
+// Extended route types (100-1702) don't fit in a model.RouteType, so they fail to parse before reaching this check
+func isValidRouteType(routeType model.RouteType) bool {
+	return (routeType >= model.Tram && routeType <= model.Funicular) || routeType == model.Trolleybus || routeType == model.Monorail
+}
+
+func validateCalendars(report *ValidationReport, calendars []lineRecord[model.Calendar]) map[string]struct{} {
+	serviceIds := make(map[string]struct{}, len(calendars))
+	for _, calendar := range calendars {
+		addUniqueId(report, serviceIds, "calendar.txt", calendar.Line, "service_id", calendar.Record.ServiceId)
+		days := []model.ServiceAvailable{calendar.Record.Monday, calendar.Record.Tuesday, calendar.Record.Wednesday,
+			calendar.Record.Thursday, calendar.Record.Friday, calendar.Record.Saturday, calendar.Record.Sunday}
+		for i, day := range days {
+			validateEnum(report, "calendar.txt", calendar.Line, strings.ToLower(weekdaysStartingMonday[i]), day, model.ServiceIsNotAvailable, model.ServiceIsAvailable)
+		}
+		if calendar.Record.EndDate.Before(calendar.Record.StartDate) {
+			report.add("calendar.txt", calendar.Line, SeverityError, InvalidDateRange, "end_date is before start_date for service %s", calendar.Record.ServiceId)
+		}
+	}
+	return serviceIds
+}
+
+var weekdaysStartingMonday = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+func validateTrips(report *ValidationReport, trips []lineRecord[model.Trip], routeIds map[string]struct{}, serviceIds map[string]struct{}) map[string]struct{} {
+	tripIds := make(map[string]struct{}, len(trips))
+	for _, trip := range trips {
+		addUniqueId(report, tripIds, "trips.txt", trip.Line, "trip_id", trip.Record.Id)
+
+		if _, ok := routeIds[trip.Record.RouteId]; !ok {
+			report.add("trips.txt", trip.Line, SeverityError, ForeignKeyViolation, "route_id %s does not exist in routes.txt", trip.Record.RouteId)
+		}
+		if _, ok := serviceIds[trip.Record.ServiceId]; !ok {
+			report.add("trips.txt", trip.Line, SeverityError, ForeignKeyViolation, "service_id %s does not exist in calendar.txt or calendar_dates.txt", trip.Record.ServiceId)
+		}
+		if trip.Record.DirectionId.Valid {
+			validateEnum(report, "trips.txt", trip.Line, "direction_id", trip.Record.DirectionId.V, model.OutboundTravel, model.InboundTravel)
+		}
+		validateEnum(report, "trips.txt", trip.Line, "wheelchair_accessible", trip.Record.WheelchairAccessible, model.WheelchairNoInfo, model.NoWheelchairs)
+		validateEnum(report, "trips.txt", trip.Line, "bikes_allowed", trip.Record.BikesAllowed, model.BikesNoInfo, model.NoBikes)
+	}
+	return tripIds
+}
+
+func validateStopTimes(report *ValidationReport, stopTimes []lineRecord[model.StopTime], tripIds map[string]struct{}, stopIds map[string]struct{},
+	locationGroupIds map[string]struct{}, locationIds map[string]struct{}, bookingRuleIds map[string]struct{}) {
+	stopTimesByTripId := make(map[string][]*lineRecord[model.StopTime])
+	var tripIdsInOrder []string
+	for i := range stopTimes {
+		stopTime := &stopTimes[i]
+		if _, ok := tripIds[stopTime.Record.TripId]; !ok {
+			report.add("stop_times.txt", stopTime.Line, SeverityError, ForeignKeyViolation, "trip_id %s does not exist in trips.txt", stopTime.Record.TripId)
+		}
+		validateStopTimeReferences(report, stopTime, stopIds, locationGroupIds, locationIds, bookingRuleIds)
+		validatePickupDropOffWindow(report, stopTime)
+		validateEnum(report, "stop_times.txt", stopTime.Line, "pickup_type", stopTime.Record.PickupType, model.RegularPickupDropoff, model.CoordinateWithDriverPickupDropoff)
+		validateEnum(report, "stop_times.txt", stopTime.Line, "drop_off_type", stopTime.Record.DropoffType, model.RegularPickupDropoff, model.CoordinateWithDriverPickupDropoff)
+		if stopTime.Record.ContinuousPickup.Valid {
+			validateEnum(report, "stop_times.txt", stopTime.Line, "continuous_pickup", stopTime.Record.ContinuousPickup.V, model.ContinuousStopping, model.MustCoordinateWithDriver)
+		}
+
+		if _, ok := stopTimesByTripId[stopTime.Record.TripId]; !ok {
+			tripIdsInOrder = append(tripIdsInOrder, stopTime.Record.TripId)
+		}
+		stopTimesByTripId[stopTime.Record.TripId] = append(stopTimesByTripId[stopTime.Record.TripId], stopTime)
+	}
+
+	for _, tripId := range tripIdsInOrder {
+		tripStopTimes := stopTimesByTripId[tripId]
+		// Stop times don't need to be in order in the file, but stop_sequence must be unique per trip
+		sort.SliceStable(tripStopTimes, func(i, j int) bool {
+			return tripStopTimes[i].Record.StopSequence < tripStopTimes[j].Record.StopSequence
+		})
+
+		var lastTime model.ArrivalDepartureTime
+		for i, stopTime := range tripStopTimes {
+			if i > 0 && stopTime.Record.StopSequence == tripStopTimes[i-1].Record.StopSequence {
+				report.add("stop_times.txt", stopTime.Line, SeverityError, NonIncreasingStopSequence, "stop_sequence %d is repeated for trip %s", stopTime.Record.StopSequence, tripId)
+			}
+
+			// Empty times are allowed for stops that are not timepoints, but not for the first and last stops
+			arrival := stopTime.Record.ArrivalTime
+			departure := stopTime.Record.DepartureTime
+			// Demand-responsive stop times have a pickup and drop off window instead
+			isDemandResponsive := stopTime.Record.StartPickupDropOffWindow.Valid || stopTime.Record.EndPickupDropOffWindow.Valid
+			if (i == 0 || i == len(tripStopTimes)-1) && !arrival.Valid && !departure.Valid && !isDemandResponsive {
+				report.add("stop_times.txt", stopTime.Line, SeverityError, MissingRequiredValue, "the first and last stops of trip %s must have an arrival_time or departure_time", tripId)
+			}
+			if arrival.Valid && departure.Valid && departure.V < arrival.V {
+				report.add("stop_times.txt", stopTime.Line, SeverityError, DepartureBeforeArrival, "departure_time is before arrival_time for trip %s", tripId)
+			}
+			if arrival.Valid {
+				if arrival.V < lastTime {
+					report.add("stop_times.txt", stopTime.Line, SeverityError, DecreasingStopTime, "arrival_time is before the previous stop's departure for trip %s", tripId)
+				}
+				lastTime = arrival.V
+			}
+			if departure.Valid {
+				lastTime = departure.V
+			}
+		}
+	}
+}
+
+// Every stop time is at exactly one stop, location group or location
+func validateStopTimeReferences(report *ValidationReport, stopTime *lineRecord[model.StopTime], stopIds map[string]struct{},
+	locationGroupIds map[string]struct{}, locationIds map[string]struct{}, bookingRuleIds map[string]struct{}) {
+	record := stopTime.Record
+	numLocations := 0
+	for _, id := range []string{record.StopId, record.LocationGroupId, record.LocationId} {
+		if id != "" {
+			numLocations++
+		}
+	}
+	if numLocations == 0 {
+		report.add("stop_times.txt", stopTime.Line, SeverityError, MissingRequiredValue, "one of stop_id, location_group_id or location_id is required")
+	} else if numLocations > 1 {
+		report.add("stop_times.txt", stopTime.Line, SeverityError, InvalidRow, "only one of stop_id, location_group_id or location_id can be set")
+	}
+
+	for _, column := range []struct {
+		name string
+		id   string
+		ids  map[string]struct{}
+		file string
+	}{
+		{"stop_id", record.StopId, stopIds, "stops.txt"},
+		{"location_group_id", record.LocationGroupId, locationGroupIds, "location_groups.txt"},
+		{"location_id", record.LocationId, locationIds, "locations.geojson"},
+		{"pickup_booking_rule_id", record.PickupBookingRuleId, bookingRuleIds, "booking_rules.txt"},
+		{"drop_off_booking_rule_id", record.DropOffBookingRuleId, bookingRuleIds, "booking_rules.txt"},
+	} {
+		if column.id == "" {
+			continue
+		}
+		if _, ok := column.ids[column.id]; !ok {
+			report.add("stop_times.txt", stopTime.Line, SeverityError, ForeignKeyViolation, "%s %s does not exist in %s", column.name, column.id, column.file)
+		}
+	}
+}
+
+func validatePickupDropOffWindow(report *ValidationReport, stopTime *lineRecord[model.StopTime]) {
+	record := stopTime.Record
+	start, end := record.StartPickupDropOffWindow, record.EndPickupDropOffWindow
+	if !start.Valid && !end.Valid {
+		if record.LocationGroupId != "" || record.LocationId != "" {
+			report.add("stop_times.txt", stopTime.Line, SeverityError, MissingRequiredValue, "start_pickup_drop_off_window and end_pickup_drop_off_window are required for a location group or location")
+		}
+		return
+	}
+	if !start.Valid || !end.Valid {
+		report.add("stop_times.txt", stopTime.Line, SeverityError, MissingRequiredValue, "start_pickup_drop_off_window and end_pickup_drop_off_window must both be set")
+	} else if end.V < start.V {
+		report.add("stop_times.txt", stopTime.Line, SeverityError, InvalidRow, "end_pickup_drop_off_window is before start_pickup_drop_off_window for trip %s", record.TripId)
+	}
+	if record.ArrivalTime.Valid || record.DepartureTime.Valid {
+		report.add("stop_times.txt", stopTime.Line, SeverityError, InvalidRow, "arrival_time and departure_time must be empty when there is a pickup and drop off window")
+	}
+}
+
+// Ids of stops, locations and location groups share one namespace, since stop times can refer to any of them
+func validateLocations(report *ValidationReport, filesByName map[string]GtfsFile, stopIds map[string]struct{}) map[string]struct{} {
+	locationIds := make(map[string]struct{})
+	f, ok := filesByName["locations.geojson"]
+	if !ok {
+		return locationIds
+	}
+	f.FileObj.Seek(0, io.SeekStart)
+	locations, err := decodeLocationsGeoJson(f.FileObj)
+	if err != nil {
+		report.add("locations.geojson", 0, SeverityError, InvalidRow, "unable to read GeoJSON: %s", err.Error())
+		return locationIds
+	}
+	for i, location := range locations {
+		if location.Id == "" {
+			report.add("locations.geojson", 0, SeverityError, MissingRequiredValue, "feature %d has no id", i+1)
+			continue
+		}
+		if _, ok := locationIds[location.Id]; ok {
+			report.addForEntity("locations.geojson", location.Id, SeverityError, DuplicateKey, "duplicate id %s", location.Id)
+		} else if _, ok := stopIds[location.Id]; ok {
+			report.addForEntity("locations.geojson", location.Id, SeverityError, DuplicateKey, "id %s is also a stop_id in stops.txt", location.Id)
+		}
+		locationIds[location.Id] = struct{}{}
+		if geometryType := getGeometryType(location.Geometry); geometryType != "Polygon" && geometryType != "MultiPolygon" {
+			report.addForEntity("locations.geojson", location.Id, SeverityError, InvalidRow, "geometry must be a Polygon or MultiPolygon, found %q", geometryType)
+		}
+	}
+	return locationIds
+}
+
+func validateLocationGroups(report *ValidationReport, locationGroups []lineRecord[model.LocationGroup], locationGroupStops []lineRecord[model.LocationGroupStop],
+	stopIds map[string]struct{}, locationIds map[string]struct{}) map[string]struct{} {
+	locationGroupIds := make(map[string]struct{}, len(locationGroups))
+	for _, locationGroup := range locationGroups {
+		addUniqueId(report, locationGroupIds, "location_groups.txt", locationGroup.Line, "location_group_id", locationGroup.Record.Id)
+		_, isStopId := stopIds[locationGroup.Record.Id]
+		_, isLocationId := locationIds[locationGroup.Record.Id]
+		if isStopId || isLocationId {
+			report.add("location_groups.txt", locationGroup.Line, SeverityError, DuplicateKey, "location_group_id %s is also the id of a stop or location", locationGroup.Record.Id)
+		}
+	}
+	for _, groupStop := range locationGroupStops {
+		if _, ok := locationGroupIds[groupStop.Record.LocationGroupId]; !ok {
+			report.add("location_group_stops.txt", groupStop.Line, SeverityError, ForeignKeyViolation, "location_group_id %s does not exist in location_groups.txt", groupStop.Record.LocationGroupId)
+		}
+		if _, ok := stopIds[groupStop.Record.StopId]; !ok {
+			report.add("location_group_stops.txt", groupStop.Line, SeverityError, ForeignKeyViolation, "stop_id %s does not exist in stops.txt", groupStop.Record.StopId)
+		}
+	}
+	return locationGroupIds
+}
+
+func validateBookingRules(report *ValidationReport, bookingRules []lineRecord[model.BookingRule], serviceIds map[string]struct{}) map[string]struct{} {
+	bookingRuleIds := make(map[string]struct{}, len(bookingRules))
+	for _, bookingRule := range bookingRules {
+		record := bookingRule.Record
+		addUniqueId(report, bookingRuleIds, "booking_rules.txt", bookingRule.Line, "booking_rule_id", record.Id)
+		validateEnum(report, "booking_rules.txt", bookingRule.Line, "booking_type", record.BookingType, model.RealTimeBooking, model.PriorDaysBooking)
+		switch record.BookingType {
+		case model.SameDayBooking:
+			if !record.PriorNoticeDurationMin.Valid {
+				report.add("booking_rules.txt", bookingRule.Line, SeverityError, MissingRequiredValue, "prior_notice_duration_min is required for same-day booking")
+			}
+		case model.PriorDaysBooking:
+			if !record.PriorNoticeLastDay.Valid || !record.PriorNoticeLastTime.Valid {
+				report.add("booking_rules.txt", bookingRule.Line, SeverityError, MissingRequiredValue, "prior_notice_last_day and prior_notice_last_time are required for booking days in advance")
+			}
+		}
+		if record.PriorNoticeServiceId != "" {
+			if _, ok := serviceIds[record.PriorNoticeServiceId]; !ok {
+				report.add("booking_rules.txt", bookingRule.Line, SeverityError, ForeignKeyViolation, "prior_notice_service_id %s does not exist in calendar.txt or calendar_dates.txt", record.PriorNoticeServiceId)
+			}
+		}
+	}
+	return bookingRuleIds
+}
+
+func getZoneIds(stops []lineRecord[model.Stop]) map[string]struct{} {
+	zoneIds := make(map[string]struct{})
+	for _, stop := range stops {
+		if stop.Record.ZoneId != "" {
+			zoneIds[stop.Record.ZoneId] = struct{}{}
+		}
+	}
+	return zoneIds
+}
+
+func validateFareAttributes(report *ValidationReport, fareAttributes []lineRecord[model.FareAttribute], agencyIds map[string]struct{}) map[string]struct{} {
+	fareIds := make(map[string]struct{}, len(fareAttributes))
+	for _, fare := range fareAttributes {
+		addUniqueId(report, fareIds, "fare_attributes.txt", fare.Line, "fare_id", fare.Record.Id)
+
+		if fare.Record.Price < 0 {
+			report.add("fare_attributes.txt", fare.Line, SeverityError, InvalidRow, "price of fare %s is negative", fare.Record.Id)
+		}
+		validateEnum(report, "fare_attributes.txt", fare.Line, "payment_method", fare.Record.PaymentMethod, model.PaidOnBoard, model.PaidBeforeBoarding)
+		if fare.Record.Transfers.Valid {
+			validateEnum(report, "fare_attributes.txt", fare.Line, "transfers", fare.Record.Transfers.V, model.NoTransfers, model.TwoTransfers)
+		}
+		if fare.Record.AgencyId != "" {
+			if _, ok := agencyIds[fare.Record.AgencyId]; !ok {
+				report.add("fare_attributes.txt", fare.Line, SeverityError, ForeignKeyViolation, "agency_id %s does not exist in agency.txt", fare.Record.AgencyId)
+			}
+		}
+	}
+	return fareIds
+}
+
+func validateFareRules(report *ValidationReport, fareRules []lineRecord[model.FareRule], fareIds map[string]struct{}, routeIds map[string]struct{}, zoneIds map[string]struct{}) {
+	for _, rule := range fareRules {
+		if _, ok := fareIds[rule.Record.FareId]; !ok {
+			report.add("fare_rules.txt", rule.Line, SeverityError, ForeignKeyViolation, "fare_id %s does not exist in fare_attributes.txt", rule.Record.FareId)
+		}
+		if rule.Record.RouteId != "" {
+			if _, ok := routeIds[rule.Record.RouteId]; !ok {
+				report.add("fare_rules.txt", rule.Line, SeverityError, ForeignKeyViolation, "route_id %s does not exist in routes.txt", rule.Record.RouteId)
+			}
+		}
+		zoneColumns := []struct {
+			name   string
+			zoneId string
+		}{{"origin_id", rule.Record.OriginId}, {"destination_id", rule.Record.DestinationId}, {"contains_id", rule.Record.ContainsId}}
+		for _, column := range zoneColumns {
+			if column.zoneId == "" {
+				continue
+			}
+			if _, ok := zoneIds[column.zoneId]; !ok {
+				report.add("fare_rules.txt", rule.Line, SeverityError, ForeignKeyViolation, "%s %s is not the zone_id of any stop", column.name, column.zoneId)
+			}
+		}
+	}
+}
+
+func validateTransfers(report *ValidationReport, transfers []lineRecord[model.Transfer], stopIds map[string]struct{}, routeIds map[string]struct{}, tripIds map[string]struct{}) {
+	for _, transfer := range transfers {
+		record := transfer.Record
+		validateEnum(report, "transfers.txt", transfer.Line, "transfer_type", record.TransferType, model.RecommendedTransfer, model.InSeatTransferNotAllowed)
+		// In-seat transfers are between trips, every other transfer is at a stop
+		if record.TransferType == model.InSeatTransfer || record.TransferType == model.InSeatTransferNotAllowed {
+			if record.FromTripId == "" || record.ToTripId == "" {
+				report.add("transfers.txt", transfer.Line, SeverityError, MissingRequiredValue, "from_trip_id and to_trip_id are required for transfer_type %d", record.TransferType)
+			}
+		} else if record.TransferType != model.RecommendedTransfer && (record.FromStopId == "" || record.ToStopId == "") {
+			report.add("transfers.txt", transfer.Line, SeverityError, MissingRequiredValue, "from_stop_id and to_stop_id are required for transfer_type %d", record.TransferType)
+		}
+
+		idColumns := []struct {
+			name string
+			id   string
+			ids  map[string]struct{}
+			file string
+		}{
+			{"from_stop_id", record.FromStopId, stopIds, "stops.txt"},
+			{"to_stop_id", record.ToStopId, stopIds, "stops.txt"},
+			{"from_route_id", record.FromRouteId, routeIds, "routes.txt"},
+			{"to_route_id", record.ToRouteId, routeIds, "routes.txt"},
+			{"from_trip_id", record.FromTripId, tripIds, "trips.txt"},
+			{"to_trip_id", record.ToTripId, tripIds, "trips.txt"},
+		}
+		for _, column := range idColumns {
+			if column.id == "" {
+				continue
+			}
+			if _, ok := column.ids[column.id]; !ok {
+				report.add("transfers.txt", transfer.Line, SeverityError, ForeignKeyViolation, "%s %s does not exist in %s", column.name, column.id, column.file)
+			}
+		}
+		if record.MinTransferTime.Valid && record.MinTransferTime.V < 0 {
+			report.add("transfers.txt", transfer.Line, SeverityError, InvalidRow, "min_transfer_time must not be negative, found %d", record.MinTransferTime.V)
+		}
+	}
+}
+
+func validateLevels(report *ValidationReport, levels []lineRecord[model.Level], stops []lineRecord[model.Stop]) {
+	levelIds := make(map[string]struct{}, len(levels))
+	for _, level := range levels {
+		addUniqueId(report, levelIds, "levels.txt", level.Line, "level_id", level.Record.Id)
+	}
+	for _, stop := range stops {
+		if stop.Record.LevelId == "" {
+			continue
+		}
+		if _, ok := levelIds[stop.Record.LevelId]; !ok {
+			report.add("stops.txt", stop.Line, SeverityError, ForeignKeyViolation, "level_id %s does not exist in levels.txt", stop.Record.LevelId)
+		}
+	}
+}
+
+func validatePathways(report *ValidationReport, pathways []lineRecord[model.Pathway], stops []lineRecord[model.Stop]) {
+	locationTypesByStopId := make(map[string]model.LocationType, len(stops))
+	for _, stop := range stops {
+		locationTypesByStopId[stop.Record.Id] = stop.Record.LocationType
+	}
+	pathwayIds := make(map[string]struct{}, len(pathways))
+	for _, pathway := range pathways {
+		record := pathway.Record
+		addUniqueId(report, pathwayIds, "pathways.txt", pathway.Line, "pathway_id", record.Id)
+		validateEnum(report, "pathways.txt", pathway.Line, "pathway_mode", record.Mode, model.Walkway, model.ExitGate)
+		validateEnum(report, "pathways.txt", pathway.Line, "is_bidirectional", record.IsBidirectional, model.Unidirectional, model.Bidirectional)
+		if record.Mode == model.ExitGate && record.IsBidirectional == model.Bidirectional {
+			report.add("pathways.txt", pathway.Line, SeverityError, InvalidRow, "exit gate %s must not be bidirectional", record.Id)
+		}
+		for _, column := range []struct {
+			name   string
+			stopId string
+		}{{"from_stop_id", record.FromStopId}, {"to_stop_id", record.ToStopId}} {
+			locationType, ok := locationTypesByStopId[column.stopId]
+			if !ok {
+				report.add("pathways.txt", pathway.Line, SeverityError, ForeignKeyViolation, "%s %s does not exist in stops.txt", column.name, column.stopId)
+			} else if locationType == model.Station {
+				report.add("pathways.txt", pathway.Line, SeverityError, InvalidRow, "%s %s is a station, which can't be the end of a pathway", column.name, column.stopId)
+			}
+		}
+		if record.TraversalTime.Valid && record.TraversalTime.V <= 0 {
+			report.add("pathways.txt", pathway.Line, SeverityError, InvalidRow, "traversal_time must be positive, found %d", record.TraversalTime.V)
+		}
+	}
+}
+
+var translatableTableNames = map[string]struct{}{
+	"agency": {}, "stops": {}, "routes": {}, "trips": {}, "stop_times": {}, "pathways": {}, "levels": {}, "feed_info": {}, "attributions": {},
+}
+
+func validateTranslations(report *ValidationReport, translations []lineRecord[model.Translation]) {
+	for _, translation := range translations {
+		record := translation.Record
+		if _, ok := translatableTableNames[record.TableName]; !ok {
+			report.add("translations.txt", translation.Line, SeverityError, InvalidRow, "table_name %s can't be translated", record.TableName)
+			continue
+		}
+		// feed_info.txt has a single record, so its translations don't say which record they are for
+		if record.TableName == "feed_info" {
+			if record.RecordId != "" || record.RecordSubId != "" || record.FieldValue != "" {
+				report.add("translations.txt", translation.Line, SeverityError, InvalidRow, "record_id, record_sub_id and field_value must be empty for table_name feed_info")
+			}
+			continue
+		}
+		if record.RecordId != "" && record.FieldValue != "" {
+			report.add("translations.txt", translation.Line, SeverityError, InvalidRow, "only one of record_id or field_value can be set")
+		} else if record.RecordId == "" && record.FieldValue == "" {
+			report.add("translations.txt", translation.Line, SeverityError, MissingRequiredValue, "one of record_id or field_value is required")
+		}
+		if record.TableName == "stop_times" && record.RecordId != "" && record.RecordSubId == "" {
+			report.add("translations.txt", translation.Line, SeverityError, MissingRequiredValue, "record_sub_id is required for stop_times translations with a record_id")
+		} else if record.TableName != "stop_times" && record.RecordSubId != "" {
+			report.add("translations.txt", translation.Line, SeverityError, InvalidRow, "record_sub_id is only used for table_name stop_times")
+		}
+	}
+}
+
+func validateAttributions(report *ValidationReport, attributions []lineRecord[model.Attribution], agencyIds map[string]struct{}, routeIds map[string]struct{}, tripIds map[string]struct{}) {
+	attributionIds := make(map[string]struct{}, len(attributions))
+	for _, attribution := range attributions {
+		record := attribution.Record
+		if record.Id != "" {
+			addUniqueId(report, attributionIds, "attributions.txt", attribution.Line, "attribution_id", record.Id)
+		}
+		validateEnum(report, "attributions.txt", attribution.Line, "is_producer", record.IsProducer, model.DoesNotHaveRole, model.HasRole)
+		validateEnum(report, "attributions.txt", attribution.Line, "is_operator", record.IsOperator, model.DoesNotHaveRole, model.HasRole)
+		validateEnum(report, "attributions.txt", attribution.Line, "is_authority", record.IsAuthority, model.DoesNotHaveRole, model.HasRole)
+		if record.IsProducer != model.HasRole && record.IsOperator != model.HasRole && record.IsAuthority != model.HasRole {
+			report.add("attributions.txt", attribution.Line, SeverityError, MissingRequiredValue, "one of is_producer, is_operator or is_authority must be 1")
+		}
+
+		numIds := 0
+		for _, column := range []struct {
+			name string
+			id   string
+			ids  map[string]struct{}
+			file string
+		}{
+			{"agency_id", record.AgencyId, agencyIds, "agency.txt"},
+			{"route_id", record.RouteId, routeIds, "routes.txt"},
+			{"trip_id", record.TripId, tripIds, "trips.txt"},
+		} {
+			if column.id == "" {
+				continue
+			}
+			numIds++
+			if _, ok := column.ids[column.id]; !ok {
+				report.add("attributions.txt", attribution.Line, SeverityError, ForeignKeyViolation, "%s %s does not exist in %s", column.name, column.id, column.file)
+			}
+		}
+		if numIds > 1 {
+			report.add("attributions.txt", attribution.Line, SeverityError, InvalidRow, "only one of agency_id, route_id or trip_id can be set")
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createGtfsFiles(filesByName map[string]string) *GtfsFileCollection {
+	var gtfsFiles []GtfsFile
+	for name, contents := range filesByName {
+		gtfsFiles = append(gtfsFiles, GtfsFile{Name: name, FileObj: strings.NewReader(contents)})
+	}
+	return &GtfsFileCollection{GtfsFiles: gtfsFiles}
+}
+
+func getValidGtfsFiles() map[string]string {
+	return map[string]string{
+		"agency.txt":     "agency_id,agency_name,agency_url,agency_timezone\nrtd,RTD,https://www.rtd-denver.com,America/Denver",
+		"stops.txt":      "stop_id,stop_name,stop_lat,stop_lon\nstop1,Union Station,39.7525,-105.0001\nstop2,Civic Center,39.7392,-104.9875",
+		"routes.txt":     "route_id,agency_id,route_short_name,route_type\nroute15,rtd,15,3",
+		"trips.txt":      "route_id,service_id,trip_id\nroute15,wkdayService,trip1",
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\ntrip1,08:30:00,08:30:00,stop1,1\ntrip1,08:45:00,08:46:00,stop2,2",
+		"calendar.txt":   "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\nwkdayService,1,1,1,1,1,0,0,20230501,20230901",
+	}
+}
+
+// The files of getValidGtfsFiles, with the files in a folder of test_files added, like the fares
+// files in test_files/fares. A file in the folder replaces the valid feed's file of the same name
+func getGtfsFilesWith(t *testing.T, folder string) map[string]string {
+	files := getValidGtfsFiles()
+	entries, err := os.ReadDir(path.Join(getTestFilesPath(), folder))
+	assert.NoError(t, err)
+	for _, entry := range entries {
+		contents, err := os.ReadFile(path.Join(getTestFilesPath(), folder, entry.Name()))
+		assert.NoError(t, err)
+		files[entry.Name()] = string(contents)
+	}
+	return files
+}
+
+func TestValidateValidFeed(t *testing.T) {
+	report := ValidateStaticGtfsFiles(createGtfsFiles(getValidGtfsFiles()))
+	assert.Empty(t, report.Findings)
+}
+
+func TestValidateCollectsAllFindings(t *testing.T) {
+	files := getValidGtfsFiles()
+	delete(files, "calendar.txt")
+	files["stops.txt"] = "stop_id,stop_name,stop_lat,stop_lon,location_type\nstop1,Union Station,39.7525,-105.0001,0\nstop1,Union Station,39.7525,-105.0001,0\nstop2,Civic Center,0,0,0\nstop4,Colfax,39.74,-104.98,9"
+	files["routes.txt"] = "route_id,agency_id,route_short_name\nroute15,metro,15"
+	files["stop_times.txt"] = "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+		"trip1,08:30:00,08:30:00,stop1,1\n" +
+		"trip1,08:25:00,08:24:00,stop3,2\n" +
+		"trip1,garbage,08:50:00,stop2,3\n" +
+		"trip2,08:55:00,08:55:00,stop2,1"
+
+	report := ValidateStaticGtfsFiles(createGtfsFiles(files))
+	assert.Contains(t, report.Findings, ValidationFinding{File: "calendar.txt", Severity: SeverityError, Code: MissingRequiredFile, Message: "one of calendar.txt or calendar_dates.txt is required"})
+	assert.Contains(t, report.Findings, ValidationFinding{File: "routes.txt", Line: 1, Severity: SeverityError, Code: MissingRequiredColumn, Message: "required column route_type is missing"})
+	assert.Contains(t, report.Findings, ValidationFinding{File: "routes.txt", Line: 2, Severity: SeverityError, Code: ForeignKeyViolation, Message: "agency_id metro does not exist in agency.txt"})
+	assert.Contains(t, report.Findings, ValidationFinding{File: "stops.txt", Line: 3, Severity: SeverityError, Code: DuplicateKey, Message: "duplicate stop_id stop1"})
+	assert.Contains(t, report.Findings, ValidationFinding{File: "stops.txt", Line: 5, Severity: SeverityError, Code: InvalidEnumValue, Message: "location_type must be between 0 and 4, found 9"})
+	assert.Contains(t, report.Findings, ValidationFinding{File: "trips.txt", Line: 2, Severity: SeverityError, Code: ForeignKeyViolation, Message: "service_id wkdayService does not exist in calendar.txt or calendar_dates.txt"})
+	assert.Contains(t, report.Findings, ValidationFinding{File: "stop_times.txt", Line: 3, Severity: SeverityError, Code: ForeignKeyViolation, Message: "stop_id stop3 does not exist in stops.txt"})
+	assert.Contains(t, report.Findings, ValidationFinding{File: "stop_times.txt", Line: 3, Severity: SeverityError, Code: DepartureBeforeArrival, Message: "departure_time is before arrival_time for trip trip1"})
+	assert.Contains(t, report.Findings, ValidationFinding{File: "stop_times.txt", Line: 3, Severity: SeverityError, Code: DecreasingStopTime, Message: "arrival_time is before the previous stop's departure for trip trip1"})
+	assert.Contains(t, report.Findings, ValidationFinding{File: "stop_times.txt", Line: 4, Severity: SeverityError, Code: InvalidRow, Message: "column arrival_time: Invalid ArrivalDepartureTime garbage"})
+	assert.Contains(t, report.Findings, ValidationFinding{File: "stop_times.txt", Line: 5, Severity: SeverityError, Code: ForeignKeyViolation, Message: "trip_id trip2 does not exist in trips.txt"})
+
+	// Stop at (0, 0) is only a warning
+	assert.Contains(t, report.Findings, ValidationFinding{File: "stops.txt", Line: 4, Severity: SeverityWarning, Code: SuspiciousCoordinates, Message: "stop stop2 is located at (0, 0)"})
+	assert.Equal(t, 1, report.CountBySeverity(SeverityWarning))
+}
+
+func TestValidateRepeatedStopSequence(t *testing.T) {
+	files := getValidGtfsFiles()
+	files["stop_times.txt"] = "trip_id,arrival_time,departure_time,stop_id,stop_sequence\ntrip1,08:30:00,08:30:00,stop1,1\ntrip1,08:45:00,08:46:00,stop2,1"
+	report := ValidateStaticGtfsFiles(createGtfsFiles(files))
+	assert.Equal(t, []ValidationFinding{{File: "stop_times.txt", Line: 3, Severity: SeverityError, Code: NonIncreasingStopSequence, Message: "stop_sequence 1 is repeated for trip trip1"}}, report.Findings)
+}
+
+func TestValidateMissingRequiredValues(t *testing.T) {
+	files := getValidGtfsFiles()
+	files["stops.txt"] = "stop_id,stop_name,stop_lat,stop_lon,location_type\nstop1,Union Station,39.7525,-105.0001,0\nstop2,Civic Center,,,0\nnode1,Mezzanine,,,3"
+	files["stop_times.txt"] = "trip_id,arrival_time,departure_time,stop_id,stop_sequence\ntrip1,08:30:00,08:30:00,stop1,1\ntrip1,,,node1,2\ntrip1,,,stop2,3"
+	report := ValidateStaticGtfsFiles(createGtfsFiles(files))
+	assert.ElementsMatch(t, []ValidationFinding{
+		{File: "stops.txt", Line: 3, Severity: SeverityError, Code: MissingRequiredValue, Message: "stop stop2 is missing stop_lat or stop_lon"},
+		{File: "stop_times.txt", Line: 4, Severity: SeverityError, Code: MissingRequiredValue, Message: "the first and last stops of trip trip1 must have an arrival_time or departure_time"},
+	}, report.Findings)
+}
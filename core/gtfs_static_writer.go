@@ -0,0 +1,236 @@
+package core
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/samc1213/gtfs-analyze/csv_parse"
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+)
+
+// Reconstructs the GTFS zip file for a feed version stored in the database, and writes it to outputPath
+func ExportStaticGtfsToPath(dbPath string, version string, outputPath string, logLevel log.Level) error {
+	logger := log.New(logLevel)
+
+	db, err := openExistingDb(logger, dbPath, logLevel)
+	if err != nil {
+		return err
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDb.Close()
+
+	logger.Info("Loading feed version %s from database", version)
+	feed, err := GetFeedByVersion(version, db)
+	if err != nil {
+		return err
+	}
+	logger.Info("Done loading feed version %s from database", version)
+
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+
+	logger.Info("Writing GTFS zip file to %s", outputPath)
+	err = WriteStaticGtfsToZip(feed, outputFile)
+	if err != nil {
+		return err
+	}
+	logger.Info("Done writing GTFS zip file to %s", outputPath)
+
+	return outputFile.Close()
+}
+
+// Writes each file of the static feed into a GTFS zip archive. Optional files are
+// only written if the feed has rows for them, and feed_info.txt only if the feed published one
+func WriteStaticGtfsToZip(feed *model.GtfsStaticFeed, output io.Writer) error {
+	archive := zip.NewWriter(output)
+
+	err := writeSingleStaticFile(archive, "agency.txt", feed.Agency)
+	if err != nil {
+		return err
+	}
+	err = writeSingleStaticFile(archive, "stops.txt", feed.Stop)
+	if err != nil {
+		return err
+	}
+	err = writeSingleStaticFile(archive, "routes.txt", feed.Route)
+	if err != nil {
+		return err
+	}
+	err = writeSingleStaticFile(archive, "trips.txt", feed.Trip)
+	if err != nil {
+		return err
+	}
+	err = writeSingleStaticFile(archive, "stop_times.txt", feed.StopTime)
+	if err != nil {
+		return err
+	}
+	if len(feed.ShapePoint) > 0 {
+		err = writeSingleStaticFile(archive, "shapes.txt", feed.ShapePoint)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.Calendar) > 0 {
+		err = writeSingleStaticFile(archive, "calendar.txt", feed.Calendar)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.Transfer) > 0 {
+		err = writeSingleStaticFile(archive, "transfers.txt", feed.Transfer)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.Level) > 0 {
+		err = writeSingleStaticFile(archive, "levels.txt", feed.Level)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.Pathway) > 0 {
+		err = writeSingleStaticFile(archive, "pathways.txt", feed.Pathway)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.Translation) > 0 {
+		err = writeSingleStaticFile(archive, "translations.txt", feed.Translation)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.Attribution) > 0 {
+		err = writeSingleStaticFile(archive, "attributions.txt", feed.Attribution)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.LocationGroup) > 0 {
+		err = writeSingleStaticFile(archive, "location_groups.txt", feed.LocationGroup)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.LocationGroupStop) > 0 {
+		err = writeSingleStaticFile(archive, "location_group_stops.txt", feed.LocationGroupStop)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.BookingRule) > 0 {
+		err = writeSingleStaticFile(archive, "booking_rules.txt", feed.BookingRule)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.Location) > 0 {
+		err = writeLocationsGeoJson(archive, feed.Location)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.FareAttribute) > 0 {
+		err = writeSingleStaticFile(archive, "fare_attributes.txt", feed.FareAttribute)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.FareRule) > 0 {
+		err = writeSingleStaticFile(archive, "fare_rules.txt", feed.FareRule)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.FareMedia) > 0 {
+		err = writeSingleStaticFile(archive, "fare_media.txt", feed.FareMedia)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.FareProduct) > 0 {
+		err = writeSingleStaticFile(archive, "fare_products.txt", feed.FareProduct)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.FareLegRule) > 0 {
+		err = writeSingleStaticFile(archive, "fare_leg_rules.txt", feed.FareLegRule)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.FareTransferRule) > 0 {
+		err = writeSingleStaticFile(archive, "fare_transfer_rules.txt", feed.FareTransferRule)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.Area) > 0 {
+		err = writeSingleStaticFile(archive, "areas.txt", feed.Area)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.StopArea) > 0 {
+		err = writeSingleStaticFile(archive, "stop_areas.txt", feed.StopArea)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.Timeframe) > 0 {
+		err = writeSingleStaticFile(archive, "timeframes.txt", feed.Timeframe)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.Network) > 0 {
+		err = writeSingleStaticFile(archive, "networks.txt", feed.Network)
+		if err != nil {
+			return err
+		}
+	}
+	if len(feed.RouteNetwork) > 0 {
+		err = writeSingleStaticFile(archive, "route_networks.txt", feed.RouteNetwork)
+		if err != nil {
+			return err
+		}
+	}
+	if feedInfo, ok := getPublishedFeedInfo(feed.FeedInfo); ok {
+		err = writeSingleStaticFile(archive, "feed_info.txt", []model.FeedInfo{feedInfo})
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// Returns the feed_info.txt row of a feed as it was published, with the version it had before it was
+// named after its namespace. A feed without a feed_info.txt only has the version and download time
+// this application gave it, and has no row to write
+func getPublishedFeedInfo(feedInfo model.FeedInfo) (model.FeedInfo, bool) {
+	if feedInfo.Namespace != "" {
+		feedInfo.Version = strings.TrimPrefix(feedInfo.Version, feedInfo.Namespace+"/")
+	}
+	published := feedInfo.PublisherName != "" || feedInfo.PublisherUrl != "" || feedInfo.Language != "" || feedInfo.DefaultLanguage != "" ||
+		!feedInfo.StartDate.IsZero() || !feedInfo.EndDate.IsZero() || feedInfo.ContactEmail != "" || feedInfo.ContactUrl != "" || len(feedInfo.Extra) > 0
+	return feedInfo, published
+}
+
+func writeSingleStaticFile[T any](archive *zip.Writer, name string, elements []T) error {
+	fileWriter, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	return csv_parse.WriteCsv(fileWriter, elements)
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"archive/zip"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/csv_parse"
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportStaticGtfsRoundTrip(t *testing.T) {
+	feed, tripOneId, stopOneId, _, _ := createStaticFeed()
+	feed.Agency[0].Id = "rtd"
+	feed.Agency[0].Name = "Regional Transportation District"
+	feed.Agency[0].Extra = map[string]string{"agency_branding_url": "https://www.rtd-denver.com/brand"}
+	feed.Stop = append(feed.Stop, model.Stop{Id: stopOneId, Name: "Union Station", Latitude: csv_parse.NewOptional(39.7525), Longitude: csv_parse.NewOptional(-105.0001)})
+	feed.Calendar[0].StartDate = time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	feed.Calendar[0].EndDate = time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	feed.StopTime[0].StopSequence = 1
+	feed.StopTime[1].StopSequence = 2
+	feed.StopTime[1].DepartureTime = csv_parse.NewOptional(model.ArrivalDepartureTime(25 * 60 * 60))
+	feed.FareAttribute = []model.FareAttribute{{Id: "local", Price: 3, CurrencyType: "USD", TransferDuration: csv_parse.NewOptional(int32(5400))}}
+	feed.FareRule = []model.FareRule{{FareId: "local", RouteId: "route15"}, {FareId: "local", OriginId: "A"}}
+	feed.Translation = []model.Translation{
+		{TableName: "stops", FieldName: "stop_name", Language: "es", Translation: "Estación Union", RecordId: stopOneId},
+		{TableName: "stops", FieldName: "stop_name", Language: "fr", Translation: "Gare Union", FieldValue: "Union Station"},
+	}
+	feed.Attribution = []model.Attribution{{OrganizationName: "Transdev", IsOperator: model.HasRole}}
+	feed.FeedInfo = model.FeedInfo{PublisherName: "RTD", Version: "v1", DownloadTime: time.Now()}
+	addVersionToAllObjects(feed, feed.FeedInfo.Version)
+
+	dbPath := path.Join(t.TempDir(), "gtfs.db")
+	db, err := InitializeSqliteDatabase(dbPath, log.Silent)
+	assert.NoError(t, err)
+	assert.NoError(t, WriteStaticGtfsFeedToDatabase(feed, db))
+
+	zipPath := path.Join(t.TempDir(), "google_transit.zip")
+	assert.NoError(t, ExportStaticGtfsToPath(dbPath, "v1", zipPath, log.Silent))
+
+	exported, err := ParseStaticGtfsFromPath(zipPath, StaticParseOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", exported.FeedInfo.Version)
+	assert.Equal(t, "RTD", exported.FeedInfo.PublisherName)
+	assert.Equal(t, "Regional Transportation District", exported.Agency[0].Name)
+	assert.Equal(t, "America/Denver", exported.Agency[0].Timezone)
+	assert.Equal(t, feed.Agency[0].Extra, exported.Agency[0].Extra)
+	assert.Nil(t, exported.Stop[0].Extra)
+	assert.Equal(t, feed.Stop[0].Latitude, exported.Stop[0].Latitude)
+	assert.Equal(t, feed.Stop[0].Longitude, exported.Stop[0].Longitude)
+	assert.Equal(t, tripOneId, exported.Trip[0].Id)
+	assert.Equal(t, feed.Calendar[0].StartDate, exported.Calendar[0].StartDate)
+	assert.Equal(t, 2, len(exported.StopTime))
+	assert.Equal(t, feed.StopTime[0].ArrivalTime, exported.StopTime[0].ArrivalTime)
+	// Missing times are stored as NULL, and written back out as empty values
+	assert.False(t, exported.StopTime[0].DepartureTime.Valid)
+	assert.Equal(t, csv_parse.NewOptional(model.ArrivalDepartureTime(25*60*60)), exported.StopTime[1].DepartureTime)
+	assert.Equal(t, feed.FareAttribute[0].TransferDuration, exported.FareAttribute[0].TransferDuration)
+	assert.False(t, exported.FareAttribute[0].Transfers.Valid)
+	assert.Equal(t, 2, len(exported.FareRule))
+	assert.ElementsMatch(t, feed.Translation, exported.Translation)
+	assert.Equal(t, feed.Attribution, exported.Attribution)
+}
+
+func TestExportUnknownVersion(t *testing.T) {
+	dbPath := path.Join(t.TempDir(), "gtfs.db")
+	_, err := InitializeSqliteDatabase(dbPath, log.Silent)
+	assert.NoError(t, err)
+	err = ExportStaticGtfsToPath(dbPath, "missing", path.Join(t.TempDir(), "google_transit.zip"), log.Silent)
+	assert.EqualError(t, err, "no feed with version missing found in database")
+}
+
+func TestExportMissingDatabase(t *testing.T) {
+	dbPath := path.Join(t.TempDir(), "gtfs.db")
+	err := ExportStaticGtfsToPath(dbPath, "v1", path.Join(t.TempDir(), "google_transit.zip"), log.Silent)
+	assert.EqualError(t, err, "no database found at "+dbPath)
+	// The mistyped path isn't left behind as an empty database
+	assert.NoFileExists(t, dbPath)
+}
+
+func TestExportFeedWithoutFeedInfo(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getValidGtfsFiles()), StaticParseOptions{})
+	assert.NoError(t, err)
+	addNamespaceToFeed(feed, "rtd")
+	dbPath := path.Join(t.TempDir(), "gtfs.db")
+	db, err := InitializeSqliteDatabase(dbPath, log.Silent)
+	assert.NoError(t, err)
+	assert.NoError(t, WriteStaticGtfsFeedToDatabase(feed, db))
+
+	zipPath := path.Join(t.TempDir(), "google_transit.zip")
+	assert.NoError(t, ExportStaticGtfsToPath(dbPath, feed.FeedInfo.Version, zipPath, log.Silent))
+
+	report, err := ValidateStaticGtfsFromPath(zipPath)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Findings)
+	archive, err := zip.OpenReader(zipPath)
+	assert.NoError(t, err)
+	defer archive.Close()
+	for _, file := range archive.File {
+		assert.NotEqual(t, "feed_info.txt", file.Name)
+	}
+}
+
+func TestExportNamespacedFeedVersion(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getValidGtfsFiles()), StaticParseOptions{})
+	assert.NoError(t, err)
+	feed.FeedInfo = model.FeedInfo{PublisherName: "RTD", PublisherUrl: "https://www.rtd-denver.com", Language: "en", Version: "v1", DownloadTime: time.Now()}
+	addNamespaceToFeed(feed, "rtd")
+	dbPath := path.Join(t.TempDir(), "gtfs.db")
+	db, err := InitializeSqliteDatabase(dbPath, log.Silent)
+	assert.NoError(t, err)
+	assert.NoError(t, WriteStaticGtfsFeedToDatabase(feed, db))
+
+	zipPath := path.Join(t.TempDir(), "google_transit.zip")
+	assert.NoError(t, ExportStaticGtfsToPath(dbPath, "rtd/v1", zipPath, log.Silent))
+
+	report, err := ValidateStaticGtfsFromPath(zipPath)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Findings)
+	exported, err := ParseStaticGtfsFromPath(zipPath, StaticParseOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", exported.FeedInfo.Version)
+	// Storing it again in the same namespace gives the same version
+	addNamespaceToFeed(exported, "rtd")
+	assert.Equal(t, "rtd/v1", exported.FeedInfo.Version)
+}
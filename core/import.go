@@ -0,0 +1,189 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/samc1213/gtfs-analyze/csv_parse"
+	"github.com/samc1213/gtfs-analyze/gtfs_realtime"
+	"github.com/samc1213/gtfs-analyze/log"
+)
+
+// Stores static feeds from local zip files or folders into the database at dbPath, in the order
+// given, like the store command does from a url. A feed whose version is already stored is skipped
+func ImportStaticGtfs(dbPath string, paths []string, namespace string, options StaticParseOptions, logLevel log.Level) error {
+	logger := log.New(logLevel)
+	db, err := initializeDb(logger, dbPath, logLevel)
+	if err != nil {
+		return err
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDb.Close()
+	repository := NewGormRepository(db)
+
+	if options.OnError == nil {
+		options.OnError = func(fileName string, err *csv_parse.ParseError) {
+			logger.Warning("Ignoring unparseable data in %s: %s", fileName, err.Error())
+		}
+	}
+	for _, path := range paths {
+		logger.Info("Parsing static GTFS from path: %s", path)
+		feed, err := ParseStaticGtfsFromPath(path, options)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		addNamespaceToFeed(feed, namespace)
+		err = writeStaticGtfsToDbIfNeeded(feed, repository, logger, dbPath)
+		if err != nil && !errors.Is(err, errDuplicateMessage) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// A local file holding one GTFS-RT message, like a snapshot from an archive of a feed
+type rtMessageFile struct {
+	path             string
+	messageTimestamp uint64
+}
+
+// Stores GTFS-RT messages from local .pb files, or gzipped .pb.gz files, into the database at dbPath
+// under namespace. Folders are searched for them, including subfolders. Messages are stored in the
+// order of their header timestamps, whatever order the files are in, and a message whose timestamp is
+// already stored for its entity type is skipped, so an archive can be imported more than once.
+// Files that aren't GTFS-RT messages, or that can't be converted, are logged and skipped
+func ImportRtGtfs(dbPath string, paths []string, namespace string, logLevel log.Level) error {
+	logger := log.New(logLevel)
+	files, err := findRtMessageFiles(paths, logger)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].messageTimestamp < files[j].messageTimestamp
+	})
+
+	db, err := initializeDb(logger, dbPath, logLevel)
+	if err != nil {
+		return err
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDb.Close()
+	repository := NewGormRepository(db)
+
+	entityTypes := make([]string, 0, len(rtEntityTypes))
+	trackers := make(map[string]*LatestRtUpdateTracker, len(rtEntityTypes))
+	for entityType := range rtEntityTypes {
+		entityTypes = append(entityTypes, entityType)
+		// Unlike when polling, messages older than the stored ones are stored, to fill in history
+		trackers[entityType] = &LatestRtUpdateTracker{}
+	}
+	sort.Strings(entityTypes)
+	writeNow := func(writeToDb func() error) error { return writeToDb() }
+
+	imported := make(map[string]uint64, len(rtEntityTypes))
+	duplicates := 0
+	for _, file := range files {
+		protoBytes, err := readRtMessageFile(file.path)
+		if err != nil {
+			return err
+		}
+		for _, entityType := range entityTypes {
+			rtType := rtEntityTypes[entityType]
+			stored, err := repository.HasMessage(entityType, namespace, file.messageTimestamp)
+			if err != nil {
+				return err
+			}
+			if stored {
+				duplicates++
+				continue
+			}
+			count, err := rtType.convertAndWrite(protoBytes, namespace, trackers[entityType], repository, writeNow)
+			var parseErr *feedParseError
+			switch {
+			case errors.Is(err, errDuplicateMessage):
+				duplicates++
+			case errors.As(err, &parseErr):
+				logger.Warning("Skipping the %s in %s, which can't be converted: %s", rtType.name, file.path, err.Error())
+			case err != nil:
+				return fmt.Errorf("%s: %w", file.path, err)
+			default:
+				imported[entityType] += count
+			}
+		}
+	}
+	logger.Info("Imported %d vehicle positions, %d trip updates and %d alerts from %d files. Skipped %d messages that were already stored",
+		imported["vehicle_positions"], imported["trip_updates"], imported["alerts"], len(files), duplicates)
+	return nil
+}
+
+// Lists the GTFS-RT message files at paths with their header timestamps. Files given directly are
+// read whatever their names, while only .pb and .pb.gz files in folders are
+func findRtMessageFiles(paths []string, logger log.Interface) ([]rtMessageFile, error) {
+	var filePaths []string
+	for _, path := range paths {
+		fileInfo, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !fileInfo.IsDir() {
+			filePaths = append(filePaths, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && (strings.HasSuffix(filePath, ".pb") || strings.HasSuffix(filePath, ".pb.gz")) {
+				filePaths = append(filePaths, filePath)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files := make([]rtMessageFile, 0, len(filePaths))
+	for _, filePath := range filePaths {
+		protoBytes, err := readRtMessageFile(filePath)
+		var parseErr *feedParseError
+		if err != nil && !errors.As(err, &parseErr) {
+			return nil, err
+		}
+		var feedMessage *gtfs_realtime.FeedMessage
+		if err == nil {
+			feedMessage, err = unmarshalFeedMessage(protoBytes)
+		}
+		if err != nil {
+			logger.Warning("Skipping %s, which isn't a GTFS-RT message: %s", filePath, err.Error())
+			continue
+		}
+		files = append(files, rtMessageFile{path: filePath, messageTimestamp: feedMessage.GetHeader().GetTimestamp()})
+	}
+	return files, nil
+}
+
+func readRtMessageFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(path, ".gz") {
+		data, err = decompressPayload(data)
+		if err != nil {
+			return nil, &feedParseError{err}
+		}
+	}
+	return data, nil
+}
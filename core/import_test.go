@@ -0,0 +1,88 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestImportStaticGtfs(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getValidGtfsFiles()), StaticParseOptions{})
+	assert.NoError(t, err)
+	var staticZip bytes.Buffer
+	assert.NoError(t, WriteStaticGtfsToZip(feed, &staticZip))
+	zipPath := path.Join(t.TempDir(), "google_transit.zip")
+	assert.NoError(t, os.WriteFile(zipPath, staticZip.Bytes(), 0644))
+	// Without a feed_info.txt, the version is the hash of the zip's files
+	zipFeed, err := ParseStaticGtfsFromPath(zipPath, StaticParseOptions{})
+	assert.NoError(t, err)
+
+	dbPath := path.Join(t.TempDir(), "gtfs.db")
+	assert.NoError(t, ImportStaticGtfs(dbPath, []string{zipPath}, "rtd", StaticParseOptions{}, log.Silent))
+	// A version that is already stored is skipped
+	assert.NoError(t, ImportStaticGtfs(dbPath, []string{zipPath}, "rtd", StaticParseOptions{}, log.Silent))
+
+	db, err := InitializeSqliteDatabase(dbPath, log.Silent)
+	assert.NoError(t, err)
+	var feedInfos []model.FeedInfo
+	assert.NoError(t, db.Find(&feedInfos).Error)
+	assert.Equal(t, 1, len(feedInfos))
+	assert.Equal(t, "rtd/"+zipFeed.FeedInfo.Version, feedInfos[0].Version)
+	storedFeed, err := GetFeedByVersion("rtd/"+zipFeed.FeedInfo.Version, db)
+	assert.NoError(t, err)
+	assert.Equal(t, len(feed.StopTime), len(storedFeed.StopTime))
+
+	assert.Error(t, ImportStaticGtfs(dbPath, []string{path.Join(t.TempDir(), "missing.zip")}, "", StaticParseOptions{}, log.Silent))
+}
+
+func TestImportRtGtfs(t *testing.T) {
+	vehiclePositions, err := os.ReadFile(path.Join(getTestFilesPath(), "VehiclePosition_RTD_2023_05_23.pb"))
+	assert.NoError(t, err)
+	tripUpdatesAndAlerts, err := proto.Marshal(getTripUpdateAndAlertMessage())
+	assert.NoError(t, err)
+	compressedTripUpdatesAndAlerts, err := compressPayload(tripUpdatesAndAlerts)
+	assert.NoError(t, err)
+	// An older snapshot of the same feed, with a vehicle somewhere else
+	olderMessage, err := unmarshalFeedMessage(vehiclePositions)
+	assert.NoError(t, err)
+	*olderMessage.Header.Timestamp -= 30
+	movedVehicle := olderMessage.Entity[0]
+	*movedVehicle.Vehicle.Position.Latitude += 1
+	olderVehiclePositions, err := proto.Marshal(olderMessage)
+	assert.NoError(t, err)
+
+	archiveDir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(path.Join(archiveDir, "2023", "06"), 0755))
+	assert.NoError(t, os.WriteFile(path.Join(archiveDir, "a.pb"), vehiclePositions, 0644))
+	assert.NoError(t, os.WriteFile(path.Join(archiveDir, "2023", "06", "realtime.pb.gz"), compressedTripUpdatesAndAlerts, 0644))
+	// Named to sort last, though its message is the oldest
+	assert.NoError(t, os.WriteFile(path.Join(archiveDir, "z.pb"), olderVehiclePositions, 0644))
+	assert.NoError(t, os.WriteFile(path.Join(archiveDir, "copy.pb"), vehiclePositions, 0644))
+	assert.NoError(t, os.WriteFile(path.Join(archiveDir, "broken.pb"), []byte("not a feed message"), 0644))
+	assert.NoError(t, os.WriteFile(path.Join(archiveDir, "notes.txt"), []byte("skipped"), 0644))
+
+	dbPath := path.Join(t.TempDir(), "gtfs.db")
+	assert.NoError(t, ImportRtGtfs(dbPath, []string{archiveDir}, "rtd", log.Silent))
+	assert.NoError(t, ImportRtGtfs(dbPath, []string{path.Join(archiveDir, "a.pb")}, "rtd", log.Silent))
+
+	db, err := InitializeSqliteDatabase(dbPath, log.Silent)
+	assert.NoError(t, err)
+	var count int64
+	assert.NoError(t, db.Model(&model.VehiclePosition{}).Where("namespace = ?", "rtd").Count(&count).Error)
+	assert.EqualValues(t, 331, count)
+	// The newer message was stored last, whatever the order of the files
+	var vehiclePosition model.VehiclePosition
+	assert.NoError(t, db.Where("namespace = ? AND id = ?", "rtd", movedVehicle.GetId()).First(&vehiclePosition).Error)
+	assert.EqualValues(t, 1684864397, vehiclePosition.MessageTimestamp)
+	assert.InDelta(t, float64(movedVehicle.Vehicle.Position.GetLatitude())-1, vehiclePosition.Latitude, 0.0001)
+	assert.NoError(t, db.Model(&model.TripUpdate{}).Where("namespace = ?", "rtd").Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+	assert.NoError(t, db.Model(&model.Alert{}).Where("namespace = ?", "rtd").Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+}
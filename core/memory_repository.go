@@ -0,0 +1,199 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/model"
+)
+
+// Keeps feeds and GTFS-RT entities in memory, for tests and short-lived analyses that don't need a
+// database. Feeds are kept as written, so a feed mustn't be changed after it is written or loaded
+type MemoryRepository struct {
+	lock  sync.Mutex
+	feeds map[string]*model.GtfsStaticFeed // By version
+	// By namespace and id
+	vehiclePositions map[[2]string]model.VehiclePosition
+	tripUpdates      map[[2]string]model.TripUpdate
+	alerts           map[[2]string]model.Alert
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		feeds:            make(map[string]*model.GtfsStaticFeed),
+		vehiclePositions: make(map[[2]string]model.VehiclePosition),
+		tripUpdates:      make(map[[2]string]model.TripUpdate),
+		alerts:           make(map[[2]string]model.Alert),
+	}
+}
+
+func (repository *MemoryRepository) WriteFeed(feed *model.GtfsStaticFeed) error {
+	repository.lock.Lock()
+	defer repository.lock.Unlock()
+	if _, ok := repository.feeds[feed.FeedInfo.Version]; ok {
+		return fmt.Errorf("feed with version %s is already stored", feed.FeedInfo.Version)
+	}
+	// Sorted like GormRepository loads them
+	storedFeed := *feed
+	storedFeed.StopTime = append([]model.StopTime(nil), feed.StopTime...)
+	sort.SliceStable(storedFeed.StopTime, func(i, j int) bool {
+		if storedFeed.StopTime[i].TripId != storedFeed.StopTime[j].TripId {
+			return storedFeed.StopTime[i].TripId < storedFeed.StopTime[j].TripId
+		}
+		return storedFeed.StopTime[i].StopSequence < storedFeed.StopTime[j].StopSequence
+	})
+	storedFeed.ShapePoint = append([]model.ShapePoint(nil), feed.ShapePoint...)
+	sort.SliceStable(storedFeed.ShapePoint, func(i, j int) bool {
+		if storedFeed.ShapePoint[i].ShapeId != storedFeed.ShapePoint[j].ShapeId {
+			return storedFeed.ShapePoint[i].ShapeId < storedFeed.ShapePoint[j].ShapeId
+		}
+		return storedFeed.ShapePoint[i].Sequence < storedFeed.ShapePoint[j].Sequence
+	})
+	repository.feeds[feed.FeedInfo.Version] = &storedFeed
+	return nil
+}
+
+func (repository *MemoryRepository) HasFeedVersion(version string) (bool, error) {
+	repository.lock.Lock()
+	defer repository.lock.Unlock()
+	_, ok := repository.feeds[version]
+	return ok, nil
+}
+
+func (repository *MemoryRepository) GetFeedByVersion(version string) (*model.GtfsStaticFeed, error) {
+	repository.lock.Lock()
+	defer repository.lock.Unlock()
+	feed, ok := repository.feeds[version]
+	if !ok {
+		return nil, fmt.Errorf("no feed with version %s found in database", version)
+	}
+	return feed, nil
+}
+
+func (repository *MemoryRepository) GetFeedOnDate(namespace string, year int, month time.Month, day int) (*model.GtfsStaticFeed, error) {
+	repository.lock.Lock()
+	defer repository.lock.Unlock()
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	var latestFeed *model.GtfsStaticFeed
+	for _, feed := range repository.feeds {
+		feedInfo := &feed.FeedInfo
+		if feedInfo.Namespace != namespace {
+			continue
+		}
+		downloadYear, downloadMonth, downloadDay := feedInfo.DownloadTime.UTC().Date()
+		if feedInfo.StartDate.After(date) || feedInfo.EndDate.Before(date) || time.Date(downloadYear, downloadMonth, downloadDay, 0, 0, 0, 0, time.UTC).After(date) {
+			continue
+		}
+		if latestFeed == nil || feedInfo.DownloadTime.After(latestFeed.FeedInfo.DownloadTime) {
+			latestFeed = feed
+		}
+	}
+	if latestFeed == nil {
+		return nil, getNoFeedOnDateError(namespace, date)
+	}
+	return latestFeed, nil
+}
+
+func (repository *MemoryRepository) WriteVehiclePositions(vehiclePositions []model.VehiclePosition) error {
+	repository.lock.Lock()
+	defer repository.lock.Unlock()
+	for _, vehiclePosition := range vehiclePositions {
+		repository.vehiclePositions[[2]string{vehiclePosition.Namespace, vehiclePosition.Id}] = vehiclePosition
+	}
+	return nil
+}
+
+func (repository *MemoryRepository) WriteTripUpdates(tripUpdates []model.TripUpdate) error {
+	repository.lock.Lock()
+	defer repository.lock.Unlock()
+	for _, tripUpdate := range tripUpdates {
+		repository.tripUpdates[[2]string{tripUpdate.Namespace, tripUpdate.Id}] = tripUpdate
+	}
+	return nil
+}
+
+func (repository *MemoryRepository) WriteAlerts(alerts []model.Alert) error {
+	repository.lock.Lock()
+	defer repository.lock.Unlock()
+	for _, alert := range alerts {
+		repository.alerts[[2]string{alert.Namespace, alert.Id}] = alert
+	}
+	return nil
+}
+
+func (repository *MemoryRepository) GetVehiclePositions(namespace string, startTime time.Time, endTime time.Time) ([]model.VehiclePosition, error) {
+	repository.lock.Lock()
+	defer repository.lock.Unlock()
+	var vehiclePositions []model.VehiclePosition
+	for key, vehiclePosition := range repository.vehiclePositions {
+		if key[0] == namespace && int64(vehiclePosition.PositionTimestamp) >= startTime.Unix() && int64(vehiclePosition.PositionTimestamp) <= endTime.Unix() {
+			vehiclePositions = append(vehiclePositions, vehiclePosition)
+		}
+	}
+	// Map order is random, so sort for the same results every time
+	sort.Slice(vehiclePositions, func(i, j int) bool {
+		return vehiclePositions[i].Id < vehiclePositions[j].Id
+	})
+	return vehiclePositions, nil
+}
+
+// The message timestamps of the entities of a type in a namespace
+func (repository *MemoryRepository) getMessageTimestamps(entityType string, namespace string) ([]uint64, error) {
+	var timestamps []uint64
+	switch entityType {
+	case "vehicle_positions":
+		for key, vehiclePosition := range repository.vehiclePositions {
+			if key[0] == namespace {
+				timestamps = append(timestamps, vehiclePosition.MessageTimestamp)
+			}
+		}
+	case "trip_updates":
+		for key, tripUpdate := range repository.tripUpdates {
+			if key[0] == namespace {
+				timestamps = append(timestamps, tripUpdate.MessageTimestamp)
+			}
+		}
+	case "alerts":
+		for key, alert := range repository.alerts {
+			if key[0] == namespace {
+				timestamps = append(timestamps, alert.MessageTimestamp)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown GTFS-RT entity type %s", entityType)
+	}
+	return timestamps, nil
+}
+
+func (repository *MemoryRepository) GetLatestMessageTimestamp(entityType string, namespace string) (uint64, error) {
+	repository.lock.Lock()
+	defer repository.lock.Unlock()
+	timestamps, err := repository.getMessageTimestamps(entityType, namespace)
+	if err != nil {
+		return 0, err
+	}
+	var latestTimestamp uint64
+	for _, timestamp := range timestamps {
+		if timestamp > latestTimestamp {
+			latestTimestamp = timestamp
+		}
+	}
+	return latestTimestamp, nil
+}
+
+func (repository *MemoryRepository) HasMessage(entityType string, namespace string, messageTimestamp uint64) (bool, error) {
+	repository.lock.Lock()
+	defer repository.lock.Unlock()
+	timestamps, err := repository.getMessageTimestamps(entityType, namespace)
+	if err != nil {
+		return false, err
+	}
+	for _, timestamp := range timestamps {
+		if timestamp == messageTimestamp {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"errors"
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 	"sync"
@@ -15,27 +16,35 @@ import (
 )
 
 type InternalStopTime struct {
-	StopId            string
-	StopTime          time.Time
-	ActualArrivalTime time.Time
+	StopId              string
+	StopTime            time.Time
+	DepartureTime       time.Time
+	ActualArrivalTime   time.Time
+	ActualDepartureTime time.Time
 }
 
 type InternalTrip struct {
 	Id                  string
+	RouteId             string
+	AgencyId            string
 	StopTimes           []InternalStopTime
 	HaveStartedTracking bool
 }
 
 type EasyLookupFeed struct {
 	CalendarByServiceId map[string]*model.Calendar
+	StopsById           map[string]*model.Stop
 	StopTimesByTripId   map[string][]*model.StopTime
+	TripsById           map[string]*model.Trip
+	AgencyIdByRouteId   map[string]string
+	LocationByAgencyId  map[string]*time.Location
 }
 
 type OtpCalculation struct {
 	TripsByDate    map[infra.Date]map[string]*InternalTrip
 	Feed           *model.GtfsStaticFeed
 	EasyLookupFeed *EasyLookupFeed
-	Location       *time.Location
+	Location       *time.Location // The timezone of trips whose agency has no timezone of its own
 	Lock           sync.Mutex
 }
 
@@ -46,25 +55,37 @@ type InternalVehiclePosition struct {
 	PositionTime  time.Time
 }
 
-func CalculateOtpForTimeRange(sqliteDbPath string, startTime time.Time, endTime time.Time, onTimeThreshold time.Duration, logLevel log.Level) (*OtpSummary, error) {
+// Calculates the on-time performance of the trips of agencyIds, or of every trip when agencyIds is empty,
+// from the feed and vehicle positions stored under a namespace
+func CalculateOtpForTimeRange(dbPath string, namespace string, startTime time.Time, endTime time.Time, onTimeThreshold time.Duration, groupBy GroupBy, agencyIds []string, language string, logLevel log.Level) (*OtpSummary, error) {
 	logger := log.New(logLevel)
 	logger.Debug("Caluclating Otp for time range %s to %s with threshold %s", startTime.String(), endTime.String(), onTimeThreshold.String())
 
-	db, err := InitializeSqliteDatabase(sqliteDbPath, logLevel)
+	db, err := openExistingDb(logger, dbPath, logLevel)
 	if err != nil {
 		return nil, err
 	}
-	var vehiclePositions []model.VehiclePosition
-	tx := db.Where("position_timestamp >= ? AND position_timestamp <= ?", startTime.Unix(), endTime.Unix()).Find(&vehiclePositions)
-	if tx.Error != nil {
-		return nil, tx.Error
+	sqlDb, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlDb.Close()
+	repository := NewGormRepository(db)
+	return CalculateOtp(repository, repository, namespace, startTime, endTime, onTimeThreshold, groupBy, agencyIds, language, logger)
+}
+
+// Like CalculateOtpForTimeRange, with the feed and vehicle positions loaded from repositories
+func CalculateOtp(feeds FeedRepository, realtime RealtimeRepository, namespace string, startTime time.Time, endTime time.Time, onTimeThreshold time.Duration, groupBy GroupBy, agencyIds []string, language string, logger log.Interface) (*OtpSummary, error) {
+	vehiclePositions, err := realtime.GetVehiclePositions(namespace, startTime, endTime)
+	if err != nil {
+		return nil, err
 	}
 
 	logger.Debug("Found %d VechilePosition updates", len(vehiclePositions))
 
 	// TODO: Need to update the static feed depending on the day we're looking at
 	year, month, day := startTime.Date()
-	feed, err := GetFeedOnDate(year, month, day, db)
+	feed, err := feeds.GetFeedOnDate(namespace, year, month, day)
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +97,9 @@ func CalculateOtpForTimeRange(sqliteDbPath string, startTime time.Time, endTime
 
 	calculation.OnNewPositionData(vehiclePositions, logger)
 
-	return calculation.SummarizeOnTimePerformanceByTrip(onTimeThreshold, startTime, endTime, logger), nil
+	summary := calculation.SummarizeOnTimePerformanceForAgencies(agencyIds, groupBy, onTimeThreshold, startTime, endTime, logger)
+	calculation.LabelOtpSummary(summary, NewTranslator(feed, language))
+	return summary, nil
 }
 
 func CreateOtpCalculation(feed *model.GtfsStaticFeed) (*OtpCalculation, error) {
@@ -88,6 +111,10 @@ func CreateOtpCalculation(feed *model.GtfsStaticFeed) (*OtpCalculation, error) {
 	for calendarIdx := range feed.Calendar {
 		easyLookup.CalendarByServiceId[feed.Calendar[calendarIdx].ServiceId] = &feed.Calendar[calendarIdx]
 	}
+	easyLookup.StopsById = make(map[string]*model.Stop)
+	for stopIdx := range feed.Stop {
+		easyLookup.StopsById[feed.Stop[stopIdx].Id] = &feed.Stop[stopIdx]
+	}
 	easyLookup.StopTimesByTripId = make(map[string][]*model.StopTime)
 	for stopTimeIdx := range feed.StopTime {
 		tripId := feed.StopTime[stopTimeIdx].TripId
@@ -100,11 +127,32 @@ func CreateOtpCalculation(feed *model.GtfsStaticFeed) (*OtpCalculation, error) {
 		})
 	}
 
-	if len(feed.Agency) < 1 {
-		return nil, errors.New("cannot lookup agency timezone")
+	easyLookup.TripsById = make(map[string]*model.Trip)
+	for tripIdx := range feed.Trip {
+		easyLookup.TripsById[feed.Trip[tripIdx].Id] = &feed.Trip[tripIdx]
+	}
+	// Routes without an agency_id belong to the feed's only agency
+	easyLookup.AgencyIdByRouteId = make(map[string]string)
+	for _, route := range feed.Route {
+		agencyId := route.AgencyId
+		if agencyId == "" && len(feed.Agency) == 1 {
+			agencyId = feed.Agency[0].Id
+		}
+		easyLookup.AgencyIdByRouteId[route.Id] = agencyId
+	}
+	easyLookup.LocationByAgencyId = make(map[string]*time.Location)
+	for _, agency := range feed.Agency {
+		if agency.Timezone == "" {
+			continue
+		}
+		agencyLocation, err := time.LoadLocation(agency.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		easyLookup.LocationByAgencyId[agency.Id] = agencyLocation
 	}
 
-	location, err := time.LoadLocation(feed.Agency[0].Timezone)
+	location, err := getDefaultLocation(feed, easyLookup.LocationByAgencyId)
 	if err != nil {
 		return nil, err
 	}
@@ -112,6 +160,35 @@ func CreateOtpCalculation(feed *model.GtfsStaticFeed) (*OtpCalculation, error) {
 	return &OtpCalculation{Feed: feed, EasyLookupFeed: &easyLookup, TripsByDate: make(map[infra.Date]map[string]*InternalTrip), Location: location}, nil
 }
 
+// The timezone of the first agency, for trips whose agency has no timezone. Times in stop_times.txt are
+// always in the agency's timezone, so stop_timezone is only used for feeds without an agency timezone
+func getDefaultLocation(feed *model.GtfsStaticFeed, locationByAgencyId map[string]*time.Location) (*time.Location, error) {
+	for _, agency := range feed.Agency {
+		if location, ok := locationByAgencyId[agency.Id]; ok {
+			return location, nil
+		}
+	}
+	for _, stop := range feed.Stop {
+		if stop.Timezone != "" {
+			return time.LoadLocation(stop.Timezone)
+		}
+	}
+	return nil, errors.New("cannot lookup agency timezone")
+}
+
+// Each trip's times are in the timezone of the agency of its route
+func (calculation *OtpCalculation) getTripLocation(tripId string) *time.Location {
+	trip, ok := calculation.EasyLookupFeed.TripsById[tripId]
+	if !ok {
+		return calculation.Location
+	}
+	location, ok := calculation.EasyLookupFeed.LocationByAgencyId[calculation.EasyLookupFeed.AgencyIdByRouteId[trip.RouteId]]
+	if !ok {
+		return calculation.Location
+	}
+	return location
+}
+
 func (calculation *OtpCalculation) populateTripsForDate(date infra.Date, logger log.Interface) {
 	for _, trip := range calculation.Feed.Trip {
 		calendar, ok := calculation.EasyLookupFeed.CalendarByServiceId[trip.ServiceId]
@@ -125,12 +202,23 @@ func (calculation *OtpCalculation) populateTripsForDate(date infra.Date, logger
 				logger.Warning("Cannot find stop times for trip %s", trip.Id)
 				continue
 			}
+			if isDemandResponsiveTrip(stopTimes) {
+				logger.Debug("Skipping trip %s, which has pickup and drop off windows instead of arrival times", trip.Id)
+				continue
+			}
+			arrivalTimes, ok := interpolateArrivalTimes(stopTimes, calculation.EasyLookupFeed.StopsById)
+			if !ok {
+				logger.Warning("Cannot find any arrival or departure times for trip %s", trip.Id)
+				continue
+			}
 			internalStopTimes := make([]InternalStopTime, len(stopTimes))
+			midnight := time.Date(date.Year, date.Month, date.Day, 0, 0, 0, 0, calculation.getTripLocation(trip.Id))
 			for stopTimeIdx := range stopTimes {
-				stopTime := stopTimes[stopTimeIdx]
+				departureTime := stopTimes[stopTimeIdx].DepartureTime.Get(arrivalTimes[stopTimeIdx])
 				internalStopTimes[stopTimeIdx] = InternalStopTime{
-					StopId:   stopTime.StopId,
-					StopTime: time.Date(date.Year, date.Month, date.Day, 0, 0, 0, 0, calculation.Location).Add(time.Duration(*&stopTime.ArrivalTime) * time.Second),
+					StopId:        stopTimes[stopTimeIdx].StopId,
+					StopTime:      midnight.Add(time.Duration(arrivalTimes[stopTimeIdx]) * time.Second),
+					DepartureTime: midnight.Add(time.Duration(departureTime) * time.Second),
 				}
 			}
 			tripsForDate := calculation.TripsByDate[date]
@@ -138,13 +226,118 @@ func (calculation *OtpCalculation) populateTripsForDate(date infra.Date, logger
 				calculation.TripsByDate[date] = make(map[string]*InternalTrip)
 				tripsForDate = calculation.TripsByDate[date]
 			}
-			tripsForDate[trip.Id] = &InternalTrip{Id: trip.Id, StopTimes: internalStopTimes}
+			tripsForDate[trip.Id] = &InternalTrip{Id: trip.Id, RouteId: trip.RouteId, AgencyId: calculation.EasyLookupFeed.AgencyIdByRouteId[trip.RouteId], StopTimes: internalStopTimes}
+		}
+	}
+}
+
+// Demand-responsive trips from GTFS-Flex have no schedule to be on time for
+func isDemandResponsiveTrip(stopTimes []*model.StopTime) bool {
+	for _, stopTime := range stopTimes {
+		if stopTime.StartPickupDropOffWindow.Valid || stopTime.EndPickupDropOffWindow.Valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the scheduled arrival time at each stop of a trip, given its stop times in stop sequence order.
+// Stops without a time, which are allowed for stops that are not timepoints, are placed between the
+// surrounding stops that have one by how far along the trip they are. Returns false if none of the
+// stops have a time
+func interpolateArrivalTimes(stopTimes []*model.StopTime, stopsById map[string]*model.Stop) ([]model.ArrivalDepartureTime, bool) {
+	arrivalTimes := make([]model.ArrivalDepartureTime, len(stopTimes))
+	lastKnownIdx := -1
+	var lastKnownDeparture model.ArrivalDepartureTime
+	for i, stopTime := range stopTimes {
+		arrival := stopTime.ArrivalTime
+		if !arrival.Valid {
+			arrival = stopTime.DepartureTime
+		}
+		if !arrival.Valid {
+			continue
+		}
+		arrivalTimes[i] = arrival.V
+		if lastKnownIdx == -1 {
+			// The first stop should always have a time, but if it doesn't, assume the earlier stops are served at the same time
+			for j := 0; j < i; j++ {
+				arrivalTimes[j] = arrival.V
+			}
+		} else if i > lastKnownIdx+1 {
+			distances := getDistancesAlongTrip(stopTimes[lastKnownIdx:i+1], stopsById)
+			duration := float64(arrival.V - lastKnownDeparture)
+			for j := lastKnownIdx + 1; j < i; j++ {
+				fraction := distances[j-lastKnownIdx] / distances[i-lastKnownIdx]
+				arrivalTimes[j] = lastKnownDeparture + model.ArrivalDepartureTime(math.Round(duration*fraction))
+			}
+		}
+		lastKnownIdx = i
+		lastKnownDeparture = stopTime.DepartureTime.Get(arrival.V)
+	}
+	if lastKnownIdx == -1 {
+		return nil, false
+	}
+	for j := lastKnownIdx + 1; j < len(stopTimes); j++ {
+		arrivalTimes[j] = lastKnownDeparture
+	}
+	return arrivalTimes, true
+}
+
+// How far each stop of part of a trip is from its first stop. This is the shape_dist_traveled of the
+// stop times when they all have one, or else the distance between consecutive stops when they all have
+// coordinates. Otherwise, stops are counted as though they were evenly spaced. The last stop is always
+// further than the first
+func getDistancesAlongTrip(stopTimes []*model.StopTime, stopsById map[string]*model.Stop) []float64 {
+	last := len(stopTimes) - 1
+	byShape := make([]float64, len(stopTimes))
+	byShapeOk := true
+	for i, stopTime := range stopTimes {
+		if !stopTime.ShapeDistTraveled.Valid {
+			byShapeOk = false
+			break
+		}
+		byShape[i] = stopTime.ShapeDistTraveled.V - stopTimes[0].ShapeDistTraveled.V
+		// shape_dist_traveled must increase along the trip
+		if i > 0 && byShape[i] < byShape[i-1] {
+			byShapeOk = false
+			break
 		}
 	}
+	if byShapeOk && byShape[last] > 0 {
+		return byShape
+	}
+
+	byStops := make([]float64, len(stopTimes))
+	byStopsOk := true
+	var previous *model.Stop
+	for i, stopTime := range stopTimes {
+		stop, ok := stopsById[stopTime.StopId]
+		if !ok || !stop.Latitude.Valid || !stop.Longitude.Valid {
+			byStopsOk = false
+			break
+		}
+		if previous != nil {
+			byStops[i] = byStops[i-1] + infra.DistanceMeters(previous.Latitude.V, previous.Longitude.V, stop.Latitude.V, stop.Longitude.V)
+		}
+		previous = stop
+	}
+	if byStopsOk && byStops[last] > 0 {
+		return byStops
+	}
+
+	byCount := make([]float64, len(stopTimes))
+	for i := range byCount {
+		byCount[i] = float64(i)
+	}
+	return byCount
 }
 
 func doesTripRunOnDate(date infra.Date, calendar *model.Calendar) bool {
-	switch date.Weekday() {
+	return isServiceAvailableOnWeekday(date.Weekday(), calendar)
+}
+
+func isServiceAvailableOnWeekday(weekday time.Weekday, calendar *model.Calendar) bool {
+	switch weekday {
 	case time.Monday:
 		return calendar.Monday == model.ServiceIsAvailable
 	case time.Tuesday:
@@ -165,6 +358,7 @@ func doesTripRunOnDate(date infra.Date, calendar *model.Calendar) bool {
 
 type OtpSummaryEntry struct {
 	Name              string // This value depends on the grouping logic. Could be a route id, trip id,
+	Label             string // A readable name for Name, like the trip headsign or the stop name
 	OnTimePerformance float64
 }
 
@@ -172,8 +366,49 @@ type GroupBy string
 
 const (
 	TripId GroupBy = "TripId"
+	StopId GroupBy = "StopId"
+	// Platforms and boarding areas are rolled up to their parent station
+	StationId GroupBy = "StationId"
+	AgencyId  GroupBy = "AgencyId"
 )
 
+// These functions are used for the cobra CLI tool to parse user specified groupings
+func (g *GroupBy) String() string {
+	switch *g {
+	case StopId:
+		return "stop"
+	case StationId:
+		return "station"
+	case AgencyId:
+		return "agency"
+	default:
+		return "trip"
+	}
+}
+
+func (g *GroupBy) Set(v string) error {
+	switch v {
+	case "trip":
+		*g = TripId
+		return nil
+	case "stop":
+		*g = StopId
+		return nil
+	case "station":
+		*g = StationId
+		return nil
+	case "agency":
+		*g = AgencyId
+		return nil
+	default:
+		return errors.New(`must be one of "trip", "stop", "station" or "agency"`)
+	}
+}
+
+func (g *GroupBy) Type() string {
+	return "core.GroupBy"
+}
+
 type OtpSummary struct {
 	GroupBy      GroupBy
 	OtpSummaries []OtpSummaryEntry
@@ -183,9 +418,21 @@ func (summary *OtpSummary) PrettyPrint() string {
 	sort.Slice(summary.OtpSummaries, func(i, j int) bool { return summary.OtpSummaries[i].Name < summary.OtpSummaries[j].Name })
 	builder := strings.Builder{}
 	writer := tabwriter.NewWriter(&builder, 0, 0, 0, ' ', tabwriter.Debug)
-	fmt.Fprintf(writer, "%s\tOTP\n", summary.GroupBy)
+	hasLabels := false
+	for _, entry := range summary.OtpSummaries {
+		hasLabels = hasLabels || entry.Label != ""
+	}
+	if hasLabels {
+		fmt.Fprintf(writer, "%s\tName\tOTP\n", summary.GroupBy)
+	} else {
+		fmt.Fprintf(writer, "%s\tOTP\n", summary.GroupBy)
+	}
 	for _, summary := range summary.OtpSummaries {
-		fmt.Fprintf(writer, "%s\t%.2f\n", summary.Name, summary.OnTimePerformance*100)
+		if hasLabels {
+			fmt.Fprintf(writer, "%s\t%s\t%.2f\n", summary.Name, summary.Label, summary.OnTimePerformance*100)
+		} else {
+			fmt.Fprintf(writer, "%s\t%.2f\n", summary.Name, summary.OnTimePerformance*100)
+		}
 	}
 	writer.Flush()
 	return builder.String()
@@ -196,22 +443,49 @@ func (summary *OtpSummary) PrettyPrint() string {
 // where "service on time" means that the service arrived at the stop within onTimeThreshold amount
 // of time
 func (calculation *OtpCalculation) SummarizeOnTimePerformanceByTrip(onTimeThreshold time.Duration, startTime time.Time, endTime time.Time, logger log.Interface) *OtpSummary {
+	return calculation.SummarizeOnTimePerformance(TripId, onTimeThreshold, startTime, endTime, logger)
+}
+
+// Summarize on time performance like SummarizeOnTimePerformanceByTrip, but grouped by trip, stop, station or agency
+func (calculation *OtpCalculation) SummarizeOnTimePerformance(groupBy GroupBy, onTimeThreshold time.Duration, startTime time.Time, endTime time.Time, logger log.Interface) *OtpSummary {
+	return calculation.SummarizeOnTimePerformanceForAgencies(nil, groupBy, onTimeThreshold, startTime, endTime, logger)
+}
+
+// Summarize on time performance like SummarizeOnTimePerformance, but only for the trips of agencyIds.
+// When agencyIds is empty, every trip is included
+func (calculation *OtpCalculation) SummarizeOnTimePerformanceForAgencies(agencyIds []string, groupBy GroupBy, onTimeThreshold time.Duration, startTime time.Time, endTime time.Time, logger log.Interface) *OtpSummary {
 	calculation.Lock.Lock()
 	defer calculation.Lock.Unlock()
 
-	numStopsOnTimeByTripId := make(map[string]int)
-	numStopsTotalByTripId := make(map[string]int)
+	includedAgencyIds := make(map[string]struct{}, len(agencyIds))
+	for _, agencyId := range agencyIds {
+		includedAgencyIds[agencyId] = struct{}{}
+	}
+	numStopsOnTimeByName := make(map[string]int)
+	numStopsTotalByName := make(map[string]int)
 
 	for _, tripIdToTrip := range calculation.TripsByDate {
 		for tripId, trip := range tripIdToTrip {
+			if _, ok := includedAgencyIds[trip.AgencyId]; len(includedAgencyIds) > 0 && !ok {
+				continue
+			}
 			// For now we do not include trips that have not been tracked at all (could be an issue with GTFS-RT)
 			if trip.HaveStartedTracking {
 				for _, stopTime := range trip.StopTimes {
 					if stopTime.StopTime.After(startTime) && stopTime.StopTime.Before(endTime) {
-						numStopsTotalByTripId[tripId] += 1
+						name := tripId
+						switch groupBy {
+						case StopId:
+							name = stopTime.StopId
+						case StationId:
+							name = getParentStationId(calculation.EasyLookupFeed.StopsById, stopTime.StopId)
+						case AgencyId:
+							name = trip.AgencyId
+						}
+						numStopsTotalByName[name] += 1
 
 						if stopTime.ActualArrivalTime.Sub(stopTime.StopTime).Abs() < onTimeThreshold.Abs() {
-							numStopsOnTimeByTripId[tripId] += 1
+							numStopsOnTimeByName[name] += 1
 						}
 					}
 				}
@@ -220,21 +494,45 @@ func (calculation *OtpCalculation) SummarizeOnTimePerformanceByTrip(onTimeThresh
 	}
 
 	summary := OtpSummary{}
-	summary.GroupBy = TripId
-	summary.OtpSummaries = make([]OtpSummaryEntry, len(numStopsTotalByTripId))
+	summary.GroupBy = groupBy
+	summary.OtpSummaries = make([]OtpSummaryEntry, len(numStopsTotalByName))
 	summariesIdx := 0
-	for tripId, numStopsTotal := range numStopsTotalByTripId {
-		numStopsOnTime, ok := numStopsOnTimeByTripId[tripId]
+	for name, numStopsTotal := range numStopsTotalByName {
+		numStopsOnTime, ok := numStopsOnTimeByName[name]
 		otp := 0.0
 		if ok {
 			otp = float64(numStopsOnTime) / float64(numStopsTotal)
 		}
-		summary.OtpSummaries[summariesIdx] = OtpSummaryEntry{Name: tripId, OnTimePerformance: otp}
+		summary.OtpSummaries[summariesIdx] = OtpSummaryEntry{Name: name, OnTimePerformance: otp}
 		summariesIdx++
 	}
 	return &summary
 }
 
+// Labels each entry of the summary with the trip headsign, the stop or station name, or the agency
+// name, in the language of the translator
+func (calculation *OtpCalculation) LabelOtpSummary(summary *OtpSummary, translator *Translator) {
+	for i := range summary.OtpSummaries {
+		entry := &summary.OtpSummaries[i]
+		switch summary.GroupBy {
+		case TripId:
+			if trip, ok := calculation.EasyLookupFeed.TripsById[entry.Name]; ok {
+				entry.Label = translator.TripHeadsign(trip)
+			}
+		case AgencyId:
+			for _, agency := range calculation.Feed.Agency {
+				if agency.Id == entry.Name {
+					entry.Label = translator.AgencyName(&agency)
+				}
+			}
+		default:
+			if stop, ok := calculation.EasyLookupFeed.StopsById[entry.Name]; ok {
+				entry.Label = translator.StopName(stop)
+			}
+		}
+	}
+}
+
 func (calculation *OtpCalculation) onNewPositionData(positionData []InternalVehiclePosition, logger log.Interface) {
 	calculation.Lock.Lock()
 	defer calculation.Lock.Unlock()
@@ -257,6 +555,7 @@ func (calculation *OtpCalculation) onNewPositionData(positionData []InternalVehi
 		if position.CurrentStatus == model.IncomingAt || position.CurrentStatus == model.InTransitTo {
 			calculation.markArrivalTimeForAllStopsPrior(trip, position.StopId, position.PositionTime)
 		}
+		calculation.markDepartureTime(trip, position.StopId, position.PositionTime, position.CurrentStatus == model.StoppedAt)
 	}
 }
 
@@ -264,8 +563,8 @@ func (calculation *OtpCalculation) onNewPositionData(positionData []InternalVehi
 // this trip is on. This current implementation works for trips before midnight, but doesn't work
 // for anything after midnight UTC
 func (calculation *OtpCalculation) inferTripDate(position *InternalVehiclePosition) infra.Date {
-	y, m, d := position.PositionTime.Date()
-	return infra.Date{y, m, d}
+	y, m, d := position.PositionTime.In(calculation.getTripLocation(position.TripId)).Date()
+	return infra.Date{Year: y, Month: m, Day: d}
 }
 
 func (calculation *OtpCalculation) OnNewPositionData(positionData []model.VehiclePosition, logger log.Interface) {
@@ -319,3 +618,30 @@ func (calculation *OtpCalculation) markArrivalTimeInternal(trip *InternalTrip, s
 	}
 	return nil
 }
+
+// Departures are not reported directly, so the departure from a stop is the last time the vehicle
+// was seen stopped there, or else the first time it was seen past it. Only stops that already have
+// an arrival are marked, so stops before tracking started are left alone
+func (calculation *OtpCalculation) markDepartureTime(trip *InternalTrip, stopId string, positionTime time.Time, isStoppedAtStop bool) {
+	providedStopIdx := -1
+	for stopIdx := range trip.StopTimes {
+		if trip.StopTimes[stopIdx].StopId == stopId {
+			providedStopIdx = stopIdx
+			break
+		}
+	}
+	if providedStopIdx == -1 {
+		return
+	}
+	if isStoppedAtStop {
+		trip.StopTimes[providedStopIdx].ActualDepartureTime = positionTime
+	}
+	for stopIdx := providedStopIdx - 1; stopIdx >= 0; stopIdx-- {
+		stop := &trip.StopTimes[stopIdx]
+		// Once we reach a previously-marked departure, or a stop before tracking started, stop
+		if !stop.ActualDepartureTime.IsZero() || stop.ActualArrivalTime.IsZero() {
+			break
+		}
+		stop.ActualDepartureTime = positionTime
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/samc1213/gtfs-analyze/csv_parse"
 	"github.com/samc1213/gtfs-analyze/infra"
 	"github.com/samc1213/gtfs-analyze/log"
 	"github.com/samc1213/gtfs-analyze/model"
@@ -112,15 +113,59 @@ func createStaticFeed() (*model.GtfsStaticFeed, string, string, string, infra.Da
 	tripOneStopOneArrivalTime := model.NewArrivalTime(time.Time{}.Add(8*time.Hour + 30*time.Minute))
 	tripOneStopOne := model.StopTime{TripId: tripOneId,
 		StopId:      stopOneId,
-		ArrivalTime: tripOneStopOneArrivalTime}
+		ArrivalTime: csv_parse.NewOptional(tripOneStopOneArrivalTime)}
 	tripOneStopTwoArrivalTime := model.NewArrivalTime(time.Time{}.Add(8*time.Hour + 45*time.Minute))
 	tripOneStopTwo := model.StopTime{TripId: tripOneId,
 		StopId:      stopTwoId,
-		ArrivalTime: tripOneStopTwoArrivalTime}
+		ArrivalTime: csv_parse.NewOptional(tripOneStopTwoArrivalTime)}
 	feed.StopTime = append(feed.StopTime, tripOneStopOne, tripOneStopTwo)
 	return &feed, tripOneId, stopOneId, stopTwoId, tripDate
 }
 
+func TestInterpolateArrivalTimes(t *testing.T) {
+	stopTimes := []*model.StopTime{
+		{ArrivalTime: csv_parse.NewOptional(model.ArrivalDepartureTime(100)), DepartureTime: csv_parse.NewOptional(model.ArrivalDepartureTime(130))},
+		{},
+		{},
+		{DepartureTime: csv_parse.NewOptional(model.ArrivalDepartureTime(160))},
+		{},
+	}
+	// Without distances, stops are spaced evenly
+	arrivalTimes, ok := interpolateArrivalTimes(stopTimes, nil)
+	assert.True(t, ok)
+	assert.Equal(t, []model.ArrivalDepartureTime{100, 140, 150, 160, 160}, arrivalTimes)
+
+	_, ok = interpolateArrivalTimes([]*model.StopTime{{}, {}}, nil)
+	assert.False(t, ok)
+
+	// Stops along a street, with the second stop a quarter of the way from the first to the third
+	stopsById := map[string]*model.Stop{
+		"stop1": {Id: "stop1", Latitude: csv_parse.NewOptional(39.70), Longitude: csv_parse.NewOptional(-105.0)},
+		"stop2": {Id: "stop2", Latitude: csv_parse.NewOptional(39.71), Longitude: csv_parse.NewOptional(-105.0)},
+		"stop3": {Id: "stop3", Latitude: csv_parse.NewOptional(39.74), Longitude: csv_parse.NewOptional(-105.0)},
+	}
+	stopTimes = []*model.StopTime{
+		{StopId: "stop1", ArrivalTime: csv_parse.NewOptional(model.ArrivalDepartureTime(100))},
+		{StopId: "stop2"},
+		{StopId: "stop3", ArrivalTime: csv_parse.NewOptional(model.ArrivalDepartureTime(500))},
+	}
+	arrivalTimes, _ = interpolateArrivalTimes(stopTimes, stopsById)
+	assert.Equal(t, []model.ArrivalDepartureTime{100, 200, 500}, arrivalTimes)
+
+	// The route winds, so the second stop is further along it than its coordinates suggest
+	stopTimes[0].ShapeDistTraveled = csv_parse.NewOptional(1.0)
+	stopTimes[1].ShapeDistTraveled = csv_parse.NewOptional(4.0)
+	stopTimes[2].ShapeDistTraveled = csv_parse.NewOptional(5.0)
+	arrivalTimes, _ = interpolateArrivalTimes(stopTimes, stopsById)
+	assert.Equal(t, []model.ArrivalDepartureTime{100, 400, 500}, arrivalTimes)
+
+	// Without shape_dist_traveled at every stop, or coordinates for every stop, stops are spaced evenly
+	stopTimes[1].ShapeDistTraveled = csv_parse.Optional[float64]{}
+	delete(stopsById, "stop2")
+	arrivalTimes, _ = interpolateArrivalTimes(stopTimes, stopsById)
+	assert.Equal(t, []model.ArrivalDepartureTime{100, 300, 500}, arrivalTimes)
+}
+
 func simulateStop(tripDate time.Time, arrivalTime time.Duration, tripId string, stopId string, calculation *OtpCalculation, logger log.Interface) {
 	simulateStopInner(tripDate, arrivalTime, tripId, stopId, calculation, model.StoppedAt, logger)
 }
@@ -137,6 +182,37 @@ func simulateStopInner(tripDate time.Time, arrivalTime time.Duration, tripId str
 	calculation.onNewPositionData(positionData, logger)
 }
 
+func TestOtpGroupedByStopAndStation(t *testing.T) {
+	files := getGtfsFilesWith(t, "stations")
+	files["trips.txt"] = "route_id,service_id,trip_id\nroute15,wkdayService,trip1\nroute15,wkdayService,trip2"
+	files["stop_times.txt"] = "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+		"trip1,08:30:00,08:30:00,platform1,1\n" +
+		"trip1,08:45:00,08:45:00,stop2,2\n" +
+		"trip2,08:40:00,08:40:00,boarding1,1\n" +
+		"trip2,08:55:00,08:55:00,stop2,2"
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(files), StaticParseOptions{})
+	assert.NoError(t, err)
+	calculation, err := CreateOtpCalculation(feed)
+	assert.NoError(t, err)
+	logger := log.New(log.Info)
+	tripDate := time.Date(2023, 6, 5, 0, 0, 0, 0, calculation.Location)
+
+	simulateStop(tripDate, 8*time.Hour+31*time.Minute, "trip1", "platform1", calculation, logger)
+	simulateStop(tripDate, 8*time.Hour+46*time.Minute, "trip1", "stop2", calculation, logger)
+	// Late at track 2
+	simulateStop(tripDate, 8*time.Hour+55*time.Minute, "trip2", "boarding1", calculation, logger)
+	simulateStop(tripDate, 8*time.Hour+56*time.Minute, "trip2", "stop2", calculation, logger)
+
+	startTime, endTime := tripDate.Add(8*time.Hour), tripDate.Add(10*time.Hour)
+	otpByStop := calculation.SummarizeOnTimePerformance(StopId, 7*time.Minute, startTime, endTime, logger)
+	assert.EqualValues(t, "StopId", otpByStop.GroupBy)
+	assert.ElementsMatch(t, []OtpSummaryEntry{{Name: "platform1", OnTimePerformance: 1}, {Name: "boarding1", OnTimePerformance: 0}, {Name: "stop2", OnTimePerformance: 1}}, otpByStop.OtpSummaries)
+
+	otpByStation := calculation.SummarizeOnTimePerformance(StationId, 7*time.Minute, startTime, endTime, logger)
+	assert.EqualValues(t, "StationId", otpByStation.GroupBy)
+	assert.ElementsMatch(t, []OtpSummaryEntry{{Name: "station1", OnTimePerformance: 0.5}, {Name: "stop2", OnTimePerformance: 1}}, otpByStation.OtpSummaries)
+}
+
 func TestOtpSummaryPrint(t *testing.T) {
 	summary := OtpSummary{}
 	summary.GroupBy = "TripId"
@@ -151,3 +227,48 @@ func TestOtpSummaryPrint(t *testing.T) {
 // 	summary, _ := CalculateOtpForTimeRange("/home/sam/Downloads/rtd.db", time.Unix(1692661211, 0), time.Unix(1692662655, 0), 7*time.Minute, log.Info)
 // 	fmt.Println(summary.PrettyPrint())
 // }
+
+func TestOtpMultiAgency(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getGtfsFilesWith(t, "multi_agency")), StaticParseOptions{})
+	assert.NoError(t, err)
+	calculation, err := CreateOtpCalculation(feed)
+	assert.NoError(t, err)
+	logger := log.New(log.Info)
+	denverDate := time.Date(2023, 6, 5, 0, 0, 0, 0, calculation.Location)
+	losAngeles, err := time.LoadLocation("America/Los_Angeles")
+	assert.NoError(t, err)
+	losAngelesDate := time.Date(2023, 6, 5, 0, 0, 0, 0, losAngeles)
+
+	simulateStop(denverDate, 8*time.Hour+31*time.Minute, "trip1", "stop1", calculation, logger)
+	simulateStop(denverDate, 8*time.Hour+46*time.Minute, "trip1", "stop2", calculation, logger)
+	simulateStop(denverDate, 9*time.Hour+20*time.Minute, "bustang1", "stop1", calculation, logger)
+	simulateStop(denverDate, 9*time.Hour+31*time.Minute, "bustang1", "stop2", calculation, logger)
+	// On time in Los Angeles, but an hour late if its schedule were read in Denver time
+	simulateStop(losAngelesDate, 8*time.Hour+31*time.Minute, "zephyr1", "stop1", calculation, logger)
+	simulateStop(losAngelesDate, 8*time.Hour+46*time.Minute, "zephyr1", "stop2", calculation, logger)
+	assert.Equal(t, "amtrak", calculation.TripsByDate[infra.Date{Year: 2023, Month: 6, Day: 5}]["zephyr1"].AgencyId)
+
+	startTime, endTime := denverDate, denverDate.Add(24*time.Hour)
+	otpByAgency := calculation.SummarizeOnTimePerformance(AgencyId, 7*time.Minute, startTime, endTime, logger)
+	calculation.LabelOtpSummary(otpByAgency, NewTranslator(feed, ""))
+	assert.ElementsMatch(t, []OtpSummaryEntry{
+		{Name: "rtd", Label: "RTD", OnTimePerformance: 1},
+		{Name: "bustang", Label: "Bustang", OnTimePerformance: 0.5},
+		{Name: "amtrak", Label: "Amtrak", OnTimePerformance: 1},
+	}, otpByAgency.OtpSummaries)
+
+	otpByTrip := calculation.SummarizeOnTimePerformanceForAgencies([]string{"rtd", "bustang"}, TripId, 7*time.Minute, startTime, endTime, logger)
+	assert.ElementsMatch(t, []OtpSummaryEntry{{Name: "trip1", OnTimePerformance: 1}, {Name: "bustang1", OnTimePerformance: 0.5}}, otpByTrip.OtpSummaries)
+}
+
+func TestOtpTimezoneWithoutAgency(t *testing.T) {
+	feed, _, _, _, _ := createStaticFeed()
+	feed.Agency = nil
+	_, err := CreateOtpCalculation(feed)
+	assert.EqualError(t, err, "cannot lookup agency timezone")
+
+	feed.Stop = []model.Stop{{Id: "stop1", Timezone: "America/Chicago"}}
+	calculation, err := CreateOtpCalculation(feed)
+	assert.NoError(t, err)
+	assert.Equal(t, "America/Chicago", calculation.Location.String())
+}
@@ -0,0 +1,677 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/csv_parse"
+	"github.com/samc1213/gtfs-analyze/infra"
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+	"github.com/samc1213/gtfs-analyze/parquet"
+	"gorm.io/gorm"
+)
+
+// Exports the data in the database at dbPath to Parquet files in outputDir, for tools like DuckDB,
+// pandas and Spark. Each dataset is a folder of Hive-style partitions, with one file per partition:
+//   - static/<table>/version=<version>/part-0.parquet, for each table of each stored feed version
+//   - vehicle_positions/service_date=<yyyy-mm-dd>/part-0.parquet
+//   - stop_events/service_date=<yyyy-mm-dd>/part-0.parquet, the arrivals at and departures from
+//     stops observed in the vehicle positions, as the calculate otp command sees them
+//
+// Only vehicle positions with a position timestamp from startTime to endTime are exported, when they
+// aren't zero. The static, vehicle_positions and stop_events folders of an earlier export are replaced
+func ExportParquet(dbPath string, outputDir string, startTime time.Time, endTime time.Time, logLevel log.Level) error {
+	logger := log.New(logLevel)
+	db, err := openExistingDb(logger, dbPath, logLevel)
+	if err != nil {
+		return err
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDb.Close()
+	repository := NewGormRepository(db)
+	for _, dataset := range []string{"static", "vehicle_positions", "stop_events"} {
+		if err := os.RemoveAll(filepath.Join(outputDir, dataset)); err != nil {
+			return err
+		}
+	}
+
+	locations, err := exportStaticFeedsToParquet(db, repository, outputDir, logger)
+	if err != nil {
+		return err
+	}
+	return exportVehiclePositionsToParquet(db, repository, outputDir, startTime, endTime, locations, logger)
+}
+
+// The path of a file of a Hive-style partition, like dir/service_date=2023-06-08/part-0.parquet. Values
+// are escaped like Hive escapes them, so a value like a feed version with a / is one folder
+func getParquetPartitionPath(dir string, column string, value string, part int) string {
+	return filepath.Join(dir, column+"="+url.PathEscape(value), fmt.Sprintf("part-%d.parquet", part))
+}
+
+// A Parquet file being written
+type parquetFile struct {
+	file   *os.File
+	writer *parquet.Writer
+}
+
+func createParquetFile(path string, columns []parquet.Column) (*parquetFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := parquet.NewWriter(file, columns)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &parquetFile{file: file, writer: writer}, nil
+}
+
+func (file *parquetFile) close() error {
+	err := file.writer.Close()
+	if closeErr := file.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// The files of a dataset, like vehicle_positions, with a file for each value of its partition column
+type parquetPartitions struct {
+	dir     string // Like outputDir/vehicle_positions
+	column  string // Like service_date
+	columns []parquet.Column
+	files   map[string]*parquetFile
+	// How many files each partition has had. Rows for a partition whose file was finished go to a new file
+	fileCounts map[string]int
+}
+
+func newParquetPartitions(dir string, column string, columns []parquet.Column) *parquetPartitions {
+	return &parquetPartitions{dir: dir, column: column, columns: columns, files: make(map[string]*parquetFile), fileCounts: make(map[string]int)}
+}
+
+func (partitions *parquetPartitions) writeRow(value string, row []interface{}) error {
+	file, ok := partitions.files[value]
+	if !ok {
+		var err error
+		file, err = createParquetFile(getParquetPartitionPath(partitions.dir, partitions.column, value, partitions.fileCounts[value]), partitions.columns)
+		if err != nil {
+			return err
+		}
+		partitions.files[value] = file
+		partitions.fileCounts[value]++
+	}
+	return file.writer.WriteRow(row)
+}
+
+// Finishes the files of the partitions with values before value, so their rows don't stay in memory
+func (partitions *parquetPartitions) closeBefore(value string) error {
+	for partitionValue, file := range partitions.files {
+		if partitionValue < value {
+			delete(partitions.files, partitionValue)
+			if err := file.close(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (partitions *parquetPartitions) close() error {
+	var firstErr error
+	for partitionValue, file := range partitions.files {
+		delete(partitions.files, partitionValue)
+		if err := file.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Writes the tables of every stored feed version. Returns the timezone of the most recently
+// downloaded feed of each namespace, which the namespace's vehicle positions are dated in
+func exportStaticFeedsToParquet(db *gorm.DB, feeds FeedRepository, outputDir string, logger log.Interface) (map[string]*time.Location, error) {
+	var feedInfos []model.FeedInfo
+	if err := db.Order("download_time").Find(&feedInfos).Error; err != nil {
+		return nil, err
+	}
+	locations := make(map[string]*time.Location)
+	for _, feedInfo := range feedInfos {
+		logger.Info("Exporting feed version %s to Parquet", feedInfo.Version)
+		feed, err := feeds.GetFeedByVersion(feedInfo.Version)
+		if err != nil {
+			return nil, err
+		}
+		err = writeStaticFeedToParquet(feed, func(table string) string {
+			return getParquetPartitionPath(filepath.Join(outputDir, "static", table), "version", feedInfo.Version, 0)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("feed version %s: %w", feedInfo.Version, err)
+		}
+		locations[feedInfo.Namespace] = getFeedLocation(feed)
+	}
+	logger.Info("Exported %d feed versions to Parquet", len(feedInfos))
+	return locations, nil
+}
+
+// The timezone of a feed's first agency with one
+func getFeedLocation(feed *model.GtfsStaticFeed) *time.Location {
+	for _, agency := range feed.Agency {
+		if agency.Timezone == "" {
+			continue
+		}
+		if location, err := time.LoadLocation(agency.Timezone); err == nil {
+			return location
+		}
+	}
+	return time.UTC
+}
+
+// Writes each table of a feed that has rows to the path for its name, like stop_times
+func writeStaticFeedToParquet(feed *model.GtfsStaticFeed, pathForTable func(table string) string) error {
+	tables := []struct {
+		name  string
+		write func(path string) error
+	}{
+		{"agency", func(path string) error { return writeStaticParquetTable(path, feed.Agency) }},
+		{"stops", func(path string) error { return writeStaticParquetTable(path, feed.Stop) }},
+		{"routes", func(path string) error { return writeStaticParquetTable(path, feed.Route) }},
+		{"trips", func(path string) error { return writeStaticParquetTable(path, feed.Trip) }},
+		{"stop_times", func(path string) error { return writeStaticParquetTable(path, feed.StopTime) }},
+		{"shapes", func(path string) error { return writeStaticParquetTable(path, feed.ShapePoint) }},
+		{"calendar", func(path string) error { return writeStaticParquetTable(path, feed.Calendar) }},
+		{"transfers", func(path string) error { return writeStaticParquetTable(path, feed.Transfer) }},
+		{"levels", func(path string) error { return writeStaticParquetTable(path, feed.Level) }},
+		{"pathways", func(path string) error { return writeStaticParquetTable(path, feed.Pathway) }},
+		{"translations", func(path string) error { return writeStaticParquetTable(path, feed.Translation) }},
+		{"attributions", func(path string) error { return writeStaticParquetTable(path, feed.Attribution) }},
+		{"locations", func(path string) error { return writeLocationsParquetTable(path, feed.Location) }},
+		{"location_groups", func(path string) error { return writeStaticParquetTable(path, feed.LocationGroup) }},
+		{"location_group_stops", func(path string) error { return writeStaticParquetTable(path, feed.LocationGroupStop) }},
+		{"booking_rules", func(path string) error { return writeStaticParquetTable(path, feed.BookingRule) }},
+		{"fare_attributes", func(path string) error { return writeStaticParquetTable(path, feed.FareAttribute) }},
+		{"fare_rules", func(path string) error { return writeStaticParquetTable(path, feed.FareRule) }},
+		{"fare_media", func(path string) error { return writeStaticParquetTable(path, feed.FareMedia) }},
+		{"fare_products", func(path string) error { return writeStaticParquetTable(path, feed.FareProduct) }},
+		{"fare_leg_rules", func(path string) error { return writeStaticParquetTable(path, feed.FareLegRule) }},
+		{"fare_transfer_rules", func(path string) error { return writeStaticParquetTable(path, feed.FareTransferRule) }},
+		{"areas", func(path string) error { return writeStaticParquetTable(path, feed.Area) }},
+		{"stop_areas", func(path string) error { return writeStaticParquetTable(path, feed.StopArea) }},
+		{"timeframes", func(path string) error { return writeStaticParquetTable(path, feed.Timeframe) }},
+		{"networks", func(path string) error { return writeStaticParquetTable(path, feed.Network) }},
+		{"route_networks", func(path string) error { return writeStaticParquetTable(path, feed.RouteNetwork) }},
+		{"feed_info", func(path string) error { return writeStaticParquetTable(path, []model.FeedInfo{feed.FeedInfo}) }},
+	}
+	for _, table := range tables {
+		if err := table.write(pathForTable(table.name)); err != nil {
+			return fmt.Errorf("%s: %w", table.name, err)
+		}
+	}
+	return nil
+}
+
+// The columns of a GTFS file, from the csv_parse tags of its model
+type staticParquetSchema struct {
+	columns       []parquet.Column
+	fieldIdxs     []int    // The field of each tagged column
+	extraColumns  []string // Columns from the records' extra fields, after the tagged columns
+	extraFieldIdx int      // -1 when the model has no extra field
+}
+
+var csvParsePkgPath = reflect.TypeOf(csv_parse.Optional[int]{}).PkgPath()
+
+// The type of the value a csv_parse.Optional holds, or nil if t isn't a csv_parse.Optional
+func getCsvOptionalValueType(t reflect.Type) reflect.Type {
+	if t.Kind() != reflect.Struct || t.PkgPath() != csvParsePkgPath || !strings.HasPrefix(t.Name(), "Optional[") {
+		return nil
+	}
+	field, _ := t.FieldByName("V")
+	return field.Type
+}
+
+// Dates, the only times in GTFS files, are Date columns. Times of day, like arrival_time, are the
+// seconds after midnight they are stored as. Enums are their GTFS codes
+func getParquetType(t reflect.Type) (parquet.Type, error) {
+	if t == reflect.TypeOf(time.Time{}) {
+		return parquet.Date, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return parquet.Boolean, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return parquet.Int32, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return parquet.Int64, nil
+	case reflect.Float32:
+		return parquet.Float, nil
+	case reflect.Float64:
+		return parquet.Double, nil
+	case reflect.String:
+		return parquet.String, nil
+	}
+	return 0, errors.New("can't export " + t.String() + " to Parquet")
+}
+
+func getStaticParquetSchema[T any](records []T) (staticParquetSchema, error) {
+	var record T
+	recordType := reflect.TypeOf(record)
+	schema := staticParquetSchema{extraFieldIdx: -1}
+	tagged := make(map[string]bool)
+	for i := 0; i < recordType.NumField(); i++ {
+		field := recordType.Field(i)
+		tag := field.Tag.Get("csv_parse")
+		if tag == csv_parse.ExtraColumnsTag {
+			schema.extraFieldIdx = i
+			continue
+		}
+		// Like "start_date;timeLayout:20060102"
+		name := strings.TrimSpace(strings.Split(tag, ";")[0])
+		if name == "" {
+			continue
+		}
+		fieldType := field.Type
+		optional := false
+		if valueType := getCsvOptionalValueType(fieldType); valueType != nil {
+			fieldType, optional = valueType, true
+		} else if fieldType.Kind() == reflect.Pointer {
+			fieldType, optional = fieldType.Elem(), true
+		}
+		columnType, err := getParquetType(fieldType)
+		if err != nil {
+			return schema, fmt.Errorf("%s: %w", name, err)
+		}
+		// Missing dates are zero times, which are written as nulls
+		if columnType == parquet.Date {
+			optional = true
+		}
+		schema.columns = append(schema.columns, parquet.Column{Name: name, Type: columnType, Optional: optional})
+		schema.fieldIdxs = append(schema.fieldIdxs, i)
+		tagged[strings.ToLower(name)] = true
+	}
+
+	// Like csv_parse.WriteCsv, extra columns with the name of a tagged column are left out
+	if schema.extraFieldIdx != -1 {
+		extraColumns := make(map[string]bool)
+		for _, record := range records {
+			iter := reflect.ValueOf(record).Field(schema.extraFieldIdx).MapRange()
+			for iter.Next() {
+				if name := iter.Key().String(); !tagged[strings.ToLower(strings.TrimSpace(name))] {
+					extraColumns[name] = true
+				}
+			}
+		}
+		for name := range extraColumns {
+			schema.extraColumns = append(schema.extraColumns, name)
+		}
+		sort.Strings(schema.extraColumns)
+		for _, name := range schema.extraColumns {
+			schema.columns = append(schema.columns, parquet.Column{Name: name, Type: parquet.String, Optional: true})
+		}
+	}
+	return schema, nil
+}
+
+func getStaticParquetValue(field reflect.Value) interface{} {
+	if getCsvOptionalValueType(field.Type()) != nil {
+		if !field.FieldByName("Valid").Bool() {
+			return nil
+		}
+		field = field.FieldByName("V")
+	}
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+	value := field.Interface()
+	if date, ok := value.(time.Time); ok && date.IsZero() {
+		return nil
+	}
+	return value
+}
+
+// Writes the records of a GTFS file to a Parquet file at path, unless there are none. Columns are
+// named like the GTFS file's, with the file's extra columns as strings at the end
+func writeStaticParquetTable[T any](path string, records []T) error {
+	if len(records) == 0 {
+		return nil
+	}
+	schema, err := getStaticParquetSchema(records)
+	if err != nil {
+		return err
+	}
+	file, err := createParquetFile(path, schema.columns)
+	if err != nil {
+		return err
+	}
+	row := make([]interface{}, len(schema.columns))
+	for _, record := range records {
+		recordValue := reflect.ValueOf(record)
+		for i, fieldIdx := range schema.fieldIdxs {
+			row[i] = getStaticParquetValue(recordValue.Field(fieldIdx))
+		}
+		for i, name := range schema.extraColumns {
+			row[len(schema.fieldIdxs)+i] = nil
+			if value := recordValue.Field(schema.extraFieldIdx).MapIndex(reflect.ValueOf(name)); value.IsValid() {
+				row[len(schema.fieldIdxs)+i] = value.String()
+			}
+		}
+		if err := file.writer.WriteRow(row); err != nil {
+			file.close()
+			return err
+		}
+	}
+	return file.close()
+}
+
+// Locations come from locations.geojson, so their columns are the feature's id, its stop_name and
+// stop_desc properties, and its geometry as GeoJSON
+func writeLocationsParquetTable(path string, locations []model.Location) error {
+	if len(locations) == 0 {
+		return nil
+	}
+	file, err := createParquetFile(path, []parquet.Column{
+		{Name: "id", Type: parquet.String},
+		{Name: "stop_name", Type: parquet.String},
+		{Name: "stop_desc", Type: parquet.String},
+		{Name: "geometry", Type: parquet.String},
+	})
+	if err != nil {
+		return err
+	}
+	for _, location := range locations {
+		if err := file.writer.WriteRow([]interface{}{location.Id, location.Name, location.Description, location.Geometry}); err != nil {
+			file.close()
+			return err
+		}
+	}
+	return file.close()
+}
+
+var vehiclePositionParquetColumns = []parquet.Column{
+	{Name: "namespace", Type: parquet.String},
+	{Name: "id", Type: parquet.String},
+	{Name: "message_timestamp", Type: parquet.Timestamp},
+	{Name: "trip_id", Type: parquet.String},
+	{Name: "route_id", Type: parquet.String},
+	{Name: "direction_id", Type: parquet.Int32},
+	{Name: "start_time", Type: parquet.Int32}, // Seconds after midnight
+	{Name: "start_date", Type: parquet.Date, Optional: true},
+	{Name: "schedule_relationship", Type: parquet.Int32},
+	{Name: "vehicle_id", Type: parquet.String},
+	{Name: "vehicle_label", Type: parquet.String},
+	{Name: "license_plate", Type: parquet.String},
+	{Name: "wheelchair_accessible", Type: parquet.Int32},
+	{Name: "latitude", Type: parquet.Double},
+	{Name: "longitude", Type: parquet.Double},
+	{Name: "bearing", Type: parquet.Double},
+	{Name: "odometer", Type: parquet.Double},
+	{Name: "speed", Type: parquet.Double},
+	{Name: "current_stop_sequence", Type: parquet.Int32},
+	{Name: "stop_id", Type: parquet.String},
+	{Name: "current_status", Type: parquet.Int32},
+	{Name: "position_timestamp", Type: parquet.Timestamp},
+	{Name: "congestion_level", Type: parquet.Int32},
+	{Name: "occupancy_status", Type: parquet.Int32},
+	{Name: "occupancy_percentage", Type: parquet.Int64},
+}
+
+func getVehiclePositionParquetRow(position *model.VehiclePosition) []interface{} {
+	var startDate interface{}
+	if !position.StartDate.IsZero() {
+		startDate = position.StartDate
+	}
+	return []interface{}{
+		position.Namespace,
+		position.Id,
+		time.Unix(int64(position.MessageTimestamp), 0),
+		position.TripId,
+		position.RouteId,
+		position.DirectionId,
+		position.StartTime,
+		startDate,
+		position.ScheduleRelationship,
+		position.VehicleId,
+		position.VehicleLabel,
+		position.LicensePlate,
+		position.WheelchairAccessible,
+		position.Latitude,
+		position.Longitude,
+		position.Bearing,
+		position.Odometer,
+		position.Speed,
+		position.CurrentStopSequence,
+		position.StopId,
+		position.CurrentStatus,
+		time.Unix(int64(position.PositionTimestamp), 0),
+		position.CongestionLevel,
+		position.OccupancyStatus,
+		position.OccupancyPercentage,
+	}
+}
+
+// The service date of a vehicle position is the start date of its trip. Positions without one are
+// dated by when they were recorded, in the timezone of their namespace's feed, or else UTC
+func getVehiclePositionServiceDate(position *model.VehiclePosition, locations map[string]*time.Location) time.Time {
+	if !position.StartDate.IsZero() {
+		year, month, day := position.StartDate.Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	location, ok := locations[position.Namespace]
+	if !ok {
+		location = time.UTC
+	}
+	year, month, day := time.Unix(int64(position.PositionTimestamp), 0).In(location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+var stopEventParquetColumns = []parquet.Column{
+	{Name: "feed_version", Type: parquet.String},
+	{Name: "agency_id", Type: parquet.String},
+	{Name: "route_id", Type: parquet.String},
+	{Name: "trip_id", Type: parquet.String},
+	{Name: "stop_sequence", Type: parquet.Int32},
+	{Name: "stop_id", Type: parquet.String},
+	{Name: "scheduled_arrival_time", Type: parquet.Timestamp},
+	{Name: "scheduled_departure_time", Type: parquet.Timestamp},
+	{Name: "actual_arrival_time", Type: parquet.Timestamp, Optional: true},
+	{Name: "actual_departure_time", Type: parquet.Timestamp, Optional: true},
+	{Name: "arrival_delay", Type: parquet.Int64, Optional: true}, // Seconds late, or negative when early
+}
+
+// A service date of a namespace's vehicle positions
+type namespaceDate struct {
+	namespace string
+	date      time.Time
+}
+
+// The OTP calculations of the feeds in effect on the service dates of the exported vehicle positions.
+// Each namespace's positions are matched with its own feeds. Stop events are written once no more
+// positions can be on their dates
+type stopEventExport struct {
+	feeds          FeedRepository
+	calculations   map[string]*OtpCalculation // By feed version
+	versionsByDate map[namespaceDate]string   // "" when no feed was in effect
+	partitions     *parquetPartitions
+	logger         log.Interface
+}
+
+// The calculation for the feed of a namespace in effect on a service date, or nil if there is none
+func (export *stopEventExport) getCalculation(namespace string, serviceDate time.Time) (*OtpCalculation, error) {
+	key := namespaceDate{namespace: namespace, date: serviceDate}
+	version, ok := export.versionsByDate[key]
+	if !ok {
+		year, month, day := serviceDate.Date()
+		feed, err := export.feeds.GetFeedOnDate(namespace, year, month, day)
+		if err != nil {
+			export.logger.Warning("Not exporting stop events on %s: %s", serviceDate.Format("2006-01-02"), err.Error())
+			export.versionsByDate[key] = ""
+			return nil, nil
+		}
+		version = feed.FeedInfo.Version
+		export.versionsByDate[key] = version
+		if _, ok := export.calculations[version]; !ok {
+			calculation, err := CreateOtpCalculation(feed)
+			if err != nil {
+				return nil, fmt.Errorf("feed version %s: %w", version, err)
+			}
+			export.calculations[version] = calculation
+		}
+	}
+	return export.calculations[version], nil
+}
+
+// Writes the stop events on the dates before cutoff, and forgets those dates' trips
+func (export *stopEventExport) writeBefore(cutoff time.Time) error {
+	for version, calculation := range export.calculations {
+		var dates []infra.Date
+		for date := range calculation.TripsByDate {
+			if time.Date(date.Year, date.Month, date.Day, 0, 0, 0, 0, time.UTC).Before(cutoff) {
+				dates = append(dates, date)
+			}
+		}
+		for _, date := range dates {
+			if err := export.writeDate(version, calculation, date); err != nil {
+				return err
+			}
+			delete(calculation.TripsByDate, date)
+		}
+	}
+	return export.partitions.closeBefore(cutoff.Format("2006-01-02"))
+}
+
+func (export *stopEventExport) writeDate(version string, calculation *OtpCalculation, date infra.Date) error {
+	serviceDate := time.Date(date.Year, date.Month, date.Day, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+	trips := calculation.TripsByDate[date]
+	tripIds := make([]string, 0, len(trips))
+	for tripId := range trips {
+		tripIds = append(tripIds, tripId)
+	}
+	sort.Strings(tripIds)
+	for _, tripId := range tripIds {
+		trip := trips[tripId]
+		stopTimes := calculation.EasyLookupFeed.StopTimesByTripId[tripId]
+		for i, stop := range trip.StopTimes {
+			if stop.ActualArrivalTime.IsZero() && stop.ActualDepartureTime.IsZero() {
+				continue
+			}
+			var actualArrival, actualDeparture, arrivalDelay interface{}
+			if !stop.ActualArrivalTime.IsZero() {
+				actualArrival = stop.ActualArrivalTime
+				arrivalDelay = int64(stop.ActualArrivalTime.Sub(stop.StopTime) / time.Second)
+			}
+			if !stop.ActualDepartureTime.IsZero() {
+				actualDeparture = stop.ActualDepartureTime
+			}
+			row := []interface{}{version, trip.AgencyId, trip.RouteId, trip.Id, stopTimes[i].StopSequence, stop.StopId, stop.StopTime, stop.DepartureTime, actualArrival, actualDeparture, arrivalDelay}
+			if err := export.partitions.writeRow(serviceDate, row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// How many days after a service date its vehicle positions can still be recorded, like for trips
+// that run past midnight
+const serviceDateMarginDays = 2
+
+// Vehicle positions are read in the order they were recorded, so a service date's files can be
+// finished once positions are well past it, and only a few days of rows are in memory at once
+func exportVehiclePositionsToParquet(db *gorm.DB, feeds FeedRepository, outputDir string, startTime time.Time, endTime time.Time, locations map[string]*time.Location, logger log.Interface) error {
+	vehiclePositions := newParquetPartitions(filepath.Join(outputDir, "vehicle_positions"), "service_date", vehiclePositionParquetColumns)
+	defer vehiclePositions.close()
+	stopEvents := &stopEventExport{
+		feeds:          feeds,
+		calculations:   make(map[string]*OtpCalculation),
+		versionsByDate: make(map[namespaceDate]string),
+		partitions:     newParquetPartitions(filepath.Join(outputDir, "stop_events"), "service_date", stopEventParquetColumns),
+		logger:         logger,
+	}
+	defer stopEvents.partitions.close()
+
+	query := db.Model(&model.VehiclePosition{})
+	if !startTime.IsZero() {
+		query = query.Where("position_timestamp >= ?", startTime.Unix())
+	}
+	if !endTime.IsZero() {
+		query = query.Where("position_timestamp <= ?", endTime.Unix())
+	}
+	var latestServiceDate time.Time
+	var count int
+	var last *model.VehiclePosition
+	for {
+		var batch []model.VehiclePosition
+		batchQuery := query.Session(&gorm.Session{})
+		if last != nil {
+			batchQuery = batchQuery.Where("position_timestamp > ? OR (position_timestamp = ? AND (namespace > ? OR (namespace = ? AND id > ?)))",
+				last.PositionTimestamp, last.PositionTimestamp, last.Namespace, last.Namespace, last.Id)
+		}
+		if err := batchQuery.Order("position_timestamp, namespace, id").Limit(10000).Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		positionsByCalculation := make(map[*OtpCalculation][]model.VehiclePosition)
+		for i := range batch {
+			position := &batch[i]
+			serviceDate := getVehiclePositionServiceDate(position, locations)
+			if serviceDate.After(latestServiceDate) {
+				latestServiceDate = serviceDate
+				cutoff := latestServiceDate.AddDate(0, 0, -serviceDateMarginDays)
+				if err := vehiclePositions.closeBefore(cutoff.Format("2006-01-02")); err != nil {
+					return err
+				}
+				if err := stopEvents.writeBefore(cutoff); err != nil {
+					return err
+				}
+			}
+			if err := vehiclePositions.writeRow(serviceDate.Format("2006-01-02"), getVehiclePositionParquetRow(position)); err != nil {
+				return err
+			}
+			if position.TripId == "" {
+				continue
+			}
+			calculation, err := stopEvents.getCalculation(position.Namespace, serviceDate)
+			if err != nil {
+				return err
+			}
+			if calculation != nil {
+				positionsByCalculation[calculation] = append(positionsByCalculation[calculation], *position)
+			}
+		}
+		for calculation, positions := range positionsByCalculation {
+			calculation.OnNewPositionData(positions, logger)
+		}
+		count += len(batch)
+		last = &batch[len(batch)-1]
+	}
+
+	if err := stopEvents.writeBefore(latestServiceDate.AddDate(0, 0, serviceDateMarginDays+1)); err != nil {
+		return err
+	}
+	if err := stopEvents.partitions.close(); err != nil {
+		return err
+	}
+	if err := vehiclePositions.close(); err != nil {
+		return err
+	}
+	logger.Info("Exported %d vehicle positions to Parquet", count)
+	return nil
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+	"github.com/samc1213/gtfs-analyze/parquet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStaticParquetSchema(t *testing.T) {
+	stops := []model.Stop{{Extra: map[string]string{"zone_color": "red", "Stop_Name": "Union Station"}}, {Extra: map[string]string{"bay": "A"}}}
+	schema, err := getStaticParquetSchema(stops)
+	assert.NoError(t, err)
+	columns := make(map[string]parquet.Column)
+	for _, column := range schema.columns {
+		columns[column.Name] = column
+	}
+	assert.Equal(t, parquet.Column{Name: "stop_id", Type: parquet.String}, columns["stop_id"])
+	assert.Equal(t, parquet.Double, columns["stop_lat"].Type)
+	// Extra columns with the name of a tagged column are left out
+	assert.Equal(t, []string{"bay", "zone_color"}, schema.extraColumns)
+	assert.Equal(t, parquet.Column{Name: "zone_color", Type: parquet.String, Optional: true}, schema.columns[len(schema.columns)-1])
+
+	schema, err = getStaticParquetSchema([]model.StopTime{})
+	assert.NoError(t, err)
+	for _, column := range schema.columns {
+		columns[column.Name] = column
+	}
+	// Times of day are seconds after midnight, and csv_parse.Optional fields can be null
+	assert.Equal(t, parquet.Column{Name: "arrival_time", Type: parquet.Int64, Optional: true}, columns["arrival_time"])
+	assert.Equal(t, parquet.Column{Name: "continuous_pickup", Type: parquet.Int32, Optional: true}, columns["continuous_pickup"])
+
+	schema, err = getStaticParquetSchema([]model.Calendar{})
+	assert.NoError(t, err)
+	for _, column := range schema.columns {
+		columns[column.Name] = column
+	}
+	assert.Equal(t, parquet.Column{Name: "start_date", Type: parquet.Date, Optional: true}, columns["start_date"])
+}
+
+func TestExportParquet(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "gtfs.db")
+	db, err := InitializeSqliteDatabase(dbPath, log.Silent)
+	assert.NoError(t, err)
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getValidGtfsFiles()), StaticParseOptions{})
+	assert.NoError(t, err)
+	feed.FeedInfo = model.FeedInfo{Version: "2023/06", StartDate: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2023, 6, 30, 0, 0, 0, 0, time.UTC), DownloadTime: time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC)}
+	addVersionToAllObjects(feed, feed.FeedInfo.Version)
+	repository := NewGormRepository(db)
+	assert.NoError(t, repository.WriteFeed(feed))
+
+	location, err := time.LoadLocation("America/Denver")
+	assert.NoError(t, err)
+	june8 := time.Date(2023, 6, 8, 0, 0, 0, 0, location)
+	june12 := time.Date(2023, 6, 12, 0, 0, 0, 0, location)
+	assert.NoError(t, repository.WriteVehiclePositions([]model.VehiclePosition{
+		{Id: "bus1", TripId: "trip1", StopId: "stop1", CurrentStatus: model.StoppedAt, PositionTimestamp: uint64(june8.Add(8*time.Hour + 31*time.Minute).Unix())},
+		{Id: "bus2", TripId: "trip1", StopId: "stop2", CurrentStatus: model.StoppedAt, PositionTimestamp: uint64(june8.Add(8*time.Hour + 55*time.Minute).Unix())},
+		{Id: "bus3", PositionTimestamp: uint64(june12.Add(9 * time.Hour).Unix())},
+		// Dated by its trip's start date, days after the file for that date was finished
+		{Id: "bus4", StartDate: time.Date(2023, 6, 8, 0, 0, 0, 0, time.UTC), PositionTimestamp: uint64(june12.Add(10 * time.Hour).Unix())},
+		// Outside the exported time range
+		{Id: "bus5", PositionTimestamp: uint64(june12.Add(30 * time.Hour).Unix())},
+	}))
+
+	outputDir := t.TempDir()
+	// Left over from an earlier export
+	stalePath := filepath.Join(outputDir, "vehicle_positions", "service_date=2023-06-01", "part-0.parquet")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(stalePath), 0755))
+	assert.NoError(t, os.WriteFile(stalePath, nil, 0644))
+
+	assert.NoError(t, ExportParquet(dbPath, outputDir, june8, june12.Add(24*time.Hour), log.Silent))
+
+	var files []string
+	err = filepath.Walk(outputDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		contents, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "PAR1", string(contents[:4]), path)
+		assert.Equal(t, "PAR1", string(contents[len(contents)-4:]), path)
+		relativePath, _ := filepath.Rel(outputDir, path)
+		files = append(files, filepath.ToSlash(relativePath))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"static/agency/version=2023%2F06/part-0.parquet",
+		"static/calendar/version=2023%2F06/part-0.parquet",
+		"static/feed_info/version=2023%2F06/part-0.parquet",
+		"static/routes/version=2023%2F06/part-0.parquet",
+		"static/stop_times/version=2023%2F06/part-0.parquet",
+		"static/stops/version=2023%2F06/part-0.parquet",
+		"static/trips/version=2023%2F06/part-0.parquet",
+		"stop_events/service_date=2023-06-08/part-0.parquet",
+		"vehicle_positions/service_date=2023-06-08/part-0.parquet",
+		"vehicle_positions/service_date=2023-06-08/part-1.parquet",
+		"vehicle_positions/service_date=2023-06-12/part-0.parquet",
+	}, files)
+}
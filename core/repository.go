@@ -0,0 +1,154 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/model"
+	"gorm.io/gorm"
+)
+
+// Stores and loads static feeds, by version. GormRepository keeps them in the database of the store
+// command, MemoryRepository in memory, and library users can provide their own storage
+type FeedRepository interface {
+	// Stores every entity of a feed whose version isn't stored yet
+	WriteFeed(feed *model.GtfsStaticFeed) error
+	HasFeedVersion(version string) (bool, error)
+	// Loads every entity of a feed version. Stop times are in the order of their trips and stop
+	// sequences, and shape points in the order of their shapes and sequences
+	GetFeedByVersion(version string) (*model.GtfsStaticFeed, error)
+	// Loads the most recently downloaded feed of a namespace that is in effect on a date, and was
+	// downloaded by then. The namespace is "" for feeds stored without one
+	GetFeedOnDate(namespace string, year int, month time.Month, day int) (*model.GtfsStaticFeed, error)
+}
+
+// Stores and queries GTFS-RT entities. An entity replaces the stored entity with the same namespace
+// and id. Entity types are "vehicle_positions", "trip_updates" and "alerts"
+type RealtimeRepository interface {
+	WriteVehiclePositions(vehiclePositions []model.VehiclePosition) error
+	WriteTripUpdates(tripUpdates []model.TripUpdate) error
+	WriteAlerts(alerts []model.Alert) error
+	// The vehicle positions of a namespace whose position timestamp is from startTime to endTime
+	GetVehiclePositions(namespace string, startTime time.Time, endTime time.Time) ([]model.VehiclePosition, error)
+	// The newest message timestamp of the entities of a type in a namespace, or 0 if there are none
+	GetLatestMessageTimestamp(entityType string, namespace string) (uint64, error)
+	// Whether entities of a type from the message with this timestamp are stored in a namespace
+	HasMessage(entityType string, namespace string, messageTimestamp uint64) (bool, error)
+}
+
+// Keeps feeds and GTFS-RT entities in a database opened with InitializeDatabase
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{db: db}
+}
+
+// The model each GTFS-RT entity type is stored as
+var rtModelsByEntityType = map[string]interface{}{
+	"vehicle_positions": &model.VehiclePosition{},
+	"trip_updates":      &model.TripUpdate{},
+	"alerts":            &model.Alert{},
+}
+
+func getRtModel(entityType string) (interface{}, error) {
+	rtModel, ok := rtModelsByEntityType[entityType]
+	if !ok {
+		return nil, fmt.Errorf("unknown GTFS-RT entity type %s", entityType)
+	}
+	return rtModel, nil
+}
+
+func (repository *GormRepository) WriteFeed(feed *model.GtfsStaticFeed) error {
+	return WriteStaticGtfsFeedToDatabase(feed, repository.db)
+}
+
+func (repository *GormRepository) HasFeedVersion(version string) (bool, error) {
+	var count int64
+	result := repository.db.Model(&model.FeedInfo{}).Where("version = ?", version).Count(&count)
+	return count > 0, result.Error
+}
+
+func (repository *GormRepository) GetFeedByVersion(version string) (*model.GtfsStaticFeed, error) {
+	var feedInfo model.FeedInfo
+	tx := repository.db.Where("version = ?", version).First(&feedInfo)
+	if tx.Error != nil {
+		if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("no feed with version %s found in database", version)
+		}
+		return nil, tx.Error
+	}
+	return getFeedForFeedInfo(feedInfo, repository.db)
+}
+
+// Feeds stored without a namespace have a null one
+func whereFeedNamespace(db *gorm.DB, namespace string) *gorm.DB {
+	if namespace == "" {
+		return db.Where("namespace IS NULL OR namespace = ''")
+	}
+	return db.Where("namespace = ?", namespace)
+}
+
+// Like "no feed in effect on 2023-06-08", or "no rtd feed in effect on 2023-06-08"
+func getNoFeedOnDateError(namespace string, date time.Time) error {
+	if namespace == "" {
+		return fmt.Errorf("no feed in effect on %s found in database", date.Format("2006-01-02"))
+	}
+	return fmt.Errorf("no %s feed in effect on %s found in database", namespace, date.Format("2006-01-02"))
+}
+
+func (repository *GormRepository) GetFeedOnDate(namespace string, year int, month time.Month, day int) (*model.GtfsStaticFeed, error) {
+	var feedInfo model.FeedInfo
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	tx := whereFeedNamespace(repository.db, namespace).Where("start_date <= ? and end_date >= ? and cast(download_time as date) <= ?", date, date, date).Order("download_time DESC").First(&feedInfo)
+	if tx.Error != nil {
+		if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			return nil, getNoFeedOnDateError(namespace, date)
+		}
+		return nil, tx.Error
+	}
+	return getFeedForFeedInfo(feedInfo, repository.db)
+}
+
+func (repository *GormRepository) WriteVehiclePositions(vehiclePositions []model.VehiclePosition) error {
+	return WriteRealTimePositionUpdateToDatabase(vehiclePositions, repository.db)
+}
+
+func (repository *GormRepository) WriteTripUpdates(tripUpdates []model.TripUpdate) error {
+	return WriteTripUpdatesToDatabase(tripUpdates, repository.db)
+}
+
+func (repository *GormRepository) WriteAlerts(alerts []model.Alert) error {
+	return WriteAlertsToDatabase(alerts, repository.db)
+}
+
+func (repository *GormRepository) GetVehiclePositions(namespace string, startTime time.Time, endTime time.Time) ([]model.VehiclePosition, error) {
+	var vehiclePositions []model.VehiclePosition
+	tx := repository.db.Where("namespace = ? AND position_timestamp >= ? AND position_timestamp <= ?", namespace, startTime.Unix(), endTime.Unix()).Find(&vehiclePositions)
+	return vehiclePositions, tx.Error
+}
+
+func (repository *GormRepository) GetLatestMessageTimestamp(entityType string, namespace string) (uint64, error) {
+	rtModel, err := getRtModel(entityType)
+	if err != nil {
+		return 0, err
+	}
+	var latestTimestamp *uint64
+	result := repository.db.Model(rtModel).Where("namespace = ?", namespace).Select("MAX(message_timestamp)").Scan(&latestTimestamp)
+	if result.Error != nil || latestTimestamp == nil {
+		return 0, result.Error
+	}
+	return *latestTimestamp, nil
+}
+
+func (repository *GormRepository) HasMessage(entityType string, namespace string, messageTimestamp uint64) (bool, error) {
+	rtModel, err := getRtModel(entityType)
+	if err != nil {
+		return false, err
+	}
+	var count int64
+	result := repository.db.Model(rtModel).Where("namespace = ? AND message_timestamp = ?", namespace, messageTimestamp).Limit(1).Count(&count)
+	return count > 0, result.Error
+}
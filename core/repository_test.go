@@ -0,0 +1,179 @@
+package core
+
+import (
+	"path"
+	"testing"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRepository interface {
+	FeedRepository
+	RealtimeRepository
+}
+
+func TestRepositoriesBehaveAlike(t *testing.T) {
+	db, err := InitializeSqliteDatabase(path.Join(t.TempDir(), "gtfs.db"), log.Silent)
+	assert.NoError(t, err)
+	repositories := map[string]testRepository{"gorm": NewGormRepository(db), "memory": NewMemoryRepository()}
+
+	for name, repository := range repositories {
+		t.Run(name, func(t *testing.T) {
+			june := model.FeedInfo{Version: "june", StartDate: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2023, 6, 30, 0, 0, 0, 0, time.UTC), DownloadTime: time.Date(2023, 5, 31, 12, 0, 0, 0, time.UTC)}
+			summer := model.FeedInfo{Version: "summer", StartDate: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2023, 7, 31, 0, 0, 0, 0, time.UTC), DownloadTime: time.Date(2023, 6, 14, 12, 0, 0, 0, time.UTC)}
+			for _, feedInfo := range []model.FeedInfo{june, summer} {
+				files := getValidGtfsFiles()
+				// Out of order, to check stop times are loaded in the order of their trips' stops
+				files["stop_times.txt"] = "trip_id,arrival_time,departure_time,stop_id,stop_sequence\ntrip1,08:45:00,08:46:00,stop2,2\ntrip1,08:30:00,08:30:00,stop1,1"
+				feed, err := parseStaticGtfsFromFiles(createGtfsFiles(files), StaticParseOptions{})
+				assert.NoError(t, err)
+				feed.FeedInfo = feedInfo
+				addVersionToAllObjects(feed, feedInfo.Version)
+				assert.NoError(t, repository.WriteFeed(feed))
+			}
+
+			exists, err := repository.HasFeedVersion("june")
+			assert.NoError(t, err)
+			assert.True(t, exists)
+			exists, err = repository.HasFeedVersion("july")
+			assert.NoError(t, err)
+			assert.False(t, exists)
+
+			feed, err := repository.GetFeedByVersion("june")
+			assert.NoError(t, err)
+			assert.Equal(t, "june", feed.FeedInfo.Version)
+			assert.Equal(t, 1, len(feed.Trip))
+			assert.Equal(t, "stop1", feed.StopTime[0].StopId)
+			_, err = repository.GetFeedByVersion("july")
+			assert.EqualError(t, err, "no feed with version july found in database")
+
+			// The newest feed in effect on the date
+			for date, version := range map[time.Time]string{
+				time.Date(2023, 6, 10, 0, 0, 0, 0, time.UTC): "june",
+				time.Date(2023, 6, 20, 0, 0, 0, 0, time.UTC): "summer",
+				time.Date(2023, 7, 5, 0, 0, 0, 0, time.UTC):  "summer",
+			} {
+				feed, err := repository.GetFeedOnDate("", date.Year(), date.Month(), date.Day())
+				assert.NoError(t, err)
+				assert.Equal(t, version, feed.FeedInfo.Version, date.String())
+			}
+			_, err = repository.GetFeedOnDate("", 2023, 8, 1)
+			assert.EqualError(t, err, "no feed in effect on 2023-08-01 found in database")
+
+			latestTimestamp, err := repository.GetLatestMessageTimestamp("vehicle_positions", "rtd")
+			assert.NoError(t, err)
+			assert.Zero(t, latestTimestamp)
+			for _, messageTimestamp := range []uint64{1686412800, 1686412830} {
+				assert.NoError(t, repository.WriteVehiclePositions([]model.VehiclePosition{
+					{Namespace: "rtd", Id: "bus1", MessageTimestamp: messageTimestamp, PositionTimestamp: messageTimestamp},
+					{Namespace: "rtd", Id: "bus2", MessageTimestamp: messageTimestamp, PositionTimestamp: messageTimestamp - 600},
+				}))
+			}
+			assert.NoError(t, repository.WriteTripUpdates([]model.TripUpdate{{Namespace: "rtd", Id: "trip1", MessageTimestamp: 1686412800}}))
+			assert.NoError(t, repository.WriteAlerts([]model.Alert{{Namespace: "", Id: "alert1", MessageTimestamp: 1686412830}}))
+
+			latestTimestamp, err = repository.GetLatestMessageTimestamp("vehicle_positions", "rtd")
+			assert.NoError(t, err)
+			assert.EqualValues(t, 1686412830, latestTimestamp)
+			latestTimestamp, err = repository.GetLatestMessageTimestamp("alerts", "rtd")
+			assert.NoError(t, err)
+			assert.Zero(t, latestTimestamp)
+			_, err = repository.GetLatestMessageTimestamp("shapes", "rtd")
+			assert.EqualError(t, err, "unknown GTFS-RT entity type shapes")
+
+			// The later message replaced the entities of the earlier one
+			stored, err := repository.HasMessage("vehicle_positions", "rtd", 1686412800)
+			assert.NoError(t, err)
+			assert.False(t, stored)
+			stored, err = repository.HasMessage("trip_updates", "rtd", 1686412800)
+			assert.NoError(t, err)
+			assert.True(t, stored)
+			stored, err = repository.HasMessage("alerts", "", 1686412830)
+			assert.NoError(t, err)
+			assert.True(t, stored)
+
+			vehiclePositions, err := repository.GetVehiclePositions("rtd", time.Unix(1686412800, 0), time.Unix(1686412830, 0))
+			assert.NoError(t, err)
+			assert.Equal(t, 1, len(vehiclePositions))
+			assert.Equal(t, "bus1", vehiclePositions[0].Id)
+		})
+	}
+}
+
+func TestCalculateOtpWithMemoryRepository(t *testing.T) {
+	feed, tripOneId, stopOneId, stopTwoId, tripDate := createStaticFeed()
+	feed.FeedInfo = model.FeedInfo{Version: "v1", StartDate: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2023, 6, 30, 0, 0, 0, 0, time.UTC), DownloadTime: time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC)}
+	repository := NewMemoryRepository()
+	assert.NoError(t, repository.WriteFeed(feed))
+
+	location, err := time.LoadLocation("America/Denver")
+	assert.NoError(t, err)
+	tripDateInLocation := time.Date(tripDate.Year, tripDate.Month, tripDate.Day, 0, 0, 0, 0, location)
+	// On time at stop one and 10 minutes late at stop two
+	assert.NoError(t, repository.WriteVehiclePositions([]model.VehiclePosition{
+		{Id: "bus1", TripId: tripOneId, StopId: stopOneId, CurrentStatus: model.StoppedAt, PositionTimestamp: uint64(tripDateInLocation.Add(8*time.Hour + 31*time.Minute).Unix())},
+		{Id: "bus2", TripId: tripOneId, StopId: stopTwoId, CurrentStatus: model.StoppedAt, PositionTimestamp: uint64(tripDateInLocation.Add(8*time.Hour + 55*time.Minute).Unix())},
+	}))
+
+	startTime := tripDateInLocation.Add(6 * time.Hour)
+	endTime := tripDateInLocation.Add(10 * time.Hour)
+	summary, err := CalculateOtp(repository, repository, "", startTime, endTime, 5*time.Minute, TripId, nil, "", log.New(log.Silent))
+	assert.NoError(t, err)
+	assert.Equal(t, TripId, summary.GroupBy)
+	assert.Equal(t, 1, len(summary.OtpSummaries))
+	assert.Equal(t, tripOneId, summary.OtpSummaries[0].Name)
+	assert.EqualValues(t, 0.5, summary.OtpSummaries[0].OnTimePerformance)
+}
+
+func TestRepositoriesKeepNamespacesApart(t *testing.T) {
+	db, err := InitializeSqliteDatabase(path.Join(t.TempDir(), "gtfs.db"), log.Silent)
+	assert.NoError(t, err)
+	repositories := map[string]testRepository{"gorm": NewGormRepository(db), "memory": NewMemoryRepository()}
+
+	location, err := time.LoadLocation("America/Denver")
+	assert.NoError(t, err)
+	june8 := time.Date(2023, 6, 8, 0, 0, 0, 0, location)
+	for name, repository := range repositories {
+		t.Run(name, func(t *testing.T) {
+			// The bustang feed was downloaded later, so it would be picked for rtd without the namespace
+			for namespace, downloadTime := range map[string]time.Time{"rtd": time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC), "bustang": time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)} {
+				feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getValidGtfsFiles()), StaticParseOptions{})
+				assert.NoError(t, err)
+				feed.FeedInfo = model.FeedInfo{Version: "v1", StartDate: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2023, 6, 30, 0, 0, 0, 0, time.UTC), DownloadTime: downloadTime}
+				addNamespaceToFeed(feed, namespace)
+				assert.NoError(t, repository.WriteFeed(feed))
+			}
+			// Both namespaces have a trip1 and a bus1. The rtd bus is on time at stop one and 10 minutes
+			// late at stop two, and the bustang bus is an hour late
+			assert.NoError(t, repository.WriteVehiclePositions([]model.VehiclePosition{
+				{Namespace: "rtd", Id: "bus1", TripId: "trip1", StopId: "stop1", CurrentStatus: model.StoppedAt, PositionTimestamp: uint64(june8.Add(8*time.Hour + 31*time.Minute).Unix())},
+				{Namespace: "rtd", Id: "bus2", TripId: "trip1", StopId: "stop2", CurrentStatus: model.StoppedAt, PositionTimestamp: uint64(june8.Add(8*time.Hour + 55*time.Minute).Unix())},
+				{Namespace: "bustang", Id: "bus1", TripId: "trip1", StopId: "stop1", CurrentStatus: model.StoppedAt, PositionTimestamp: uint64(june8.Add(9*time.Hour + 30*time.Minute).Unix())},
+			}))
+
+			feed, err := repository.GetFeedOnDate("rtd", 2023, 6, 8)
+			assert.NoError(t, err)
+			assert.Equal(t, "rtd/v1", feed.FeedInfo.Version)
+			_, err = repository.GetFeedOnDate("", 2023, 6, 8)
+			assert.EqualError(t, err, "no feed in effect on 2023-06-08 found in database")
+			_, err = repository.GetFeedOnDate("metro", 2023, 6, 8)
+			assert.EqualError(t, err, "no metro feed in effect on 2023-06-08 found in database")
+
+			vehiclePositions, err := repository.GetVehiclePositions("bustang", june8, june8.Add(24*time.Hour))
+			assert.NoError(t, err)
+			assert.Equal(t, 1, len(vehiclePositions))
+			assert.Equal(t, "bustang", vehiclePositions[0].Namespace)
+
+			summary, err := CalculateOtp(repository, repository, "rtd", june8.Add(6*time.Hour), june8.Add(10*time.Hour), 5*time.Minute, TripId, nil, "", log.New(log.Silent))
+			assert.NoError(t, err)
+			assert.Equal(t, 1, len(summary.OtpSummaries))
+			assert.EqualValues(t, 0.5, summary.OtpSummaries[0].OnTimePerformance)
+			summary, err = CalculateOtp(repository, repository, "bustang", june8.Add(6*time.Hour), june8.Add(10*time.Hour), 5*time.Minute, TripId, nil, "", log.New(log.Silent))
+			assert.NoError(t, err)
+			assert.EqualValues(t, 0, summary.OtpSummaries[0].OnTimePerformance)
+		})
+	}
+}
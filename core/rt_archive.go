@@ -0,0 +1,260 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Where the gzipped payloads of archived GTFS-RT messages are kept, by the hex SHA-256 of the payload
+type rawPayloadStore interface {
+	// Does nothing if the payload is already stored
+	put(hash string, compressed []byte) error
+	get(hash string) ([]byte, error)
+}
+
+// Keeps payloads in the raw_payloads table
+type dbPayloadStore struct {
+	db *gorm.DB
+}
+
+func (store *dbPayloadStore) put(hash string, compressed []byte) error {
+	return store.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.RawPayload{Hash: hash, Data: compressed}).Error
+}
+
+func (store *dbPayloadStore) get(hash string) ([]byte, error) {
+	var payload model.RawPayload
+	result := store.db.Where("hash = ?", hash).Limit(1).Find(&payload)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("payload %s isn't in the database. If it was archived to a directory, pass that directory", hash)
+	}
+	return payload.Data, nil
+}
+
+// Keeps payloads in files like dir/ab/ab12....pb.gz, so a large archive doesn't grow the database
+type dirPayloadStore struct {
+	dir string
+}
+
+func (store *dirPayloadStore) path(hash string) string {
+	return filepath.Join(store.dir, hash[:2], hash+".pb.gz")
+}
+
+func (store *dirPayloadStore) put(hash string, compressed []byte) error {
+	path := store.path(hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so a payload file is never left half written
+	file, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(compressed)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (store *dirPayloadStore) get(hash string) ([]byte, error) {
+	data, err := os.ReadFile(store.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("payload %s isn't in the archive directory %s", hash, store.dir)
+	}
+	return data, err
+}
+
+// The exact GTFS-RT messages fetched by the store command, indexed in the raw_feed_messages table by
+// the timestamp in their header. Each distinct payload is stored once, compressed
+type rawArchive struct {
+	db       *gorm.DB
+	payloads rawPayloadStore
+}
+
+// Archives into db, with the payloads in dir, or in db too when dir is empty
+func newRawArchive(db *gorm.DB, dir string) *rawArchive {
+	if dir == "" {
+		return &rawArchive{db: db, payloads: &dbPayloadStore{db: db}}
+	}
+	return &rawArchive{db: db, payloads: &dirPayloadStore{dir: dir}}
+}
+
+// Archives a message fetched from namespace's entityType url. A payload that isn't a FeedMessage is
+// a *feedParseError, and isn't archived
+func (archive *rawArchive) add(namespace string, entityType string, protoBytes []byte) error {
+	feedMessage, err := unmarshalFeedMessage(protoBytes)
+	if err != nil {
+		return &feedParseError{err}
+	}
+	hash := hashPayload(protoBytes)
+	compressed, err := compressPayload(protoBytes)
+	if err != nil {
+		return err
+	}
+	if err := archive.payloads.put(hash, compressed); err != nil {
+		return err
+	}
+	message := model.RawFeedMessage{
+		Namespace:       namespace,
+		EntityType:      entityType,
+		PayloadHash:     hash,
+		HeaderTimestamp: feedMessage.GetHeader().GetTimestamp(),
+		FetchTime:       time.Now(),
+		Size:            len(protoBytes),
+	}
+	return archive.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&message).Error
+}
+
+// Reads the payload of an archived message, checking it is the one that was archived
+func (archive *rawArchive) payload(message *model.RawFeedMessage) ([]byte, error) {
+	compressed, err := archive.payloads.get(message.PayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	protoBytes, err := decompressPayload(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("payload %s: %w", message.PayloadHash, err)
+	}
+	if hashPayload(protoBytes) != message.PayloadHash {
+		return nil, fmt.Errorf("payload %s is corrupt: its hash doesn't match", message.PayloadHash)
+	}
+	return protoBytes, nil
+}
+
+func hashPayload(protoBytes []byte) string {
+	hash := sha256.Sum256(protoBytes)
+	return hex.EncodeToString(hash[:])
+}
+
+func compressPayload(protoBytes []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(protoBytes); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+func decompressPayload(compressed []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// Ingests the GTFS-RT messages archived in the database at fromDbPath again, oldest first, into the
+// database at dbPath. Entities are converted by the current schema, so changes to it apply to data
+// stored before the change. Payloads are read from archiveDir if they were archived to a directory.
+// Messages that can't be converted are logged and skipped
+func ReplayRawArchive(fromDbPath string, archiveDir string, dbPath string, logLevel log.Level) error {
+	if filepath.Clean(fromDbPath) == filepath.Clean(dbPath) {
+		return errors.New("can't replay an archive into the database it is in")
+	}
+	logger := log.New(logLevel)
+	fromDb, err := openExistingDb(logger, fromDbPath, logLevel)
+	if err != nil {
+		return err
+	}
+	fromSqlDb, err := fromDb.DB()
+	if err != nil {
+		return err
+	}
+	defer fromSqlDb.Close()
+	db, err := initializeDb(logger, dbPath, logLevel)
+	if err != nil {
+		return err
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDb.Close()
+
+	archive := newRawArchive(fromDb, archiveDir)
+	repository := NewGormRepository(db)
+	// By namespace and entity type
+	trackers := make(map[[2]string]*LatestRtUpdateTracker)
+	writeNow := func(writeToDb func() error) error { return writeToDb() }
+	var replayed, duplicates, unconvertible int
+	// Paged by header timestamp and id, since gorm's FindInBatches only pages by id
+	var last *model.RawFeedMessage
+	for {
+		var messages []model.RawFeedMessage
+		query := fromDb.Session(&gorm.Session{})
+		if last != nil {
+			query = query.Where("header_timestamp > ? OR (header_timestamp = ? AND id > ?)", last.HeaderTimestamp, last.HeaderTimestamp, last.Id)
+		}
+		if err := query.Order("header_timestamp, id").Limit(100).Find(&messages).Error; err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			break
+		}
+
+		for i := range messages {
+			message := &messages[i]
+			rtType, ok := rtEntityTypes[message.EntityType]
+			if !ok {
+				return fmt.Errorf("archived message %d has unknown entity type %s", message.Id, message.EntityType)
+			}
+			key := [2]string{message.Namespace, message.EntityType}
+			tracker, ok := trackers[key]
+			if !ok {
+				tracker, err = newNamespaceUpdateTracker(repository, message.EntityType, message.Namespace)
+				if err != nil {
+					return err
+				}
+				trackers[key] = tracker
+			}
+			protoBytes, err := archive.payload(message)
+			if err != nil {
+				return err
+			}
+
+			_, err = rtType.convertAndWrite(protoBytes, message.Namespace, tracker, repository, writeNow)
+			var parseErr *feedParseError
+			switch {
+			case errors.Is(err, errDuplicateMessage):
+				duplicates++
+			case errors.As(err, &parseErr):
+				logger.Warning("Skipping archived %s message %d, which can't be converted: %s", rtType.name, message.Id, err.Error())
+				unconvertible++
+			case err != nil:
+				return err
+			default:
+				replayed++
+			}
+		}
+		last = &messages[len(messages)-1]
+	}
+	logger.Info("Replayed %d archived messages. Skipped %d that were already stored and %d that couldn't be converted", replayed, duplicates, unconvertible)
+	return nil
+}
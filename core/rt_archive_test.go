@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/gtfs_realtime"
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestArchiveAndReplay(t *testing.T) {
+	vehiclePositions, err := os.ReadFile(path.Join(getTestFilesPath(), "VehiclePosition_RTD_2023_05_23.pb"))
+	assert.NoError(t, err)
+	tripUpdatesAndAlerts, err := proto.Marshal(getTripUpdateAndAlertMessage())
+	assert.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/VehiclePosition.pb":
+			w.Write(vehiclePositions)
+		case "/realtime.pb":
+			w.Write(tripUpdatesAndAlerts)
+		}
+	}))
+	defer server.Close()
+
+	for _, archiveDir := range []string{"", filepath.Join(t.TempDir(), "archive")} {
+		noPolling := time.Duration(0)
+		config := StoreConfig{DbPath: path.Join(t.TempDir(), "gtfs.db"), ArchiveRaw: true, ArchiveDir: archiveDir, Feeds: []FeedConfig{{
+			Namespace:           "rtd",
+			VehiclePositionsUrl: server.URL + "/VehiclePosition.pb",
+			TripUpdatesUrl:      server.URL + "/realtime.pb",
+			AlertsUrl:           server.URL + "/realtime.pb",
+			RtPollInterval:      &noPolling,
+		}}}
+		assert.NoError(t, StoreFeeds(context.Background(), &config, log.Silent))
+		// The same messages aren't archived again
+		assert.NoError(t, StoreFeeds(context.Background(), &config, log.Silent))
+
+		db, err := InitializeSqliteDatabase(config.DbPath, log.Silent)
+		assert.NoError(t, err)
+		var messages []model.RawFeedMessage
+		assert.NoError(t, db.Order("entity_type").Find(&messages).Error)
+		assert.Equal(t, 3, len(messages))
+		assert.Equal(t, "alerts", messages[0].EntityType)
+		assert.Equal(t, len(tripUpdatesAndAlerts), messages[0].Size)
+		assert.Equal(t, hashPayload(vehiclePositions), messages[2].PayloadHash)
+		assert.EqualValues(t, 1684864397, messages[2].HeaderTimestamp)
+		// Trip updates and alerts come from the same payload, which is stored once
+		var payloadCount int64
+		assert.NoError(t, db.Model(&model.RawPayload{}).Count(&payloadCount).Error)
+		if archiveDir == "" {
+			assert.EqualValues(t, 2, payloadCount)
+		} else {
+			assert.EqualValues(t, 0, payloadCount)
+			payloadFiles, err := filepath.Glob(filepath.Join(archiveDir, "*", "*.pb.gz"))
+			assert.NoError(t, err)
+			assert.Equal(t, 2, len(payloadFiles))
+		}
+
+		replayDbPath := path.Join(t.TempDir(), "replay.db")
+		assert.NoError(t, ReplayRawArchive(config.DbPath, archiveDir, replayDbPath, log.Silent))
+		replayDb, err := InitializeSqliteDatabase(replayDbPath, log.Silent)
+		assert.NoError(t, err)
+		for _, rtModel := range []interface{}{&model.VehiclePosition{}, &model.TripUpdate{}, &model.Alert{}} {
+			var storedCount, replayedCount int64
+			assert.NoError(t, db.Model(rtModel).Where("namespace = ?", "rtd").Count(&storedCount).Error)
+			assert.NoError(t, replayDb.Model(rtModel).Where("namespace = ?", "rtd").Count(&replayedCount).Error)
+			assert.NotZero(t, storedCount)
+			assert.Equal(t, storedCount, replayedCount)
+		}
+		var fetchLogCount int64
+		assert.NoError(t, replayDb.Model(&model.FetchLog{}).Count(&fetchLogCount).Error)
+		assert.Zero(t, fetchLogCount)
+	}
+
+	assert.EqualError(t, ReplayRawArchive("gtfs.db", "", "./gtfs.db", log.Silent), "can't replay an archive into the database it is in")
+	// A mistyped archive isn't created as an empty one
+	missingDbPath := path.Join(t.TempDir(), "missing.db")
+	assert.EqualError(t, ReplayRawArchive(missingDbPath, "", path.Join(t.TempDir(), "replay.db"), log.Silent), "no database found at "+missingDbPath)
+	assert.NoFileExists(t, missingDbPath)
+}
+
+func TestReplayInHeaderTimestampOrder(t *testing.T) {
+	dbPath := path.Join(t.TempDir(), "gtfs.db")
+	db, err := InitializeSqliteDatabase(dbPath, log.Silent)
+	assert.NoError(t, err)
+	archive := newRawArchive(db, "")
+	// Two feeds archived in turn, whose clocks are 500 seconds apart, so the ids of the messages
+	// aren't in the order of their header timestamps
+	for i := 0; i < 150; i++ {
+		for _, namespace := range []string{"ahead", "behind"} {
+			timestamp := uint64(1686236400 + i)
+			if namespace == "ahead" {
+				timestamp += 500
+			}
+			message := &gtfs_realtime.FeedMessage{
+				Header: &gtfs_realtime.FeedHeader{GtfsRealtimeVersion: proto.String("2.0"), Timestamp: proto.Uint64(timestamp)},
+				Entity: []*gtfs_realtime.FeedEntity{createVehiclePositionEntity(fmt.Sprint(i), "trip1", "route15", "stop1", 1, 39.7525, -105.0001, timestamp)},
+			}
+			protoBytes, err := proto.Marshal(message)
+			assert.NoError(t, err)
+			assert.NoError(t, archive.add(namespace, "vehicle_positions", protoBytes))
+		}
+	}
+
+	replayDbPath := path.Join(t.TempDir(), "replay.db")
+	assert.NoError(t, ReplayRawArchive(dbPath, "", replayDbPath, log.Silent))
+	replayDb, err := InitializeSqliteDatabase(replayDbPath, log.Silent)
+	assert.NoError(t, err)
+	for _, namespace := range []string{"ahead", "behind"} {
+		var count int64
+		assert.NoError(t, replayDb.Model(&model.VehiclePosition{}).Where("namespace = ?", namespace).Count(&count).Error)
+		assert.EqualValues(t, 150, count, namespace)
+	}
+}
@@ -0,0 +1,278 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+)
+
+// Used to estimate how long a pathway takes when it doesn't have a traversal_time
+const WALKING_SPEED_METERS_PER_SECOND = 1.2
+const STAIR_TRAVERSAL_TIME = 500 * time.Millisecond
+const DEFAULT_PATHWAY_TRAVERSAL_TIME = 30 * time.Second
+
+type pathwayEdge struct {
+	pathwayId     string
+	toStopId      string
+	traversalTime time.Duration
+}
+
+// The locations of stations, and the pathways between them, from stops.txt and pathways.txt
+type StationGraph struct {
+	stopsById         map[string]*model.Stop
+	childStopIdsById  map[string][]string
+	edgesByFromStopId map[string][]pathwayEdge
+}
+
+type PlatformWalkingTime struct {
+	FromStopId         string   `json:"from_stop_id"`
+	ToStopId           string   `json:"to_stop_id"`
+	Reachable          bool     `json:"reachable"`
+	WalkingTimeSeconds float64  `json:"walking_time_seconds"`
+	PathwayIds         []string `json:"pathway_ids"`
+}
+
+type StationWalkingTimes struct {
+	Version     string                `json:"version"`
+	StationId   string                `json:"station_id"`
+	StationName string                `json:"station_name"`
+	Times       []PlatformWalkingTime `json:"times"`
+}
+
+func NewStationGraph(feed *model.GtfsStaticFeed) *StationGraph {
+	graph := StationGraph{
+		stopsById:         make(map[string]*model.Stop, len(feed.Stop)),
+		childStopIdsById:  make(map[string][]string),
+		edgesByFromStopId: make(map[string][]pathwayEdge),
+	}
+	for i := range feed.Stop {
+		stop := &feed.Stop[i]
+		graph.stopsById[stop.Id] = stop
+		if stop.ParentStationId != "" {
+			graph.childStopIdsById[stop.ParentStationId] = append(graph.childStopIdsById[stop.ParentStationId], stop.Id)
+		}
+	}
+	for _, pathway := range feed.Pathway {
+		traversalTime := getPathwayTraversalTime(&pathway)
+		graph.edgesByFromStopId[pathway.FromStopId] = append(graph.edgesByFromStopId[pathway.FromStopId], pathwayEdge{pathwayId: pathway.Id, toStopId: pathway.ToStopId, traversalTime: traversalTime})
+		if pathway.IsBidirectional == model.Bidirectional {
+			graph.edgesByFromStopId[pathway.ToStopId] = append(graph.edgesByFromStopId[pathway.ToStopId], pathwayEdge{pathwayId: pathway.Id, toStopId: pathway.FromStopId, traversalTime: traversalTime})
+		}
+	}
+	return &graph
+}
+
+// Uses the traversal_time of the pathway if there is one. Otherwise, the time is estimated from its
+// length or its number of stairs
+func getPathwayTraversalTime(pathway *model.Pathway) time.Duration {
+	if pathway.TraversalTime.Valid {
+		return time.Duration(pathway.TraversalTime.V) * time.Second
+	}
+	if pathway.Length.Valid {
+		return time.Duration(pathway.Length.V / WALKING_SPEED_METERS_PER_SECOND * float64(time.Second))
+	}
+	if pathway.StairCount.Valid {
+		stairCount := pathway.StairCount.V
+		if stairCount < 0 {
+			stairCount = -stairCount
+		}
+		return time.Duration(stairCount) * STAIR_TRAVERSAL_TIME
+	}
+	return DEFAULT_PATHWAY_TRAVERSAL_TIME
+}
+
+// Returns the station a stop belongs to, following parent_station from boarding areas to platforms
+// to stations. Stops that aren't part of a station are their own station
+func (graph *StationGraph) GetStationId(stopId string) string {
+	return getParentStationId(graph.stopsById, stopId)
+}
+
+func getParentStationId(stopsById map[string]*model.Stop, stopId string) string {
+	// Boarding areas are two levels below their station, so this loop runs at most twice for a valid feed
+	for i := 0; i < 2; i++ {
+		stop, ok := stopsById[stopId]
+		if !ok || stop.LocationType == model.Station || stop.ParentStationId == "" {
+			break
+		}
+		stopId = stop.ParentStationId
+	}
+	return stopId
+}
+
+// Pathways to a platform with boarding areas end at the boarding areas, so they all count as the platform
+func (graph *StationGraph) getPlatformNodes(platformId string) []string {
+	nodes := []string{platformId}
+	for _, childId := range graph.childStopIdsById[platformId] {
+		if graph.stopsById[childId].LocationType == model.BoardingArea {
+			nodes = append(nodes, childId)
+		}
+	}
+	return nodes
+}
+
+// Finds the fastest way to walk between two platforms through the station's pathways. Returns the
+// walking time and the pathways taken, or false if there is no way between them
+func (graph *StationGraph) GetWalkingTime(fromStopId string, toStopId string) (time.Duration, []string, bool) {
+	targets := make(map[string]struct{})
+	for _, node := range graph.getPlatformNodes(toStopId) {
+		targets[node] = struct{}{}
+	}
+
+	// Dijkstra's algorithm. Stations are small, so a linear scan for the closest node is fine
+	walkingTimes := make(map[string]time.Duration)
+	previousEdges := make(map[string]pathwayEdge)
+	previousStopIds := make(map[string]string)
+	visited := make(map[string]struct{})
+	for _, node := range graph.getPlatformNodes(fromStopId) {
+		walkingTimes[node] = 0
+	}
+	for {
+		closestStopId := ""
+		for stopId, walkingTime := range walkingTimes {
+			if _, ok := visited[stopId]; ok {
+				continue
+			}
+			if closestStopId == "" || walkingTime < walkingTimes[closestStopId] || (walkingTime == walkingTimes[closestStopId] && stopId < closestStopId) {
+				closestStopId = stopId
+			}
+		}
+		if closestStopId == "" {
+			return 0, nil, false
+		}
+		if _, ok := targets[closestStopId]; ok {
+			var pathwayIds []string
+			for stopId := closestStopId; ; {
+				edge, ok := previousEdges[stopId]
+				if !ok {
+					break
+				}
+				pathwayIds = append([]string{edge.pathwayId}, pathwayIds...)
+				stopId = previousStopIds[stopId]
+			}
+			return walkingTimes[closestStopId], pathwayIds, true
+		}
+		visited[closestStopId] = struct{}{}
+		for _, edge := range graph.edgesByFromStopId[closestStopId] {
+			walkingTime := walkingTimes[closestStopId] + edge.traversalTime
+			if current, ok := walkingTimes[edge.toStopId]; !ok || walkingTime < current {
+				walkingTimes[edge.toStopId] = walkingTime
+				previousEdges[edge.toStopId] = edge
+				previousStopIds[edge.toStopId] = closestStopId
+			}
+		}
+	}
+}
+
+// Returns the walking times between every pair of platforms in a station
+func (graph *StationGraph) GetStationWalkingTimes(stationId string) (*StationWalkingTimes, error) {
+	station, ok := graph.stopsById[stationId]
+	if !ok {
+		return nil, fmt.Errorf("stop %s does not exist", stationId)
+	}
+	if station.LocationType != model.Station {
+		return nil, fmt.Errorf("stop %s is not a station", stationId)
+	}
+
+	var platformIds []string
+	for _, childId := range graph.childStopIdsById[stationId] {
+		if graph.stopsById[childId].LocationType == model.StopLocationType {
+			platformIds = append(platformIds, childId)
+		}
+	}
+	sort.Strings(platformIds)
+
+	result := StationWalkingTimes{StationId: stationId, StationName: station.Name, Times: []PlatformWalkingTime{}}
+	for _, fromStopId := range platformIds {
+		for _, toStopId := range platformIds {
+			if fromStopId == toStopId {
+				continue
+			}
+			walkingTime, pathwayIds, reachable := graph.GetWalkingTime(fromStopId, toStopId)
+			result.Times = append(result.Times, PlatformWalkingTime{
+				FromStopId:         fromStopId,
+				ToStopId:           toStopId,
+				Reachable:          reachable,
+				WalkingTimeSeconds: walkingTime.Seconds(),
+				PathwayIds:         pathwayIds,
+			})
+		}
+	}
+	return &result, nil
+}
+
+// Finds the walking times in a station of a feed stored in the database. When version is empty, the
+// feed of namespace that is active today is used
+func GetStationWalkingTimesForVersion(dbPath string, namespace string, version string, stationId string, language string, logLevel log.Level) (*StationWalkingTimes, error) {
+	logger := log.New(logLevel)
+
+	db, err := openExistingDb(logger, dbPath, logLevel)
+	if err != nil {
+		return nil, err
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlDb.Close()
+
+	var feed *model.GtfsStaticFeed
+	if version == "" {
+		year, month, day := time.Now().Date()
+		feed, err = GetFeedOnDate(namespace, year, month, day, db)
+	} else {
+		feed, err = GetFeedByVersion(version, db)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return getStationWalkingTimes(feed, stationId, language)
+}
+
+// Finds the walking times in a station of a local GTFS zip file or directory
+func GetStationWalkingTimesForPath(path string, stationId string, language string, logLevel log.Level) (*StationWalkingTimes, error) {
+	logger := log.New(logLevel)
+
+	logger.Info("Parsing static GTFS from path: %s", path)
+	feed, err := ParseStaticGtfsFromPath(path, StaticParseOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return getStationWalkingTimes(feed, stationId, language)
+}
+
+// The station name is translated to language, if the feed has a translation for it
+func getStationWalkingTimes(feed *model.GtfsStaticFeed, stationId string, language string) (*StationWalkingTimes, error) {
+	graph := NewStationGraph(feed)
+	result, err := graph.GetStationWalkingTimes(stationId)
+	if err != nil {
+		return nil, err
+	}
+	result.Version = feed.FeedInfo.Version
+	result.StationName = NewTranslator(feed, language).StopName(graph.stopsById[stationId])
+	return result, nil
+}
+
+func (result *StationWalkingTimes) PrettyPrint() string {
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "Walking times between platforms of station %s (%s)\n", result.StationId, result.StationName)
+	if len(result.Times) == 0 {
+		fmt.Fprintf(&builder, "The station has fewer than two platforms\n")
+		return builder.String()
+	}
+	writer := tabwriter.NewWriter(&builder, 0, 0, 0, ' ', tabwriter.Debug)
+	fmt.Fprintf(writer, "From\tTo\tWalkingTime\tPathways\n")
+	for _, platformTime := range result.Times {
+		walkingTime := "unreachable"
+		if platformTime.Reachable {
+			walkingTime = fmt.Sprintf("%.0fs", platformTime.WalkingTimeSeconds)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", platformTime.FromStopId, platformTime.ToStopId, walkingTime, strings.Join(platformTime.PathwayIds, ","))
+	}
+	writer.Flush()
+	return builder.String()
+}
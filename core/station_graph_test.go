@@ -0,0 +1,64 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStationWalkingTimes(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getGtfsFilesWith(t, "stations")), StaticParseOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(feed.Level))
+	assert.Equal(t, 4, len(feed.Pathway))
+	assert.Equal(t, "L2", feed.Stop[2].LevelId)
+	assert.Equal(t, "2", feed.Stop[2].PlatformCode)
+
+	graph := NewStationGraph(feed)
+	assert.Equal(t, "station1", graph.GetStationId("boarding1"))
+	assert.Equal(t, "station1", graph.GetStationId("platform1"))
+	assert.Equal(t, "stop2", graph.GetStationId("stop2"))
+
+	// The escalator only goes down to track 2, so the way back is up the stairs and through the mezzanine
+	walkingTime, pathwayIds, ok := graph.GetWalkingTime("platform1", "platform2")
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, walkingTime)
+	assert.Equal(t, []string{"escalator1"}, pathwayIds)
+	walkingTime, pathwayIds, ok = graph.GetWalkingTime("platform2", "platform1")
+	assert.True(t, ok)
+	assert.Equal(t, 80*time.Second, walkingTime)
+	assert.Equal(t, []string{"stairs1", "walk1"}, pathwayIds)
+	// Pathways without a time or length use the default
+	walkingTime, _, ok = graph.GetWalkingTime("entrance1", "platform1")
+	assert.True(t, ok)
+	assert.Equal(t, DEFAULT_PATHWAY_TRAVERSAL_TIME+60*time.Second, walkingTime)
+
+	result, err := graph.GetStationWalkingTimes("station1")
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(result.Times))
+	assert.Equal(t, PlatformWalkingTime{FromStopId: "platform1", ToStopId: "platform3"}, result.Times[1])
+	assert.Contains(t, result.PrettyPrint(), "platform2|platform1|80s        |stairs1,walk1")
+	assert.Contains(t, result.PrettyPrint(), "platform1|platform3|unreachable|")
+
+	_, err = graph.GetStationWalkingTimes("platform1")
+	assert.EqualError(t, err, "stop platform1 is not a station")
+	_, err = graph.GetStationWalkingTimes("station9")
+	assert.EqualError(t, err, "stop station9 does not exist")
+}
+
+func TestValidatePathways(t *testing.T) {
+	files := getGtfsFilesWith(t, "stations")
+	files["stops.txt"] += "\nstop3,Colfax,39.74,-104.98,0,,L9,"
+	files["pathways.txt"] += "\nwalk1,station1,stop9,8,1,,0,\nexit1,entrance1,node1,7,1,,,"
+	report := ValidateStaticGtfsFiles(createGtfsFiles(files))
+	assert.ElementsMatch(t, []ValidationFinding{
+		{File: "stops.txt", Line: 10, Severity: SeverityError, Code: ForeignKeyViolation, Message: "level_id L9 does not exist in levels.txt"},
+		{File: "pathways.txt", Line: 6, Severity: SeverityError, Code: DuplicateKey, Message: "duplicate pathway_id walk1"},
+		{File: "pathways.txt", Line: 6, Severity: SeverityError, Code: InvalidEnumValue, Message: "pathway_mode must be between 1 and 7, found 8"},
+		{File: "pathways.txt", Line: 6, Severity: SeverityError, Code: InvalidRow, Message: "from_stop_id station1 is a station, which can't be the end of a pathway"},
+		{File: "pathways.txt", Line: 6, Severity: SeverityError, Code: ForeignKeyViolation, Message: "to_stop_id stop9 does not exist in stops.txt"},
+		{File: "pathways.txt", Line: 6, Severity: SeverityError, Code: InvalidRow, Message: "traversal_time must be positive, found 0"},
+		{File: "pathways.txt", Line: 7, Severity: SeverityError, Code: InvalidRow, Message: "exit gate exit1 must not be bidirectional"},
+	}, report.Findings)
+}
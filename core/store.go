@@ -1,177 +1,509 @@
 package core
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/samc1213/gtfs-analyze/csv_parse"
 	"github.com/samc1213/gtfs-analyze/log"
 	"github.com/samc1213/gtfs-analyze/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func Store(sqliteDbPath string, staticGtfsUrl string, vehiclePositionUrl string,
-	staticPollIntervalMins uint, rtPollIntervalSecs uint, logLevel log.Level) (*chan struct{}, error) {
+// Returned when a poll found the same feed that was stored last time. Not a failure
+var errDuplicateMessage = errors.New("feed hasn't changed since it was last stored")
+
+// A response that was fetched but couldn't be parsed as a feed
+type feedParseError struct {
+	err error
+}
+
+func (err *feedParseError) Error() string {
+	return err.err.Error()
+}
+
+func (err *feedParseError) Unwrap() error {
+	return err.err
+}
+
+// A fetch and write of one url of one feed, repeated every interval. Every poll is recorded in the
+// fetch log
+type storePoller struct {
+	name       string
+	namespace  string
+	url        string
+	entityType string
+	logger     log.Interface
+	interval   time.Duration
+	// Fills in the http status, attempts and entities of the fetch log entry
+	poll   func(ctx context.Context, entry *model.FetchLog) error
+	record func(entry *model.FetchLog) error
+}
+
+// Stores every feed of the config into one database, with a poller for each url of each feed, until
+// ctx is cancelled. A poller with a zero interval fetches once, and if it fails, the other pollers
+// are stopped and its error is returned. Other pollers log their errors and keep polling, and a
+// poller that panics is restarted. On cancellation, fetches in progress are abandoned, but writes in
+// progress are committed before returning
+func StoreFeeds(ctx context.Context, config *StoreConfig, logLevel log.Level) error {
 	logger := log.New(logLevel)
+	db, err := initializeDb(logger, config.DbPath, logLevel)
+	if err != nil {
+		return err
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDb.Close()
 
-	db, err := initializeSqliteDb(logger, sqliteDbPath, logLevel)
+	pollers, err := createStorePollers(config, db, logger)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	quitPoll := make(chan struct{})
-	polling := false
+	return runStorePollers(ctx, pollers, logger)
+}
+
+// Runs the pollers until ctx is cancelled, or until a poller fails. Only pollers with a zero interval
+// fail, and the first failure stops the others. It is returned even if ctx was cancelled meanwhile
+func runStorePollers(ctx context.Context, pollers []storePoller, logger log.Interface) error {
+	pollCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	if staticGtfsUrl != "" {
-		if staticPollIntervalMins != 0 {
-			polling = true
-			err = storeStaticGtfs(logger, staticGtfsUrl, db, sqliteDbPath)
-			if err != nil {
-				return &quitPoll, err
+	var running sync.WaitGroup
+	var failure sync.Once
+	var firstErr error
+	for _, poller := range pollers {
+		running.Add(1)
+		go func(poller storePoller) {
+			defer running.Done()
+			if err := poller.run(pollCtx); err != nil {
+				failure.Do(func() {
+					poller.logger.Error("Polling %s failed, stopping: %s", poller.name, err.Error())
+					firstErr = err
+					cancel()
+				})
 			}
-			staticTicker := time.NewTicker(time.Duration(staticPollIntervalMins) * time.Minute)
-			go func() {
-				for {
-					select {
-					case <-staticTicker.C:
-						err = storeStaticGtfs(logger, staticGtfsUrl, db, sqliteDbPath)
-						if err != nil {
-							staticTicker.Stop()
-							return
-						}
-					case <-quitPoll:
-						staticTicker.Stop()
-						return
-					}
-				}
-			}()
-		} else {
-			err = storeStaticGtfs(logger, staticGtfsUrl, db, sqliteDbPath)
+		}(poller)
+	}
+	running.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if ctx.Err() != nil {
+		logger.Info("Stopped storing feeds")
+	}
+	return nil
+}
+
+func createStorePollers(config *StoreConfig, db *gorm.DB, logger log.Interface) ([]storePoller, error) {
+	// SQLite allows one writer at a time, so pollers take turns writing. Fetches happen at the same time
+	writeMutex := &sync.Mutex{}
+	write := func(writeToDb func() error) error {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		return writeToDb()
+	}
+	record := func(entry *model.FetchLog) error {
+		return write(func() error { return db.Create(entry).Error })
+	}
+
+	repository := NewGormRepository(db)
+	var archive *rawArchive
+	if config.ArchiveRaw || config.ArchiveDir != "" {
+		archive = newRawArchive(db, config.ArchiveDir)
+	}
+
+	var pollers []storePoller
+	for i := range config.Feeds {
+		feed := &config.Feeds[i]
+		errorMode, err := feed.parseErrorMode()
+		if err != nil {
+			return nil, err
+		}
+		feedLogger := newFeedLogger(logger, feed)
+		parseOptions := StaticParseOptions{ErrorMode: errorMode, Encoding: feed.Encoding, OnError: func(fileName string, err *csv_parse.ParseError) {
+			feedLogger.Warning("Ignoring unparseable data in %s: %s", fileName, err.Error())
+		}}
+		credentials, err := feed.credentials()
+		if err != nil {
+			return nil, fmt.Errorf("feed %s: %w", feed.name(), err)
+		}
+		// Static feeds can be large, so only realtime fetches time out
+		staticFetcher := newFeedFetcher(0, credentials)
+		staticFetcher.retry = feed.retryPolicy()
+		rtFetcher := newFeedFetcher(rtFetchTimeout, credentials)
+		rtFetcher.retry = feed.retryPolicy()
+		addPoller := func(entityType string, url string, interval time.Duration, poll func(ctx context.Context, entry *model.FetchLog) error) {
+			pollers = append(pollers, storePoller{
+				name:       strings.ReplaceAll(entityType, "_", " "),
+				namespace:  feed.Namespace,
+				url:        url,
+				entityType: entityType,
+				logger:     feedLogger,
+				interval:   interval,
+				poll:       poll,
+				record:     record,
+			})
+		}
+
+		if feed.StaticUrl != "" {
+			state, err := getStaticFetchState(db, feed.Namespace, feed.StaticUrl)
 			if err != nil {
-				return &quitPoll, err
+				return nil, err
 			}
+			addPoller("static", feed.StaticUrl, feed.staticPollInterval(), func(ctx context.Context, entry *model.FetchLog) error {
+				return storeStaticGtfs(ctx, feedLogger, staticFetcher, feed.Namespace, parseOptions, state, entry, func(staticFeed *model.GtfsStaticFeed) error {
+					return write(func() error { return writeStaticGtfsToDbIfNeeded(staticFeed, repository, feedLogger, config.DbPath) })
+				}, func(newState *model.StaticFetchState) error {
+					return write(func() error { return db.Clauses(clause.OnConflict{UpdateAll: true}).Create(newState).Error })
+				})
+			})
 		}
-	}
-
-	if vehiclePositionUrl != "" {
-		if rtPollIntervalSecs != 0 {
-			polling = true
-			err = storeRtGtfs(logger, vehiclePositionUrl, db)
+		rtUrls := []struct{ entityType, url string }{
+			{"vehicle_positions", feed.VehiclePositionsUrl},
+			{"trip_updates", feed.TripUpdatesUrl},
+			{"alerts", feed.AlertsUrl},
+		}
+		for _, rtUrl := range rtUrls {
+			if rtUrl.url == "" {
+				continue
+			}
+			entityType, url, rtType := rtUrl.entityType, rtUrl.url, rtEntityTypes[rtUrl.entityType]
+			tracker, err := newNamespaceUpdateTracker(repository, entityType, feed.Namespace)
 			if err != nil {
-				return &quitPoll, err
+				return nil, err
 			}
-			rtTicker := time.NewTicker(time.Duration(rtPollIntervalSecs) * time.Second)
-			go func() {
-				for {
-					select {
-					case <-rtTicker.C:
-						err = storeRtGtfs(logger, vehiclePositionUrl, db)
-						if err != nil {
-							rtTicker.Stop()
-							return
+			addPoller(entityType, url, feed.rtPollInterval(), func(ctx context.Context, entry *model.FetchLog) error {
+				return storeRtGtfs(ctx, feedLogger, rtFetcher, url, rtType.name, entry, func(protoBytes []byte) (uint64, error) {
+					if archive != nil {
+						if err := write(func() error { return archive.add(feed.Namespace, entityType, protoBytes) }); err != nil {
+							return 0, err
 						}
-					case <-quitPoll:
-						rtTicker.Stop()
-						return
 					}
-				}
-			}()
-		} else {
-			err = storeRtGtfs(logger, vehiclePositionUrl, db)
-			if err != nil {
-				return &quitPoll, err
-			}
+					return rtType.convertAndWrite(protoBytes, feed.Namespace, tracker, repository, write)
+				})
+			})
+		}
+	}
+	return pollers, nil
+}
+
+// How to store each type of GTFS-RT feed
+type rtEntityType struct {
+	name string // For logs, like "vehicle positions"
+	// Converts a message to entities of namespace, returning the message's timestamp, how many
+	// entities there are, and a function that writes them
+	convert func(protoBytes []byte, namespace string) (messageTimestamp uint64, count int, writeToRepository func(realtime RealtimeRepository) error, err error)
+}
+
+var rtEntityTypes = map[string]rtEntityType{
+	"vehicle_positions": {name: "vehicle positions", convert: func(protoBytes []byte, namespace string) (uint64, int, func(realtime RealtimeRepository) error, error) {
+		vehiclePositions, err := convertVehiclePositionProtoToModel(protoBytes)
+		if err != nil || len(vehiclePositions) == 0 {
+			return 0, 0, nil, err
+		}
+		for i := range vehiclePositions {
+			vehiclePositions[i].Namespace = namespace
+		}
+		return vehiclePositions[0].MessageTimestamp, len(vehiclePositions), func(realtime RealtimeRepository) error {
+			return realtime.WriteVehiclePositions(vehiclePositions)
+		}, nil
+	}},
+	"trip_updates": {name: "trip updates", convert: func(protoBytes []byte, namespace string) (uint64, int, func(realtime RealtimeRepository) error, error) {
+		tripUpdates, err := convertTripUpdateProtoToModel(protoBytes)
+		if err != nil || len(tripUpdates) == 0 {
+			return 0, 0, nil, err
+		}
+		for i := range tripUpdates {
+			tripUpdates[i].Namespace = namespace
+		}
+		return tripUpdates[0].MessageTimestamp, len(tripUpdates), func(realtime RealtimeRepository) error {
+			return realtime.WriteTripUpdates(tripUpdates)
+		}, nil
+	}},
+	"alerts": {name: "alerts", convert: func(protoBytes []byte, namespace string) (uint64, int, func(realtime RealtimeRepository) error, error) {
+		alerts, err := convertAlertProtoToModel(protoBytes)
+		if err != nil || len(alerts) == 0 {
+			return 0, 0, nil, err
+		}
+		for i := range alerts {
+			alerts[i].Namespace = namespace
+		}
+		return alerts[0].MessageTimestamp, len(alerts), func(realtime RealtimeRepository) error {
+			return realtime.WriteAlerts(alerts)
+		}, nil
+	}},
+}
+
+// Converts a message and writes its entities to realtime inside write, returning how many it wrote.
+// Returns errDuplicateMessage when tracker has already seen a message as new, and a *feedParseError
+// when the message can't be converted
+func (rtType *rtEntityType) convertAndWrite(protoBytes []byte, namespace string, tracker *LatestRtUpdateTracker, realtime RealtimeRepository, write func(writeToDb func() error) error) (uint64, error) {
+	messageTimestamp, count, writeToRepository, err := rtType.convert(protoBytes, namespace)
+	if err != nil {
+		return 0, &feedParseError{err}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	if !tracker.ShouldProcessMessage(messageTimestamp) {
+		return 0, errDuplicateMessage
+	}
+	return uint64(count), write(func() error { return writeToRepository(realtime) })
+}
+
+// Polls once when the interval is zero, returning the error. Otherwise polls every interval until ctx
+// is cancelled. Failures are logged with how many polls in a row have failed, and a poll that panics
+// is treated as a failure, so the poller carries on at the next interval
+func (poller *storePoller) run(ctx context.Context) error {
+	if poller.interval == 0 {
+		return poller.pollAndRecord(ctx, 0)
+	}
+
+	ticker := time.NewTicker(poller.interval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		err := poller.pollAndRecord(ctx, failures)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			failures++
+			poller.logger.Error("Polling %s failed (%d in a row), retrying in %s: %s", poller.name, failures, poller.interval, err.Error())
+		} else if failures > 0 {
+			poller.logger.Info("Polling %s succeeded after %d failures", poller.name, failures)
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
 		}
 	}
+}
+
+// Polls once and records it in the fetch log, after failures failed polls in a row. A poll abandoned
+// because ctx was cancelled isn't recorded or returned, since it isn't an outage of the feed
+func (poller *storePoller) pollAndRecord(ctx context.Context, failures int) error {
+	entry := model.FetchLog{Namespace: poller.namespace, Url: poller.url, EntityType: poller.entityType, FetchTime: time.Now()}
+	err := poller.pollOnce(ctx, &entry)
+	if ctx.Err() != nil {
+		return nil
+	}
 
-	if polling {
-		for {
-			time.Sleep(50 * time.Millisecond)
+	entry.DurationMs = time.Since(entry.FetchTime).Milliseconds()
+	if err != nil {
+		entry.Outcome = getFetchOutcome(err)
+		entry.Error = err.Error()
+		entry.ConsecutiveFailures = failures + 1
+	}
+	if recordErr := poller.record(&entry); recordErr != nil {
+		poller.logger.Error("Couldn't record the fetch of %s in the fetch log: %s", poller.name, recordErr.Error())
+	}
+	return err
+}
+
+func (poller *storePoller) pollOnce(ctx context.Context, entry *model.FetchLog) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic while polling %s: %v\n%s", poller.name, recovered, debug.Stack())
 		}
+	}()
+	return poller.poll(ctx, entry)
+}
+
+func getFetchOutcome(err error) model.FetchOutcome {
+	var httpErr *httpFetchError
+	var parseErr *feedParseError
+	switch {
+	case errors.As(err, &httpErr):
+		return model.FetchHttpError
+	case errors.As(err, &parseErr):
+		return model.FetchParseError
+	default:
+		return model.FetchOtherError
 	}
+}
+
+// Prefixes log messages with the feed's namespace, so the logs of feeds polled together can be told apart
+type feedLogger struct {
+	log.Interface
+	prefix string
+}
+
+func newFeedLogger(logger log.Interface, feed *FeedConfig) log.Interface {
+	if feed.Namespace == "" {
+		return logger
+	}
+	return &feedLogger{Interface: logger, prefix: "[" + feed.Namespace + "] "}
+}
+
+func (logger *feedLogger) Debug(format string, v ...any) {
+	logger.Interface.Debug(logger.prefix+format, v...)
+}
+
+func (logger *feedLogger) Info(format string, v ...any) {
+	logger.Interface.Info(logger.prefix+format, v...)
+}
 
-	return &quitPoll, err
+func (logger *feedLogger) Warning(format string, v ...any) {
+	logger.Interface.Warning(logger.prefix+format, v...)
 }
 
-func storeStaticGtfs(logger log.Interface, staticGtfsUrl string, db *gorm.DB, sqliteDbPath string) error {
-	feed, err := parseStaticGtfsFromUrl(logger, staticGtfsUrl)
+func (logger *feedLogger) Error(format string, v ...any) {
+	logger.Interface.Error(logger.prefix+format, v...)
+}
+
+// Fetches and parses a static feed, then stores it with store, which returns errDuplicateMessage
+// when the version is already stored. The feed isn't downloaded when the server says it hasn't been
+// modified since state was saved, and isn't parsed when the zip is the same as last time. After the
+// feed is stored, or found to be unchanged, state is updated and saved with saveState
+func storeStaticGtfs(ctx context.Context, logger log.Interface, fetcher *feedFetcher, namespace string, parseOptions StaticParseOptions,
+	state *model.StaticFetchState, entry *model.FetchLog, store func(feed *model.GtfsStaticFeed) error, saveState func(state *model.StaticFetchState) error) error {
+	response, stats, err := fetcher.fetchIfModified(ctx, state.Url, cacheValidators{etag: state.ETag, lastModified: state.LastModified})
+	entry.HttpStatus, entry.Attempts = stats.statusCode, stats.attempts
 	if err != nil {
 		return err
 	}
+	if response.notModified {
+		logger.Info("Static GTFS at %s hasn't been modified, will not process", state.Url)
+		entry.Outcome = model.FetchDuplicate
+		return nil
+	}
+
+	zipHash := sha256.Sum256(response.body)
+	newState := *state
+	newState.ETag, newState.LastModified = response.validators.etag, response.validators.lastModified
+	newState.ZipHash = hex.EncodeToString(zipHash[:])
+	newState.UpdateTime = time.Now()
+	if newState.ZipHash == state.ZipHash {
+		logger.Info("Static GTFS at %s is the same as last time, will not process", state.Url)
+		entry.Outcome = model.FetchDuplicate
+		return updateStaticFetchState(state, &newState, saveState)
+	}
 
-	err = writeStaticGtfsToDbIfNeeded(feed, db, logger, sqliteDbPath)
+	logger.Info("Parsing static GTFS from url: %s", state.Url)
+	feed, err := parseStaticGtfsFromZipBytes(response.body, state.Url, parseOptions)
 	if err != nil {
+		return &feedParseError{err}
+	}
+	logger.Info("Done parsing static GTFS from url: %s", state.Url)
+
+	addNamespaceToFeed(feed, namespace)
+	err = store(feed)
+	if errors.Is(err, errDuplicateMessage) {
+		entry.Outcome = model.FetchDuplicate
+	} else if err != nil {
 		return err
 	}
+	return updateStaticFetchState(state, &newState, saveState)
+}
+
+// Names the feed's version like "namespace/version". Versions are shared by every feed in the
+// database, and two feeds could both publish a feed_version like "1.0"
+func addNamespaceToFeed(feed *model.GtfsStaticFeed, namespace string) {
+	if namespace == "" {
+		return
+	}
+	feed.FeedInfo.Namespace = namespace
+	feed.FeedInfo.Version = namespace + "/" + feed.FeedInfo.Version
+	addVersionToAllObjects(feed, feed.FeedInfo.Version)
+}
+
+func updateStaticFetchState(state *model.StaticFetchState, newState *model.StaticFetchState, saveState func(state *model.StaticFetchState) error) error {
+	if err := saveState(newState); err != nil {
+		return err
+	}
+	*state = *newState
 	return nil
 }
 
-func storeRtGtfs(logger log.Interface, vehiclePositionUrl string, db *gorm.DB) error {
-	updateTracker, err := NewUpdateTracker(db)
+// Returns the saved state of a static feed url, or an empty state if it has never been stored
+func getStaticFetchState(db *gorm.DB, namespace string, url string) (*model.StaticFetchState, error) {
+	state := model.StaticFetchState{Namespace: namespace, Url: url}
+	err := db.Where("namespace = ? AND url = ?", namespace, url).Limit(1).Find(&state).Error
+	return &state, err
+}
+
+// Fetches a GTFS-RT message and stores it with store, which returns how many entities it wrote, or
+// errDuplicateMessage when the message was already stored
+func storeRtGtfs(ctx context.Context, logger log.Interface, fetcher *feedFetcher, url string, entityType string, entry *model.FetchLog, store func(protoBytes []byte) (uint64, error)) error {
+	logger.Debug("Fetching GTFS-RT %s", entityType)
+	protoBytes, stats, err := fetcher.fetchWithStats(ctx, url)
+	entry.HttpStatus, entry.Attempts = stats.statusCode, stats.attempts
 	if err != nil {
 		return err
 	}
-	logger.Info("Fetching GTFS-RT data")
-	vehiclePositions, err := ParseRtGtfsFromUrl(vehiclePositionUrl)
+	count, err := store(protoBytes)
+	if errors.Is(err, errDuplicateMessage) {
+		logger.Info("No new GTFS-RT %s, will not process", entityType)
+		entry.Outcome = model.FetchDuplicate
+		return nil
+	}
 	if err != nil {
 		return err
 	}
-	logger.Info("Done fetching GTFS-RT data")
-	if len(vehiclePositions) > 0 && updateTracker.ShouldProcessMessage(vehiclePositions[0].MessageTimestamp) {
-		logger.Info("Writing GTFS-RT data to database")
-		WriteRealTimePositionUpdateToDatabase(vehiclePositions, db)
-		if err != nil {
-			return err
-		}
-		logger.Info("Done writing GTFS-RT data to database")
-	} else {
-		logger.Info("Duplicate RT message detected, will not process")
-	}
+	entry.Entities = count
+	logger.Info("Wrote %d GTFS-RT %s to database", count, entityType)
 	return nil
 }
 
-// TODO: wrap the gorm database objects in some interface for better testing
-// and ability to change library?
-func writeStaticGtfsToDbIfNeeded(feed *model.GtfsStaticFeed, db *gorm.DB, logger log.Interface, sqliteDbPath string) error {
-	feedExists, err := doesFeedAlreadyExist(feed, db)
+// Returns errDuplicateMessage when the feed's version is already stored
+func writeStaticGtfsToDbIfNeeded(feed *model.GtfsStaticFeed, feeds FeedRepository, logger log.Interface, dbPath string) error {
+	feedExists, err := feeds.HasFeedVersion(feed.FeedInfo.Version)
 	if err != nil {
 		return err
 	}
 
 	if feedExists {
-		logger.Warning("Feed already exists in database at %s", sqliteDbPath)
-	} else {
-		logger.Info("Writing GTFS static feed to database")
-		err = WriteStaticGtfsFeedToDatabase(feed, db)
-		logger.Info("Done writing GTFS static feed to database")
-
-		if err != nil {
-			return err
-		}
+		logger.Warning("Feed already exists in database at %s", dbPath)
+		return errDuplicateMessage
 	}
-	return nil
-}
 
-func parseStaticGtfsFromUrl(logger log.Interface, staticGtfsUrl string) (*model.GtfsStaticFeed, error) {
-	logger.Info("Parsing static GTFS from url: %s", staticGtfsUrl)
-	feed, err := ParseStaticGtfsFromUrl(staticGtfsUrl)
+	logger.Info("Writing GTFS static feed to database")
+	err = feeds.WriteFeed(feed)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	logger.Info("Done parsing static GTFS from url: %s", staticGtfsUrl)
-	return feed, nil
+	logger.Info("Done writing GTFS static feed to database")
+	return nil
 }
 
-func initializeSqliteDb(logger log.Interface, sqliteDbPath string, logLevel log.Level) (*gorm.DB, error) {
-	logger.Info("Initializing SQLite database at path: %s", sqliteDbPath)
-	db, err := InitializeSqliteDatabase(sqliteDbPath, logLevel)
+func initializeDb(logger log.Interface, dsn string, logLevel log.Level) (*gorm.DB, error) {
+	backend := getDatabaseBackend(dsn)
+	logger.Info("Initializing %s database at: %s", backend.name(), redactDsn(dsn))
+	db, err := initializeDatabase(backend, dsn, logLevel)
 	if err != nil {
 		return nil, err
 	}
-	logger.Info("Done initializing SQLite database at path: %s", sqliteDbPath)
+	logger.Info("Done initializing %s database at: %s", backend.name(), redactDsn(dsn))
 	return db, nil
 }
 
-func doesFeedAlreadyExist(feed *model.GtfsStaticFeed, db *gorm.DB) (bool, error) {
-	var count int64
-	result := db.Model(&model.FeedInfo{}).Where("version = ?", feed.FeedInfo.Version).Count(&count)
-	return count > 0, result.Error
+// Like initializeDb, for commands that only read what was stored. A SQLite file that doesn't exist,
+// like one at a mistyped path, is an error instead of a new, empty database
+func openExistingDb(logger log.Interface, dsn string, logLevel log.Level) (*gorm.DB, error) {
+	if _, ok := getDatabaseBackend(dsn).(sqliteBackend); ok {
+		if _, err := os.Stat(dsn); errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("no database found at %s", dsn)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return initializeDb(logger, dsn, logLevel)
 }
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/samc1213/gtfs-analyze/csv_parse"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultStaticPollInterval = 60 * time.Minute
+const defaultRtPollInterval = 30 * time.Second
+const defaultFetchAttempts = 3
+const defaultInitialBackoff = time.Second
+const defaultMaxBackoff = 30 * time.Second
+
+// The feeds for the store command to poll, from a YAML or TOML file
+type StoreConfig struct {
+	DbPath string `yaml:"db_path" toml:"db_path"`
+	// Keeps every GTFS-RT message exactly as it was fetched, for the replay command. The payloads are
+	// stored in ArchiveDir if it is set, and in the database otherwise
+	ArchiveRaw bool         `yaml:"archive_raw" toml:"archive_raw"`
+	ArchiveDir string       `yaml:"archive_dir" toml:"archive_dir"`
+	Feeds      []FeedConfig `yaml:"feeds" toml:"feeds"`
+}
+
+type FeedConfig struct {
+	// Identifies the feed's data in the database. Required when there is more than one feed
+	Namespace           string `yaml:"namespace" toml:"namespace"`
+	StaticUrl           string `yaml:"static_url" toml:"static_url"`
+	VehiclePositionsUrl string `yaml:"vehicle_positions_url" toml:"vehicle_positions_url"`
+	TripUpdatesUrl      string `yaml:"trip_updates_url" toml:"trip_updates_url"`
+	AlertsUrl           string `yaml:"alerts_url" toml:"alerts_url"`
+	// Durations like "60m". A zero interval fetches once instead of polling
+	StaticPollInterval *time.Duration `yaml:"static_poll_interval" toml:"static_poll_interval"`
+	RtPollInterval     *time.Duration `yaml:"rt_poll_interval" toml:"rt_poll_interval"`
+	// Sent with every request, such as an API key. Values can be "env:NAME" or "file:PATH" to keep
+	// secrets out of the config
+	Headers     map[string]string `yaml:"headers" toml:"headers"`
+	QueryParams map[string]string `yaml:"query_params" toml:"query_params"`
+	Auth        AuthConfig        `yaml:"auth" toml:"auth"`
+	// "strict", "skip-row" or "default-field", like --on-parse-error
+	OnParseError string      `yaml:"on_parse_error" toml:"on_parse_error"`
+	Encoding     string      `yaml:"encoding" toml:"encoding"`
+	Retry        RetryConfig `yaml:"retry" toml:"retry"`
+}
+
+// How a failed fetch is retried before the poll counts as failed. Fields that aren't set use the defaults
+type RetryConfig struct {
+	// Including the first request. 1 turns off retries
+	MaxAttempts    int            `yaml:"max_attempts" toml:"max_attempts"`
+	InitialBackoff *time.Duration `yaml:"initial_backoff" toml:"initial_backoff"`
+	MaxBackoff     *time.Duration `yaml:"max_backoff" toml:"max_backoff"`
+}
+
+// Reads a store config, choosing YAML or TOML by the file's extension
+func LoadStoreConfig(path string) (*StoreConfig, error) {
+	var config StoreConfig
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		decoder := yaml.NewDecoder(file)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&config); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		metadata, err := toml.DecodeFile(path, &config)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("%s: unknown field %s", path, undecoded[0].String())
+		}
+	default:
+		return nil, fmt.Errorf("%s: config must be a .yaml, .yml or .toml file", path)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &config, nil
+}
+
+func (config *StoreConfig) validate() error {
+	if len(config.Feeds) == 0 {
+		return errors.New("no feeds are configured")
+	}
+	namespaces := make(map[string]struct{}, len(config.Feeds))
+	for i, feed := range config.Feeds {
+		if feed.Namespace == "" && len(config.Feeds) > 1 {
+			return fmt.Errorf("feed %d needs a namespace, since there is more than one feed", i+1)
+		}
+		if _, ok := namespaces[feed.Namespace]; ok {
+			return fmt.Errorf("duplicate namespace %s", feed.Namespace)
+		}
+		namespaces[feed.Namespace] = struct{}{}
+
+		if feed.StaticUrl == "" && feed.VehiclePositionsUrl == "" && feed.TripUpdatesUrl == "" && feed.AlertsUrl == "" {
+			return fmt.Errorf("feed %s has no urls", feed.name())
+		}
+		if _, err := feed.parseErrorMode(); err != nil {
+			return fmt.Errorf("feed %s: on_parse_error %w", feed.name(), err)
+		}
+		if err := feed.Auth.validate(); err != nil {
+			return fmt.Errorf("feed %s: %w", feed.name(), err)
+		}
+		if feed.Retry.MaxAttempts < 0 {
+			return fmt.Errorf("feed %s: retry max_attempts can't be negative", feed.name())
+		}
+		if policy := feed.retryPolicy(); policy.maxBackoff < policy.initialBackoff {
+			return fmt.Errorf("feed %s: retry max_backoff can't be less than initial_backoff", feed.name())
+		}
+	}
+	return nil
+}
+
+// The namespace, for logs. The feed without a namespace is called "default"
+func (feed *FeedConfig) name() string {
+	if feed.Namespace == "" {
+		return "default"
+	}
+	return feed.Namespace
+}
+
+func (feed *FeedConfig) parseErrorMode() (csv_parse.ErrorMode, error) {
+	errorMode := csv_parse.Strict
+	if feed.OnParseError == "" {
+		return errorMode, nil
+	}
+	err := errorMode.Set(feed.OnParseError)
+	return errorMode, err
+}
+
+func (feed *FeedConfig) staticPollInterval() time.Duration {
+	if feed.StaticPollInterval == nil {
+		return defaultStaticPollInterval
+	}
+	return *feed.StaticPollInterval
+}
+
+func (feed *FeedConfig) rtPollInterval() time.Duration {
+	if feed.RtPollInterval == nil {
+		return defaultRtPollInterval
+	}
+	return *feed.RtPollInterval
+}
+
+func (feed *FeedConfig) retryPolicy() retryPolicy {
+	policy := retryPolicy{maxAttempts: defaultFetchAttempts, initialBackoff: defaultInitialBackoff, maxBackoff: defaultMaxBackoff}
+	if feed.Retry.MaxAttempts != 0 {
+		policy.maxAttempts = feed.Retry.MaxAttempts
+	}
+	if feed.Retry.InitialBackoff != nil {
+		policy.initialBackoff = *feed.Retry.InitialBackoff
+	}
+	if feed.Retry.MaxBackoff != nil {
+		policy.maxBackoff = *feed.Retry.MaxBackoff
+	}
+	return policy
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeStoreConfig(t *testing.T, name string, contents string) string {
+	configPath := path.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(configPath, []byte(contents), 0644))
+	return configPath
+}
+
+func TestLoadStoreConfig(t *testing.T) {
+	yamlPath := writeStoreConfig(t, "feeds.yaml", `
+db_path: gtfs.db
+feeds:
+  - namespace: rtd
+    static_url: https://www.rtd-denver.com/files/gtfs/google_transit.zip
+    vehicle_positions_url: https://www.rtd-denver.com/files/gtfs-rt/VehiclePosition.pb
+    rt_poll_interval: 15s
+    on_parse_error: skip-row
+    retry:
+      max_attempts: 5
+      initial_backoff: 2s
+  - namespace: mbta
+    trip_updates_url: https://cdn.mbta.com/realtime/TripUpdates.pb
+    alerts_url: https://cdn.mbta.com/realtime/Alerts.pb
+    headers:
+      x-api-key: env:MBTA_API_KEY
+    query_params:
+      format: pb
+    auth:
+      type: bearer
+      token: file:/run/secrets/mbta_token
+`)
+	tomlPath := writeStoreConfig(t, "feeds.toml", `
+db_path = "gtfs.db"
+
+[[feeds]]
+namespace = "rtd"
+static_url = "https://www.rtd-denver.com/files/gtfs/google_transit.zip"
+vehicle_positions_url = "https://www.rtd-denver.com/files/gtfs-rt/VehiclePosition.pb"
+rt_poll_interval = "15s"
+on_parse_error = "skip-row"
+retry = { max_attempts = 5, initial_backoff = "2s" }
+
+[[feeds]]
+namespace = "mbta"
+trip_updates_url = "https://cdn.mbta.com/realtime/TripUpdates.pb"
+alerts_url = "https://cdn.mbta.com/realtime/Alerts.pb"
+headers = { x-api-key = "env:MBTA_API_KEY" }
+query_params = { format = "pb" }
+auth = { type = "bearer", token = "file:/run/secrets/mbta_token" }
+`)
+
+	for _, configPath := range []string{yamlPath, tomlPath} {
+		config, err := LoadStoreConfig(configPath)
+		assert.NoError(t, err)
+		assert.Equal(t, "gtfs.db", config.DbPath)
+		assert.Equal(t, 2, len(config.Feeds))
+		assert.Equal(t, "rtd", config.Feeds[0].Namespace)
+		assert.Equal(t, 15*time.Second, config.Feeds[0].rtPollInterval())
+		// Intervals that aren't set use the defaults
+		assert.Equal(t, 60*time.Minute, config.Feeds[0].staticPollInterval())
+		assert.Equal(t, 30*time.Second, config.Feeds[1].rtPollInterval())
+		// Secrets are only looked up when storing starts
+		assert.Equal(t, map[string]string{"x-api-key": "env:MBTA_API_KEY"}, config.Feeds[1].Headers)
+		assert.Equal(t, map[string]string{"format": "pb"}, config.Feeds[1].QueryParams)
+		assert.Equal(t, AuthConfig{Type: "bearer", Token: "file:/run/secrets/mbta_token"}, config.Feeds[1].Auth)
+		assert.Equal(t, "https://cdn.mbta.com/realtime/Alerts.pb", config.Feeds[1].AlertsUrl)
+		assert.Equal(t, retryPolicy{maxAttempts: 5, initialBackoff: 2 * time.Second, maxBackoff: 30 * time.Second}, config.Feeds[0].retryPolicy())
+		assert.Equal(t, retryPolicy{maxAttempts: 3, initialBackoff: time.Second, maxBackoff: 30 * time.Second}, config.Feeds[1].retryPolicy())
+	}
+}
+
+func TestLoadInvalidStoreConfig(t *testing.T) {
+	for contents, expectedError := range map[string]string{
+		"feeds:\n  - static_url: a\n  - static_url: b":                                     "feed 1 needs a namespace, since there is more than one feed",
+		"feeds:\n  - namespace: a\n    static_url: a\n  - namespace: a\n    static_url: b": "duplicate namespace a",
+		"feeds:\n  - namespace: a":                                                         "feed a has no urls",
+		"feeds:\n  - static_url: a\n    on_parse_error: ignore":                            `feed default: on_parse_error must be one of "strict", "skip-row" or "default-field"`,
+		"feeds:\n  - static_url: a\n    retry:\n      max_backoff: 100ms":                  "feed default: retry max_backoff can't be less than initial_backoff",
+		"feeds:\n  - static_url: a\n    auth:\n      type: basic":                          "feed default: auth username is required for basic auth",
+		"db_path: gtfs.db": "no feeds are configured",
+	} {
+		configPath := writeStoreConfig(t, "feeds.yml", contents)
+		_, err := LoadStoreConfig(configPath)
+		assert.EqualError(t, err, configPath+": "+expectedError)
+	}
+
+	// Misspelled fields are errors rather than ignored
+	_, err := LoadStoreConfig(writeStoreConfig(t, "feeds.yaml", "feeds:\n  - namespace: a\n    static_ur: a"))
+	assert.ErrorContains(t, err, "field static_ur not found")
+	_, err = LoadStoreConfig(writeStoreConfig(t, "feeds.toml", "[[feeds]]\nnamespace = \"a\"\nstatic_ur = \"a\""))
+	assert.ErrorContains(t, err, "unknown field feeds.static_ur")
+	_, err = LoadStoreConfig(writeStoreConfig(t, "feeds.json", "{}"))
+	assert.ErrorContains(t, err, "config must be a .yaml, .yml or .toml file")
+}
@@ -0,0 +1,297 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestStoreFeeds(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getValidGtfsFiles()), StaticParseOptions{})
+	assert.NoError(t, err)
+	feed.FeedInfo = model.FeedInfo{PublisherName: "RTD", PublisherUrl: "https://www.rtd-denver.com", Language: "en", Version: "v1"}
+	var staticZip bytes.Buffer
+	assert.NoError(t, WriteStaticGtfsToZip(feed, &staticZip))
+	vehiclePositions, err := os.ReadFile(path.Join(getTestFilesPath(), "VehiclePosition_RTD_2023_05_23.pb"))
+	assert.NoError(t, err)
+	tripUpdatesAndAlerts, err := proto.Marshal(getTripUpdateAndAlertMessage())
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/other/static.zip" && r.Header.Get("x-api-key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/rtd/static.zip", "/other/static.zip":
+			w.Write(staticZip.Bytes())
+		case "/rtd/VehiclePosition.pb":
+			w.Write(vehiclePositions)
+		case "/other/realtime.pb":
+			w.Write(tripUpdatesAndAlerts)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	noPolling := time.Duration(0)
+	config := StoreConfig{DbPath: path.Join(t.TempDir(), "gtfs.db"), Feeds: []FeedConfig{
+		{Namespace: "rtd", StaticUrl: server.URL + "/rtd/static.zip", VehiclePositionsUrl: server.URL + "/rtd/VehiclePosition.pb"},
+		{
+			Namespace:      "other",
+			StaticUrl:      server.URL + "/other/static.zip",
+			TripUpdatesUrl: server.URL + "/other/realtime.pb",
+			AlertsUrl:      server.URL + "/other/realtime.pb",
+			Headers:        map[string]string{"x-api-key": "secret"},
+		},
+	}}
+	for i := range config.Feeds {
+		config.Feeds[i].StaticPollInterval = &noPolling
+		config.Feeds[i].RtPollInterval = &noPolling
+	}
+	assert.NoError(t, StoreFeeds(context.Background(), &config, log.Silent))
+	// Storing the same data again changes nothing
+	assert.NoError(t, StoreFeeds(context.Background(), &config, log.Silent))
+
+	db, err := InitializeSqliteDatabase(config.DbPath, log.Silent)
+	assert.NoError(t, err)
+	var feedInfos []model.FeedInfo
+	assert.NoError(t, db.Order("namespace").Find(&feedInfos).Error)
+	assert.Equal(t, 2, len(feedInfos))
+	// Both feeds publish the same files, so their versions are kept apart by namespace
+	assert.Equal(t, "other", feedInfos[0].Namespace)
+	assert.Equal(t, "other/"+feed.FeedInfo.Version, feedInfos[0].Version)
+	assert.Equal(t, "rtd/"+feed.FeedInfo.Version, feedInfos[1].Version)
+	storedFeed, err := GetFeedByVersion("rtd/"+feed.FeedInfo.Version, db)
+	assert.NoError(t, err)
+	assert.Equal(t, len(feed.StopTime), len(storedFeed.StopTime))
+
+	var count int64
+	assert.NoError(t, db.Model(&model.VehiclePosition{}).Where("namespace = ?", "rtd").Count(&count).Error)
+	assert.EqualValues(t, 331, count)
+	var tripUpdates []model.TripUpdate
+	assert.NoError(t, db.Find(&tripUpdates).Error)
+	assert.Equal(t, 1, len(tripUpdates))
+	assert.Equal(t, "other", tripUpdates[0].Namespace)
+	assert.Equal(t, model.SkippedStop, tripUpdates[0].StopTimeUpdates[1].ScheduleRelationship)
+	var alerts []model.Alert
+	assert.NoError(t, db.Find(&alerts).Error)
+	assert.Equal(t, 1, len(alerts))
+	assert.Equal(t, "Desvío de la ruta 15", alerts[0].HeaderText["es"])
+
+	// A single fetch that fails is an error
+	config.Feeds[1].Headers = nil
+	assert.EqualError(t, StoreFeeds(context.Background(), &config, log.Silent), "Non-successful response from uri "+server.URL+"/other/static.zip: Unauthorized")
+
+	// Every fetch is logged. The first run stored everything, and the second found nothing new
+	var fetchLogs []model.FetchLog
+	assert.NoError(t, db.Where("namespace = ? AND entity_type = ?", "other", "static").Order("id").Find(&fetchLogs).Error)
+	assert.Equal(t, []model.FetchOutcome{model.FetchSucceeded, model.FetchDuplicate, model.FetchHttpError}, []model.FetchOutcome{
+		fetchLogs[0].Outcome, fetchLogs[1].Outcome, fetchLogs[2].Outcome,
+	})
+	assert.Equal(t, http.StatusUnauthorized, fetchLogs[2].HttpStatus)
+	// 401 isn't retried, since it won't fix itself
+	assert.Equal(t, 1, fetchLogs[2].Attempts)
+	// The third run stopped once the other feed's static fetch failed, so its rtd fetch may not have happened
+	assert.NoError(t, db.Where("namespace = ? AND entity_type = ?", "rtd", "vehicle_positions").Order("id").Find(&fetchLogs).Error)
+	assert.GreaterOrEqual(t, len(fetchLogs), 2)
+	assert.EqualValues(t, 331, fetchLogs[0].Entities)
+	assert.Equal(t, model.FetchDuplicate, fetchLogs[1].Outcome)
+
+	// The server doesn't send an ETag or Last-Modified, so the static feeds were downloaded again but
+	// not parsed, since the zips were the same
+	assert.NoError(t, db.Where("namespace = ? AND entity_type = ?", "rtd", "static").Order("id").Find(&fetchLogs).Error)
+	assert.Equal(t, http.StatusOK, fetchLogs[1].HttpStatus)
+	assert.Equal(t, model.FetchDuplicate, fetchLogs[1].Outcome)
+}
+
+func TestStoreFeedsStopsWhenCancelled(t *testing.T) {
+	vehiclePositions, err := os.ReadFile(path.Join(getTestFilesPath(), "VehiclePosition_RTD_2023_05_23.pb"))
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(vehiclePositions)
+		// Shut down during the second poll. Whether or not it is abandoned, the positions are stored
+		requests++
+		if requests == 2 {
+			cancel()
+		}
+	}))
+	defer server.Close()
+
+	pollInterval := time.Millisecond
+	config := StoreConfig{DbPath: path.Join(t.TempDir(), "gtfs.db"), Feeds: []FeedConfig{
+		{VehiclePositionsUrl: server.URL, RtPollInterval: &pollInterval},
+	}}
+	assert.NoError(t, StoreFeeds(ctx, &config, log.Silent))
+
+	db, err := InitializeSqliteDatabase(config.DbPath, log.Silent)
+	assert.NoError(t, err)
+	var count int64
+	assert.NoError(t, db.Model(&model.VehiclePosition{}).Count(&count).Error)
+	assert.EqualValues(t, 331, count)
+}
+
+func TestStorePollerKeepsPollingAfterFailures(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	polls := 0
+	var entries []model.FetchLog
+	poller := storePoller{
+		name:       "alerts",
+		entityType: "alerts",
+		logger:     log.New(log.Silent),
+		interval:   time.Millisecond,
+		poll: func(ctx context.Context, entry *model.FetchLog) error {
+			polls++
+			switch polls {
+			case 1:
+				panic("unexpected message")
+			case 2:
+				entry.HttpStatus = http.StatusServiceUnavailable
+				return &httpFetchError{url: "https://example.com/alerts.pb", statusCode: http.StatusServiceUnavailable}
+			case 3:
+				entry.Entities = 2
+				return nil
+			default:
+				// Not recorded, since the poll was abandoned
+				cancel()
+				return errors.New("context canceled")
+			}
+		},
+		record: func(entry *model.FetchLog) error {
+			entries = append(entries, *entry)
+			return nil
+		},
+	}
+	assert.NoError(t, poller.run(ctx))
+	assert.Equal(t, 4, polls)
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, model.FetchOtherError, entries[0].Outcome)
+	assert.Equal(t, 1, entries[0].ConsecutiveFailures)
+	assert.Equal(t, model.FetchHttpError, entries[1].Outcome)
+	assert.Equal(t, 2, entries[1].ConsecutiveFailures)
+	assert.Equal(t, "Non-successful response from uri https://example.com/alerts.pb: Service Unavailable", entries[1].Error)
+	assert.Equal(t, model.FetchSucceeded, entries[2].Outcome)
+	assert.Equal(t, 0, entries[2].ConsecutiveFailures)
+	assert.EqualValues(t, 2, entries[2].Entities)
+
+	// A single poll returns its failure, including a panic
+	polls = 0
+	poller.interval = 0
+	assert.ErrorContains(t, poller.run(context.Background()), "panic while polling alerts: unexpected message")
+}
+
+func TestFailedSinglePollStopsOtherPollers(t *testing.T) {
+	record := func(entry *model.FetchLog) error { return nil }
+	var realtimePolls int32
+	pollers := []storePoller{
+		{
+			name:     "static",
+			logger:   log.New(log.Silent),
+			interval: 0,
+			poll: func(ctx context.Context, entry *model.FetchLog) error {
+				// Fail once the realtime poller is running
+				for atomic.LoadInt32(&realtimePolls) == 0 {
+					time.Sleep(time.Millisecond)
+				}
+				return errors.New("static feed not found")
+			},
+			record: record,
+		},
+		{
+			name:     "vehicle positions",
+			logger:   log.New(log.Silent),
+			interval: time.Millisecond,
+			poll: func(ctx context.Context, entry *model.FetchLog) error {
+				atomic.AddInt32(&realtimePolls, 1)
+				return nil
+			},
+			record: record,
+		},
+	}
+
+	done := make(chan error)
+	go func() { done <- runStorePollers(context.Background(), pollers, log.New(log.Silent)) }()
+	select {
+	case err := <-done:
+		assert.EqualError(t, err, "static feed not found")
+	case <-time.After(10 * time.Second):
+		t.Fatal("the realtime poller kept running after the static poll failed")
+	}
+
+	// The failure is returned even if the store is shut down right after it
+	ctx, cancel := context.WithCancel(context.Background())
+	pollers[0].record = func(entry *model.FetchLog) error {
+		cancel()
+		return nil
+	}
+	assert.EqualError(t, runStorePollers(ctx, pollers, log.New(log.Silent)), "static feed not found")
+}
+
+func TestStoreStaticFeedOnlyWhenModified(t *testing.T) {
+	var staticZips [2]bytes.Buffer
+	files := getValidGtfsFiles()
+	for i := range staticZips {
+		feed, err := parseStaticGtfsFromFiles(createGtfsFiles(files), StaticParseOptions{})
+		assert.NoError(t, err)
+		assert.NoError(t, WriteStaticGtfsToZip(feed, &staticZips[i]))
+		files["stops.txt"] += "\nstop3,Colfax and Broadway,39.74,-104.99"
+	}
+
+	version := 0
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf(`"v%d"`, version)
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Write(staticZips[version].Bytes())
+	}))
+	defer server.Close()
+
+	noPolling := time.Duration(0)
+	config := StoreConfig{DbPath: path.Join(t.TempDir(), "gtfs.db"), Feeds: []FeedConfig{{StaticUrl: server.URL, StaticPollInterval: &noPolling}}}
+	assert.NoError(t, StoreFeeds(context.Background(), &config, log.Silent))
+	assert.NoError(t, StoreFeeds(context.Background(), &config, log.Silent))
+	assert.Equal(t, 1, downloads)
+	version = 1
+	assert.NoError(t, StoreFeeds(context.Background(), &config, log.Silent))
+	assert.Equal(t, 2, downloads)
+
+	db, err := InitializeSqliteDatabase(config.DbPath, log.Silent)
+	assert.NoError(t, err)
+	var feedInfos []model.FeedInfo
+	assert.NoError(t, db.Find(&feedInfos).Error)
+	assert.Equal(t, 2, len(feedInfos))
+	var fetchLogs []model.FetchLog
+	assert.NoError(t, db.Order("id").Find(&fetchLogs).Error)
+	assert.Equal(t, []int{http.StatusOK, http.StatusNotModified, http.StatusOK}, []int{fetchLogs[0].HttpStatus, fetchLogs[1].HttpStatus, fetchLogs[2].HttpStatus})
+	assert.Equal(t, model.FetchDuplicate, fetchLogs[1].Outcome)
+	assert.Equal(t, model.FetchSucceeded, fetchLogs[2].Outcome)
+
+	var state model.StaticFetchState
+	assert.NoError(t, db.First(&state).Error)
+	assert.Equal(t, `"v1"`, state.ETag)
+	zipHash := sha256.Sum256(staticZips[1].Bytes())
+	assert.Equal(t, hex.EncodeToString(zipHash[:]), state.ZipHash)
+}
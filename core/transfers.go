@@ -0,0 +1,214 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+)
+
+// How often the connections of one transfer from transfers.txt were made
+type TransferConnectionSummary struct {
+	FromStopId      string             `json:"from_stop_id"`
+	ToStopId        string             `json:"to_stop_id"`
+	FromRouteId     string             `json:"from_route_id,omitempty"`
+	ToRouteId       string             `json:"to_route_id,omitempty"`
+	FromTripId      string             `json:"from_trip_id,omitempty"`
+	ToTripId        string             `json:"to_trip_id,omitempty"`
+	TransferType    model.TransferType `json:"transfer_type"`
+	MinTransferTime int32              `json:"min_transfer_time"` // In seconds
+	Scheduled       int                `json:"scheduled"`
+	Made            int                `json:"made"`
+	Missed          int                `json:"missed"`
+	Unobserved      int                `json:"unobserved"` // Either vehicle was not tracked at the transfer stop
+}
+
+type TransferSummary struct {
+	StartTime time.Time                   `json:"start_time"`
+	EndTime   time.Time                   `json:"end_time"`
+	Transfers []TransferConnectionSummary `json:"transfers"`
+}
+
+// The share of observed connections that were made, or 0 if none were observed
+func (summary *TransferConnectionSummary) MadeRatio() float64 {
+	if summary.Made+summary.Missed == 0 {
+		return 0
+	}
+	return float64(summary.Made) / float64(summary.Made+summary.Missed)
+}
+
+// Measures the timed transfers made and missed, from the feed and vehicle positions stored under a namespace
+func CalculateTransfersForTimeRange(dbPath string, namespace string, startTime time.Time, endTime time.Time, logLevel log.Level) (*TransferSummary, error) {
+	logger := log.New(logLevel)
+	logger.Debug("Calculating transfers for time range %s to %s", startTime.String(), endTime.String())
+
+	db, err := openExistingDb(logger, dbPath, logLevel)
+	if err != nil {
+		return nil, err
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlDb.Close()
+	repository := NewGormRepository(db)
+	return CalculateTransfers(repository, repository, namespace, startTime, endTime, logger)
+}
+
+// Like CalculateTransfersForTimeRange, with the feed and vehicle positions loaded from repositories
+func CalculateTransfers(feeds FeedRepository, realtime RealtimeRepository, namespace string, startTime time.Time, endTime time.Time, logger log.Interface) (*TransferSummary, error) {
+	vehiclePositions, err := realtime.GetVehiclePositions(namespace, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("Found %d VechilePosition updates", len(vehiclePositions))
+
+	year, month, day := startTime.Date()
+	feed, err := feeds.GetFeedOnDate(namespace, year, month, day)
+	if err != nil {
+		return nil, err
+	}
+
+	calculation, err := CreateOtpCalculation(feed)
+	if err != nil {
+		return nil, err
+	}
+
+	calculation.OnNewPositionData(vehiclePositions, logger)
+
+	return calculation.SummarizeTransfers(startTime, endTime, logger), nil
+}
+
+// Summarizes how often the transfers in transfers.txt between two routes or trips were made. For each
+// scheduled arrival of the from route at the from stop within the time range, the connection is the first
+// departure of the to route from the to stop, at least min_transfer_time later. The connection was made
+// if the observed departure was at least min_transfer_time after the observed arrival. Transfers that
+// are only between stops, or that are not possible or in-seat, are not summarized
+func (calculation *OtpCalculation) SummarizeTransfers(startTime time.Time, endTime time.Time, logger log.Interface) *TransferSummary {
+	calculation.Lock.Lock()
+	defer calculation.Lock.Unlock()
+
+	summary := TransferSummary{StartTime: startTime, EndTime: endTime, Transfers: []TransferConnectionSummary{}}
+	for _, transfer := range calculation.Feed.Transfer {
+		if !isConnectionTransfer(&transfer) {
+			continue
+		}
+		minTransferTime := time.Duration(transfer.MinTransferTime.Get(0)) * time.Second
+		entry := TransferConnectionSummary{
+			FromStopId:      transfer.FromStopId,
+			ToStopId:        transfer.ToStopId,
+			FromRouteId:     transfer.FromRouteId,
+			ToRouteId:       transfer.ToRouteId,
+			FromTripId:      transfer.FromTripId,
+			ToTripId:        transfer.ToTripId,
+			TransferType:    transfer.TransferType,
+			MinTransferTime: transfer.MinTransferTime.Get(0),
+		}
+
+		for _, tripIdToTrip := range calculation.TripsByDate {
+			var arrivals []tripStopTime
+			var departures []tripStopTime
+			for _, trip := range tripIdToTrip {
+				if doesTripMatchTransfer(trip, transfer.FromRouteId, transfer.FromTripId) {
+					if stopTime := findStopTime(trip, transfer.FromStopId); stopTime != nil && stopTime.StopTime.After(startTime) && stopTime.StopTime.Before(endTime) {
+						arrivals = append(arrivals, tripStopTime{tripId: trip.Id, stopTime: stopTime})
+					}
+				}
+				if doesTripMatchTransfer(trip, transfer.ToRouteId, transfer.ToTripId) {
+					if stopTime := findStopTime(trip, transfer.ToStopId); stopTime != nil {
+						departures = append(departures, tripStopTime{tripId: trip.Id, stopTime: stopTime})
+					}
+				}
+			}
+
+			for _, arrival := range arrivals {
+				var connection *InternalStopTime
+				for _, departure := range departures {
+					if departure.tripId == arrival.tripId || departure.stopTime.DepartureTime.Before(arrival.stopTime.StopTime.Add(minTransferTime)) {
+						continue
+					}
+					if connection == nil || departure.stopTime.DepartureTime.Before(connection.DepartureTime) {
+						connection = departure.stopTime
+					}
+				}
+				if connection == nil {
+					logger.Debug("No connection from trip %s at stop %s", arrival.tripId, transfer.FromStopId)
+					continue
+				}
+				entry.Scheduled++
+				if arrival.stopTime.ActualArrivalTime.IsZero() || connection.ActualDepartureTime.IsZero() {
+					entry.Unobserved++
+				} else if connection.ActualDepartureTime.Before(arrival.stopTime.ActualArrivalTime.Add(minTransferTime)) {
+					entry.Missed++
+				} else {
+					entry.Made++
+				}
+			}
+		}
+		summary.Transfers = append(summary.Transfers, entry)
+	}
+	return &summary
+}
+
+type tripStopTime struct {
+	tripId   string
+	stopTime *InternalStopTime
+}
+
+func isConnectionTransfer(transfer *model.Transfer) bool {
+	if transfer.TransferType != model.RecommendedTransfer && transfer.TransferType != model.TimedTransfer && transfer.TransferType != model.MinimumTimeTransfer {
+		return false
+	}
+	return transfer.FromStopId != "" && transfer.ToStopId != "" &&
+		(transfer.FromRouteId != "" || transfer.FromTripId != "") &&
+		(transfer.ToRouteId != "" || transfer.ToTripId != "")
+}
+
+// A trip id in a transfer is more specific than a route id, so it's used when both are set
+func doesTripMatchTransfer(trip *InternalTrip, routeId string, tripId string) bool {
+	if tripId != "" {
+		return trip.Id == tripId
+	}
+	return trip.RouteId == routeId
+}
+
+func findStopTime(trip *InternalTrip, stopId string) *InternalStopTime {
+	for stopIdx := range trip.StopTimes {
+		if trip.StopTimes[stopIdx].StopId == stopId {
+			return &trip.StopTimes[stopIdx]
+		}
+	}
+	return nil
+}
+
+func getTransferSideName(routeId string, tripId string) string {
+	if tripId != "" {
+		return "trip " + tripId
+	}
+	return "route " + routeId
+}
+
+func (summary *TransferSummary) PrettyPrint() string {
+	sort.SliceStable(summary.Transfers, func(i, j int) bool {
+		first, second := summary.Transfers[i], summary.Transfers[j]
+		if first.FromStopId != second.FromStopId {
+			return first.FromStopId < second.FromStopId
+		}
+		return getTransferSideName(first.FromRouteId, first.FromTripId) < getTransferSideName(second.FromRouteId, second.FromTripId)
+	})
+	builder := strings.Builder{}
+	writer := tabwriter.NewWriter(&builder, 0, 0, 0, ' ', tabwriter.Debug)
+	fmt.Fprintf(writer, "FromStop\tToStop\tFrom\tTo\tScheduled\tMade\tMissed\tUnobserved\tMade %%\n")
+	for _, transfer := range summary.Transfers {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%.2f\n", transfer.FromStopId, transfer.ToStopId,
+			getTransferSideName(transfer.FromRouteId, transfer.FromTripId), getTransferSideName(transfer.ToRouteId, transfer.ToTripId),
+			transfer.Scheduled, transfer.Made, transfer.Missed, transfer.Unobserved, transfer.MadeRatio()*100)
+	}
+	writer.Flush()
+	return builder.String()
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/infra"
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDepartureTimes(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getGtfsFilesWith(t, "transfers")), StaticParseOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(feed.Transfer))
+	calculation, err := CreateOtpCalculation(feed)
+	assert.NoError(t, err)
+	logger := log.New(log.Info)
+	tripDate := infra.Date{Year: 2023, Month: 6, Day: 5}
+	tripDateInLocation := time.Date(tripDate.Year, tripDate.Month, tripDate.Day, 0, 0, 0, 0, calculation.Location)
+
+	// The departure is the last time the vehicle was seen stopped
+	simulateStop(tripDateInLocation, 8*time.Hour+31*time.Minute, "trip1", "stop1", calculation, logger)
+	simulateStop(tripDateInLocation, 8*time.Hour+33*time.Minute, "trip1", "stop1", calculation, logger)
+	simulateInTransitToStop(tripDateInLocation, 8*time.Hour+36*time.Minute, "trip1", "stop2", calculation, logger)
+	stopOne := &calculation.TripsByDate[tripDate]["trip1"].StopTimes[0]
+	assert.Equal(t, tripDateInLocation.Add(8*time.Hour+31*time.Minute), stopOne.ActualArrivalTime)
+	assert.Equal(t, tripDateInLocation.Add(8*time.Hour+33*time.Minute), stopOne.ActualDepartureTime)
+	assert.Equal(t, tripDateInLocation.Add(8*time.Hour+46*time.Minute), calculation.TripsByDate[tripDate]["trip1"].StopTimes[1].DepartureTime)
+
+	// Without being seen stopped, the departure is the first time the vehicle was seen past the stop
+	simulateInTransitToStop(tripDateInLocation, 8*time.Hour+45*time.Minute, "tripA1", "stop2", calculation, logger)
+	simulateInTransitToStop(tripDateInLocation, 8*time.Hour+52*time.Minute, "tripA1", "stop3", calculation, logger)
+	simulateInTransitToStop(tripDateInLocation, 8*time.Hour+55*time.Minute, "tripA1", "stop3", calculation, logger)
+	stopTwo := &calculation.TripsByDate[tripDate]["tripA1"].StopTimes[0]
+	assert.Equal(t, tripDateInLocation.Add(8*time.Hour+52*time.Minute), stopTwo.ActualArrivalTime)
+	assert.Equal(t, tripDateInLocation.Add(8*time.Hour+52*time.Minute), stopTwo.ActualDepartureTime)
+}
+
+func TestSummarizeTransfers(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getGtfsFilesWith(t, "transfers")), StaticParseOptions{})
+	assert.NoError(t, err)
+	calculation, err := CreateOtpCalculation(feed)
+	assert.NoError(t, err)
+	logger := log.New(log.Info)
+	day := func(d int) time.Time { return time.Date(2023, 6, d, 0, 0, 0, 0, calculation.Location) }
+
+	// Made: tripA1 waits until 08:50 for trip1, which arrived at 08:47
+	simulateStop(day(5), 8*time.Hour+47*time.Minute, "trip1", "stop2", calculation, logger)
+	simulateStop(day(5), 8*time.Hour+50*time.Minute, "tripA1", "stop2", calculation, logger)
+	simulateInTransitToStop(day(5), 8*time.Hour+51*time.Minute, "tripA1", "stop3", calculation, logger)
+
+	// Missed: trip1 arrived at 08:52, after tripA1 left
+	simulateStop(day(6), 8*time.Hour+52*time.Minute, "trip1", "stop2", calculation, logger)
+	simulateStop(day(6), 8*time.Hour+50*time.Minute, "tripA1", "stop2", calculation, logger)
+	simulateInTransitToStop(day(6), 8*time.Hour+51*time.Minute, "tripA1", "stop3", calculation, logger)
+
+	// Unobserved: tripA1 wasn't tracked
+	simulateStop(day(7), 8*time.Hour+46*time.Minute, "trip1", "stop2", calculation, logger)
+
+	summary := calculation.SummarizeTransfers(day(5), day(8), logger)
+	// The second transfer is only between stops, so it isn't summarized
+	assert.Equal(t, []TransferConnectionSummary{{
+		FromStopId:      "stop2",
+		ToStopId:        "stop2",
+		FromRouteId:     "route15",
+		ToRouteId:       "routeA",
+		TransferType:    1,
+		MinTransferTime: 120,
+		Scheduled:       3,
+		Made:            1,
+		Missed:          1,
+		Unobserved:      1,
+	}}, summary.Transfers)
+	assert.Equal(t, 0.5, summary.Transfers[0].MadeRatio())
+	assert.Contains(t, summary.PrettyPrint(), "stop2   |stop2 |route route15|route routeA|3        |1   |1     |1         |50.00")
+
+	// A time range without trip1's arrivals has no connections
+	summary = calculation.SummarizeTransfers(day(5), day(5).Add(8*time.Hour), logger)
+	assert.Equal(t, 0, summary.Transfers[0].Scheduled)
+}
+
+func TestValidateTransfers(t *testing.T) {
+	files := getGtfsFilesWith(t, "transfers")
+	files["transfers.txt"] += "\nstop2,stop9,route15,,,,1,-5\n,,,,trip1,,4,\nstop1,stop2,,,,,7,"
+	report := ValidateStaticGtfsFiles(createGtfsFiles(files))
+	assert.ElementsMatch(t, []ValidationFinding{
+		{File: "transfers.txt", Line: 4, Severity: SeverityError, Code: ForeignKeyViolation, Message: "to_stop_id stop9 does not exist in stops.txt"},
+		{File: "transfers.txt", Line: 4, Severity: SeverityError, Code: InvalidRow, Message: "min_transfer_time must not be negative, found -5"},
+		{File: "transfers.txt", Line: 5, Severity: SeverityError, Code: MissingRequiredValue, Message: "from_trip_id and to_trip_id are required for transfer_type 4"},
+		{File: "transfers.txt", Line: 6, Severity: SeverityError, Code: InvalidEnumValue, Message: "transfer_type must be between 0 and 5, found 7"},
+	}, report.Findings)
+}
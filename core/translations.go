@@ -0,0 +1,95 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/samc1213/gtfs-analyze/model"
+)
+
+type recordTranslationKey struct {
+	tableName   string
+	fieldName   string
+	recordId    string
+	recordSubId string
+}
+
+type valueTranslationKey struct {
+	tableName  string
+	fieldName  string
+	fieldValue string
+}
+
+// Looks up names in one language from translations.txt. Names without a translation are returned as
+// they are in the feed
+type Translator struct {
+	Language     string
+	recordsByKey map[recordTranslationKey]string
+	valuesByKey  map[valueTranslationKey]string
+}
+
+// Creates a translator for a language like "fr" or "fr-CA". A translation in the exact language is
+// preferred, then one in the base language, so "fr-CA" falls back to "fr". When language is empty,
+// nothing is translated
+func NewTranslator(feed *model.GtfsStaticFeed, language string) *Translator {
+	translator := Translator{
+		Language:     language,
+		recordsByKey: make(map[recordTranslationKey]string),
+		valuesByKey:  make(map[valueTranslationKey]string),
+	}
+	if language == "" {
+		return &translator
+	}
+	baseLanguage, _, _ := strings.Cut(language, "-")
+	// Base language translations are added first, so exact ones overwrite them
+	for _, matchesLanguage := range []func(string) bool{
+		func(l string) bool { return strings.EqualFold(l, baseLanguage) && !strings.EqualFold(l, language) },
+		func(l string) bool { return strings.EqualFold(l, language) },
+	} {
+		for _, translation := range feed.Translation {
+			if !matchesLanguage(translation.Language) {
+				continue
+			}
+			if translation.RecordId != "" || translation.FieldValue == "" {
+				key := recordTranslationKey{translation.TableName, translation.FieldName, translation.RecordId, translation.RecordSubId}
+				translator.recordsByKey[key] = translation.Translation
+			} else {
+				key := valueTranslationKey{translation.TableName, translation.FieldName, translation.FieldValue}
+				translator.valuesByKey[key] = translation.Translation
+			}
+		}
+	}
+	return &translator
+}
+
+// Translates the value of a field of a record. A translation for the record itself is preferred over
+// one for every record with the same value. recordSubId is only used for stop_times.txt, where the
+// record id is the trip_id and the sub id is the stop_sequence
+func (translator *Translator) Translate(tableName string, fieldName string, recordId string, recordSubId string, value string) string {
+	if translation, ok := translator.recordsByKey[recordTranslationKey{tableName, fieldName, recordId, recordSubId}]; ok {
+		return translation
+	}
+	if translation, ok := translator.valuesByKey[valueTranslationKey{tableName, fieldName, value}]; ok {
+		return translation
+	}
+	return value
+}
+
+func (translator *Translator) StopName(stop *model.Stop) string {
+	return translator.Translate("stops", "stop_name", stop.Id, "", stop.Name)
+}
+
+// Uses the long name of the route, or the short name when there is no long name
+func (translator *Translator) RouteName(route *model.Route) string {
+	if route.LongName == "" {
+		return translator.Translate("routes", "route_short_name", route.Id, "", route.ShortName)
+	}
+	return translator.Translate("routes", "route_long_name", route.Id, "", route.LongName)
+}
+
+func (translator *Translator) TripHeadsign(trip *model.Trip) string {
+	return translator.Translate("trips", "trip_headsign", trip.Id, "", trip.Headsign)
+}
+
+func (translator *Translator) AgencyName(agency *model.Agency) string {
+	return translator.Translate("agency", "agency_name", agency.Id, "", agency.Name)
+}
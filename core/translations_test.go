@@ -0,0 +1,87 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samc1213/gtfs-analyze/log"
+	"github.com/samc1213/gtfs-analyze/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslator(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getGtfsFilesWith(t, "translations")), StaticParseOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(feed.Translation))
+	assert.Equal(t, []model.Attribution{
+		{Version: feed.FeedInfo.Version, Id: "attr1", OrganizationName: "Denver Regional Council", IsProducer: model.HasRole, Url: "https://drcog.org"},
+		{Version: feed.FeedInfo.Version, Id: "attr2", RouteId: "route15", OrganizationName: "Transdev", IsOperator: model.HasRole},
+	}, feed.Attribution)
+
+	spanish := NewTranslator(feed, "es")
+	assert.Equal(t, "Estación Union", spanish.StopName(&feed.Stop[0]))
+	// Stops without a translation keep their name
+	assert.Equal(t, "Civic Center", spanish.StopName(&feed.Stop[1]))
+	assert.Equal(t, "Colfax Este", spanish.RouteName(&feed.Route[0]))
+	// A translation of the record is preferred over a translation of the value
+	assert.Equal(t, "Centro de Denver", spanish.TripHeadsign(&feed.Trip[0]))
+	assert.Equal(t, "Centro", spanish.Translate("trips", "trip_headsign", "trip2", "", "Downtown"))
+
+	// Languages fall back to their base language, and are matched case-insensitively
+	assert.Equal(t, "Gare Union Station", NewTranslator(feed, "FR-ca").StopName(&feed.Stop[0]))
+	assert.Equal(t, "Gare Union", NewTranslator(feed, "fr-BE").StopName(&feed.Stop[0]))
+	assert.Equal(t, "Union Station", NewTranslator(feed, "").StopName(&feed.Stop[0]))
+	assert.Equal(t, "Union Station", NewTranslator(feed, "de").StopName(&feed.Stop[0]))
+}
+
+func TestTranslatedReports(t *testing.T) {
+	feed, err := parseStaticGtfsFromFiles(createGtfsFiles(getGtfsFilesWith(t, "translations")), StaticParseOptions{})
+	assert.NoError(t, err)
+
+	fares, err := FindFares(feed, FareQuery{OriginStopId: "stop1", DestinationStopId: "stop2", RouteId: "route15", Language: "es"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Estación Union", fares.OriginStopName)
+	assert.Equal(t, "Civic Center", fares.DestinationStopName)
+	assert.Equal(t, "Colfax Este", fares.RouteName)
+
+	calculation, err := CreateOtpCalculation(feed)
+	assert.NoError(t, err)
+	logger := log.New(log.Info)
+	tripDate := time.Date(2023, 6, 5, 0, 0, 0, 0, calculation.Location)
+	simulateStop(tripDate, 8*time.Hour+31*time.Minute, "trip1", "stop1", calculation, logger)
+	simulateStop(tripDate, 8*time.Hour+46*time.Minute, "trip1", "stop2", calculation, logger)
+	startTime, endTime := tripDate.Add(8*time.Hour), tripDate.Add(10*time.Hour)
+
+	otpByTrip := calculation.SummarizeOnTimePerformanceByTrip(7*time.Minute, startTime, endTime, logger)
+	calculation.LabelOtpSummary(otpByTrip, NewTranslator(feed, "es"))
+	assert.Equal(t, []OtpSummaryEntry{{Name: "trip1", Label: "Centro de Denver", OnTimePerformance: 1}}, otpByTrip.OtpSummaries)
+	assert.Contains(t, otpByTrip.PrettyPrint(), "trip1 |Centro de Denver|100.00")
+
+	otpByStop := calculation.SummarizeOnTimePerformance(StopId, 7*time.Minute, startTime, endTime, logger)
+	calculation.LabelOtpSummary(otpByStop, NewTranslator(feed, "es"))
+	assert.ElementsMatch(t, []OtpSummaryEntry{
+		{Name: "stop1", Label: "Estación Union", OnTimePerformance: 1},
+		{Name: "stop2", Label: "Civic Center", OnTimePerformance: 1},
+	}, otpByStop.OtpSummaries)
+}
+
+func TestValidateTranslationsAndAttributions(t *testing.T) {
+	files := getGtfsFilesWith(t, "translations")
+	files["translations.txt"] += "\n" +
+		"calendar,service_id,es,Semana,wkdayService,,\n" +
+		"stops,stop_name,es,Union,stop1,,Union Station\n" +
+		"stop_times,stop_headsign,es,Centro,trip1,,\n" +
+		"feed_info,feed_publisher_name,es,RTD,,,"
+	files["attributions.txt"] += "\n" +
+		"attr1,,Someone,0,0,\n" +
+		"attr3,route9,Someone Else,1,0,"
+	report := ValidateStaticGtfsFiles(createGtfsFiles(files))
+	assert.ElementsMatch(t, []ValidationFinding{
+		{File: "translations.txt", Line: 8, Severity: SeverityError, Code: InvalidRow, Message: "table_name calendar can't be translated"},
+		{File: "translations.txt", Line: 9, Severity: SeverityError, Code: InvalidRow, Message: "only one of record_id or field_value can be set"},
+		{File: "translations.txt", Line: 10, Severity: SeverityError, Code: MissingRequiredValue, Message: "record_sub_id is required for stop_times translations with a record_id"},
+		{File: "attributions.txt", Line: 4, Severity: SeverityError, Code: DuplicateKey, Message: "duplicate attribution_id attr1"},
+		{File: "attributions.txt", Line: 4, Severity: SeverityError, Code: MissingRequiredValue, Message: "one of is_producer, is_operator or is_authority must be 1"},
+		{File: "attributions.txt", Line: 5, Severity: SeverityError, Code: ForeignKeyViolation, Message: "route_id route9 does not exist in routes.txt"},
+	}, report.Findings)
+}
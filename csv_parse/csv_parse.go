@@ -6,27 +6,83 @@ import (
 	"io"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 )
 
 var EOF = errors.New("EOF")
 
 type RecordProvider[T any] struct {
+	header          []string
 	columnNameToIdx map[string]int
+	extraColumns    []int // Columns without a tagged field, collected into the extra columns field
 	reader          *csv.Reader
 	recordType      reflect.Type
 	decodeInfo      decodeInfo
+	line            int
+	options         Options
 }
 
-func newRecordProvider[T any](columnNameToIdx map[string]int, reader *csv.Reader) (*RecordProvider[T], error) {
+func newRecordProvider[T any](header []string, columnNameToIdx map[string]int, reader *csv.Reader, options Options) (*RecordProvider[T], error) {
 	var t T
 	recordType := reflect.TypeOf(t)
 
 	decodeInfo, err := GetDecodeInfo(recordType)
-	return &RecordProvider[T]{columnNameToIdx: columnNameToIdx, reader: reader, recordType: recordType, decodeInfo: decodeInfo}, err
+	if err != nil {
+		return nil, err
+	}
+
+	var extraColumns []int
+	if decodeInfo.extraFieldIdx != -1 {
+		tagged := make(map[string]struct{}, len(decodeInfo.fields))
+		for _, fieldDecodeInfo := range decodeInfo.fields {
+			tagged[normalizeColumnName(fieldDecodeInfo.csvName)] = struct{}{}
+		}
+		for i, columnName := range header {
+			normalized := normalizeColumnName(columnName)
+			if _, found := tagged[normalized]; !found && columnNameToIdx[normalized] == i {
+				extraColumns = append(extraColumns, i)
+			}
+		}
+	}
+	return &RecordProvider[T]{header: header, columnNameToIdx: columnNameToIdx, extraColumns: extraColumns, reader: reader, recordType: recordType, decodeInfo: decodeInfo, options: options}, nil
+}
+
+// Header returns the column names from the first row of the CSV, with surrounding whitespace removed
+func (r *RecordProvider[T]) Header() []string {
+	return r.header
+}
+
+// HasColumn reports whether the CSV has a column with the given name
+func (r *RecordProvider[T]) HasColumn(columnName string) bool {
+	_, found := r.columnNameToIdx[normalizeColumnName(columnName)]
+	return found
+}
+
+// Line returns the line number of the record most recently returned by FetchNext
+func (r *RecordProvider[T]) Line() int {
+	return r.line
 }
 
 func convertValueToType(value string, outputType reflect.Type, timeLayout string) (any, error) {
+	if reflect.PointerTo(outputType).Implements(reflect.TypeOf(new(optionalFromCsvConverter)).Elem()) {
+		newVal := reflect.New(outputType)
+		err := newVal.Interface().(optionalFromCsvConverter).convertFromCsv(value, timeLayout)
+		return newVal.Elem().Interface(), err
+	}
+	// Pointer fields are left nil when the value is empty
+	if outputType.Kind() == reflect.Pointer {
+		if value == "" {
+			return reflect.Zero(outputType).Interface(), nil
+		}
+		converted, err := convertValueToType(value, outputType.Elem(), timeLayout)
+		if err != nil {
+			return nil, err
+		}
+		newVal := reflect.New(outputType.Elem())
+		newVal.Elem().Set(reflect.ValueOf(converted).Convert(outputType.Elem()))
+		return newVal.Interface(), nil
+	}
 	if reflect.PointerTo(outputType).Implements(reflect.TypeOf(new(TypeFromCsvConverter)).Elem()) {
 		newVal := reflect.New(outputType)
 		parsed := newVal.Interface()
@@ -38,6 +94,9 @@ func convertValueToType(value string, outputType reflect.Type, timeLayout string
 	case reflect.TypeOf(time.Time{}):
 		if timeLayout == "" {
 			return time.Time{}, errors.New("must specify a timeLayout when parsing to time.Time")
+		} else if value == "" {
+			// Mirrors WriteCsv, which writes the zero time as an empty value
+			return time.Time{}, nil
 		} else {
 			return time.Parse(timeLayout, value)
 		}
@@ -72,37 +131,121 @@ func convertValueToType(value string, outputType reflect.Type, timeLayout string
 	return nil, errors.New("Cannot convert value '" + value + "' to output type " + outputType.String())
 }
 
+// FetchNext returns the next record in the CSV, or EOF once there are no more records. How rows
+// that can't be parsed are handled depends on the Options the RecordProvider was created with
 func (r *RecordProvider[T]) FetchNext() (T, error) {
+	for {
+		parsedRecord, err := r.fetchNextRow()
+		var parseErr *ParseError
+		if err != nil && r.options.ErrorMode != Strict && errors.As(err, &parseErr) {
+			r.reportError(parseErr)
+			continue
+		}
+		return parsedRecord, err
+	}
+}
+
+func (r *RecordProvider[T]) reportError(parseErr *ParseError) {
+	if r.options.OnError != nil {
+		r.options.OnError(parseErr)
+	}
+}
+
+func (r *RecordProvider[T]) fetchNextRow() (T, error) {
 	record, err := r.reader.Read()
+	if len(record) > 0 {
+		r.line, _ = r.reader.FieldPos(0)
+	} else if parseErr, ok := err.(*csv.ParseError); ok {
+		r.line = parseErr.StartLine
+	}
 	var parsedRecord T
 	if err == io.EOF {
 		return parsedRecord, EOF
 	}
 	if err != nil {
+		if _, ok := err.(*csv.ParseError); ok {
+			return parsedRecord, &ParseError{Line: r.line, Err: err}
+		}
 		return parsedRecord, err
 	}
 	for i, fieldDecodeInfo := range r.decodeInfo.fields {
 		columnName := fieldDecodeInfo.csvName
-		columnIdx, found := r.columnNameToIdx[columnName]
+		columnIdx, found := r.columnNameToIdx[normalizeColumnName(columnName)]
 		if found {
-			csvValue := record[columnIdx]
+			// Only ragged rows can be shorter than the header. A missing value is handled as though
+			// the column wasn't in the file
+			if columnIdx >= len(record) && fieldDecodeInfo.defaultValue == "" {
+				continue
+			}
+			var csvValue string
+			if columnIdx < len(record) {
+				csvValue = record[columnIdx]
+			}
+			if r.options.TrimValues {
+				csvValue = strings.TrimSpace(csvValue)
+			}
 			if csvValue == "" && fieldDecodeInfo.defaultValue != "" {
 				csvValue = fieldDecodeInfo.defaultValue
 			}
 			convertedValue, err := convertValueToType(csvValue, r.recordType.Field(i).Type, fieldDecodeInfo.timeLayout)
 			if err != nil {
-				return parsedRecord, err
+				parseErr := &ParseError{Line: r.line, Column: columnName, Value: csvValue, Err: err}
+				if r.options.ErrorMode != DefaultField {
+					return parsedRecord, parseErr
+				}
+				r.reportError(parseErr)
+				if fieldDecodeInfo.defaultValue == "" {
+					continue
+				}
+				convertedValue, err = convertValueToType(fieldDecodeInfo.defaultValue, r.recordType.Field(i).Type, fieldDecodeInfo.timeLayout)
+				if err != nil {
+					continue
+				}
 			}
 			field := reflect.ValueOf(&parsedRecord).Elem().Field(i)
 			// Convert to field.Type in case convertedValue is of a base type and needs to be downcast (e.g., Enum defined from int8 base)
 			field.Set(reflect.ValueOf(convertedValue).Convert(field.Type()))
 		}
 	}
+	r.setExtraColumns(reflect.ValueOf(&parsedRecord).Elem(), record)
 	return parsedRecord, nil
 }
 
+// Empty values are left out, so the field stays nil for rows that have nothing extra
+func (r *RecordProvider[T]) setExtraColumns(parsedRecord reflect.Value, record []string) {
+	var extra reflect.Value
+	for _, columnIdx := range r.extraColumns {
+		if columnIdx >= len(record) {
+			break
+		}
+		csvValue := record[columnIdx]
+		if r.options.TrimValues {
+			csvValue = strings.TrimSpace(csvValue)
+		}
+		if csvValue == "" {
+			continue
+		}
+		if !extra.IsValid() {
+			extra = reflect.MakeMap(r.recordType.Field(r.decodeInfo.extraFieldIdx).Type)
+			parsedRecord.Field(r.decodeInfo.extraFieldIdx).Set(extra)
+		}
+		extra.SetMapIndex(reflect.ValueOf(r.header[columnIdx]), reflect.ValueOf(csvValue))
+	}
+}
+
 func BeginParseCsv[T any](input io.Reader) (*RecordProvider[T], error) {
+	return BeginParseCsvWithOptions[T](input, Options{})
+}
+
+func BeginParseCsvWithOptions[T any](input io.Reader, options Options) (*RecordProvider[T], error) {
+	input, err := decodeInput(input, options.Encoding)
+	if err != nil {
+		return nil, err
+	}
 	reader := csv.NewReader(input)
+	if options.AllowRaggedRows {
+		reader.FieldsPerRecord = -1
+	}
 	header, err := reader.Read()
 	if err != nil && err != io.EOF {
 		print("oopsies")
@@ -114,7 +257,11 @@ func BeginParseCsv[T any](input io.Reader) (*RecordProvider[T], error) {
 	}
 	columnNameToIdx := make(map[string]int)
 	for i, columnName := range header {
-		columnNameToIdx[columnName] = i
+		header[i] = strings.TrimSpace(strings.TrimPrefix(columnName, "\ufeff"))
+		// If a column is repeated, use the first one
+		if _, found := columnNameToIdx[normalizeColumnName(columnName)]; !found {
+			columnNameToIdx[normalizeColumnName(columnName)] = i
+		}
 	}
-	return newRecordProvider[T](columnNameToIdx, reader)
+	return newRecordProvider[T](header, columnNameToIdx, reader, options)
 }
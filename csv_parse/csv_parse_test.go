@@ -81,7 +81,55 @@ func TestFailsOnUnparseableField(t *testing.T) {
 		t.Error(err)
 	}
 	_, err = recordProvider.FetchNext()
-	assert.Equal(t, "Cannot convert value 'garbage' to output type []uint8", err.Error())
+	assert.Equal(t, "line 2, column unparseable_field: Cannot convert value 'garbage' to output type []uint8", err.Error())
+	var parseErr *ParseError
+	assert.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, 2, parseErr.Line)
+	assert.Equal(t, "unparseable_field", parseErr.Column)
+	assert.Equal(t, "garbage", parseErr.Value)
+}
+
+func TestSkipRowErrorMode(t *testing.T) {
+	var parseErrs []*ParseError
+	options := Options{ErrorMode: SkipRow, OnError: func(parseErr *ParseError) { parseErrs = append(parseErrs, parseErr) }}
+	recordProvider, err := BeginParseCsvWithOptions[TestType](strings.NewReader("field_1,field_2\nvalue_1,one\nvalue_2,2,extra\nvalue_3,3"), options)
+	assert.NoError(t, err)
+
+	newRecord, err := recordProvider.FetchNext()
+	assert.NoError(t, err)
+	assert.Equal(t, TestType{Field1: "value_3", Field2: 3}, newRecord)
+	assert.Equal(t, 4, recordProvider.Line())
+
+	_, err = recordProvider.FetchNext()
+	assert.Equal(t, EOF, err)
+
+	assert.Equal(t, 2, len(parseErrs))
+	assert.Equal(t, 2, parseErrs[0].Line)
+	assert.Equal(t, "field_2", parseErrs[0].Column)
+	assert.Equal(t, "one", parseErrs[0].Value)
+	assert.Equal(t, 3, parseErrs[1].Line)
+	assert.Equal(t, "", parseErrs[1].Column)
+	assert.Equal(t, "record on line 3: wrong number of fields", parseErrs[1].Error())
+}
+
+type DefaultedType struct {
+	Field1 string `csv_parse:"field_1"`
+	Field2 int    `csv_parse:"field_2;default:7"`
+	Field3 int    `csv_parse:"field_3"`
+}
+
+func TestDefaultFieldErrorMode(t *testing.T) {
+	var parseErrs []*ParseError
+	options := Options{ErrorMode: DefaultField, OnError: func(parseErr *ParseError) { parseErrs = append(parseErrs, parseErr) }}
+	recordProvider, err := BeginParseCsvWithOptions[DefaultedType](strings.NewReader("field_1,field_2,field_3\nvalue_1,two,three"), options)
+	assert.NoError(t, err)
+
+	newRecord, err := recordProvider.FetchNext()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultedType{Field1: "value_1", Field2: 7, Field3: 0}, newRecord)
+	assert.Equal(t, 2, len(parseErrs))
+	assert.Equal(t, "field_2", parseErrs[0].Column)
+	assert.Equal(t, "field_3", parseErrs[1].Column)
 }
 
 func TestEmptyCsv(t *testing.T) {
@@ -195,3 +243,65 @@ func TestCustomParse(t *testing.T) {
 	}
 	assert.Equal(t, LetterAsNumberType(1), newRecord.Field1)
 }
+
+func TestNormalizesHeader(t *testing.T) {
+	recordProvider, err := BeginParseCsv[TestType](strings.NewReader("\ufeffField_1, FIELD_2 \nvalue_1,2"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Field_1", "FIELD_2"}, recordProvider.Header())
+	assert.True(t, recordProvider.HasColumn("Field_2"))
+	newRecord, err := recordProvider.FetchNext()
+	assert.NoError(t, err)
+	assert.Equal(t, TestType{Field1: "value_1", Field2: 2}, newRecord)
+}
+
+func TestTrimValues(t *testing.T) {
+	input := "field_1,field_2\n  value_1 , 2 "
+	recordProvider, err := BeginParseCsv[TestType](strings.NewReader(input))
+	assert.NoError(t, err)
+	_, err = recordProvider.FetchNext()
+	assert.Error(t, err)
+
+	recordProvider, err = BeginParseCsvWithOptions[TestType](strings.NewReader(input), Options{TrimValues: true})
+	assert.NoError(t, err)
+	newRecord, err := recordProvider.FetchNext()
+	assert.NoError(t, err)
+	assert.Equal(t, TestType{Field1: "value_1", Field2: 2}, newRecord)
+}
+
+func TestAllowRaggedRows(t *testing.T) {
+	recordProvider, err := BeginParseCsvWithOptions[TestType](strings.NewReader("field_1,field_2\nvalue_1,2,extra\nvalue_2"), Options{AllowRaggedRows: true})
+	assert.NoError(t, err)
+	newRecord, err := recordProvider.FetchNext()
+	assert.NoError(t, err)
+	assert.Equal(t, TestType{Field1: "value_1", Field2: 2}, newRecord)
+	newRecord, err = recordProvider.FetchNext()
+	assert.NoError(t, err)
+	assert.Equal(t, TestType{Field1: "value_2"}, newRecord)
+}
+
+type ExtraColumnsType struct {
+	Field1 string            `csv_parse:"field_1"`
+	Extra  map[string]string `csv_parse:",extra"`
+}
+
+func TestExtraColumns(t *testing.T) {
+	recordProvider, err := BeginParseCsv[ExtraColumnsType](strings.NewReader("field_1,Platform_Code,level\nvalue_1,A,\nvalue_2,,"))
+	assert.NoError(t, err)
+	newRecord, err := recordProvider.FetchNext()
+	assert.NoError(t, err)
+	assert.Equal(t, ExtraColumnsType{Field1: "value_1", Extra: map[string]string{"Platform_Code": "A"}}, newRecord)
+
+	// Rows without any extra values don't get a map
+	newRecord, err = recordProvider.FetchNext()
+	assert.NoError(t, err)
+	assert.Nil(t, newRecord.Extra)
+}
+
+type InvalidExtraColumnsType struct {
+	Extra map[string]int `csv_parse:",extra"`
+}
+
+func TestInvalidExtraColumnsType(t *testing.T) {
+	_, err := BeginParseCsv[InvalidExtraColumnsType](strings.NewReader("field_1\nvalue_1"))
+	assert.EqualError(t, err, "Field Extra must be a map[string]string to hold extra columns")
+}
@@ -0,0 +1,132 @@
+package csv_parse
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// WriteCsv writes records to output as a CSV file. The header row is made up of the
+// csv_parse tags of T, in struct field order, followed by any extra columns in
+// alphabetical order. Untagged fields are not written
+func WriteCsv[T any](output io.Writer, records []T) error {
+	var t T
+	recordType := reflect.TypeOf(t)
+	decodeInfo, err := GetDecodeInfo(recordType)
+	if err != nil {
+		return err
+	}
+
+	var header []string
+	var fieldIdxs []int
+	for i, fieldDecodeInfo := range decodeInfo.fields {
+		if fieldDecodeInfo.csvName != "" {
+			header = append(header, fieldDecodeInfo.csvName)
+			fieldIdxs = append(fieldIdxs, i)
+		}
+	}
+	extraColumns := getExtraColumns(records, decodeInfo)
+	header = append(header, extraColumns...)
+
+	writer := csv.NewWriter(output)
+	err = writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	row := make([]string, len(header))
+	for _, record := range records {
+		recordValue := reflect.ValueOf(record)
+		for rowIdx, fieldIdx := range fieldIdxs {
+			row[rowIdx], err = convertTypeToValue(recordValue.Field(fieldIdx), decodeInfo.fields[fieldIdx].timeLayout)
+			if err != nil {
+				return err
+			}
+		}
+		for i, columnName := range extraColumns {
+			row[len(fieldIdxs)+i] = ""
+			if value := recordValue.Field(decodeInfo.extraFieldIdx).MapIndex(reflect.ValueOf(columnName)); value.IsValid() {
+				row[len(fieldIdxs)+i] = value.String()
+			}
+		}
+		err = writer.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// Returns the names of the extra columns used by any of the records. Extra columns with
+// the same name as a tagged field are left out, since they can't be parsed back in
+func getExtraColumns[T any](records []T, decodeInfo decodeInfo) []string {
+	if decodeInfo.extraFieldIdx == -1 {
+		return nil
+	}
+	tagged := make(map[string]struct{}, len(decodeInfo.fields))
+	for _, fieldDecodeInfo := range decodeInfo.fields {
+		tagged[normalizeColumnName(fieldDecodeInfo.csvName)] = struct{}{}
+	}
+	columns := make(map[string]struct{})
+	for _, record := range records {
+		iter := reflect.ValueOf(record).Field(decodeInfo.extraFieldIdx).MapRange()
+		for iter.Next() {
+			columnName := iter.Key().String()
+			if _, found := tagged[normalizeColumnName(columnName)]; !found {
+				columns[columnName] = struct{}{}
+			}
+		}
+	}
+	sortedColumns := make([]string, 0, len(columns))
+	for columnName := range columns {
+		sortedColumns = append(sortedColumns, columnName)
+	}
+	sort.Strings(sortedColumns)
+	return sortedColumns
+}
+
+func convertTypeToValue(value reflect.Value, timeLayout string) (string, error) {
+	if value.Type().Implements(reflect.TypeOf(new(optionalToCsvConverter)).Elem()) {
+		return value.Interface().(optionalToCsvConverter).convertToCsv(timeLayout)
+	}
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return "", nil
+		}
+		return convertTypeToValue(value.Elem(), timeLayout)
+	}
+	if value.Type().Implements(reflect.TypeOf(new(TypeToCsvConverter)).Elem()) {
+		return value.Interface().(TypeToCsvConverter).ConvertToCsv()
+	}
+	switch value.Type() {
+	case reflect.TypeOf(time.Time{}):
+		if timeLayout == "" {
+			return "", errors.New("must specify a timeLayout when writing time.Time")
+		}
+		timeValue := value.Interface().(time.Time)
+		if timeValue.IsZero() {
+			return "", nil
+		}
+		return timeValue.Format(timeLayout), nil
+	}
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64), nil
+	case reflect.String:
+		return value.String(), nil
+	}
+	return "", errors.New("Cannot convert type " + value.Type().String() + " to a csv value")
+}
@@ -0,0 +1,66 @@
+package csv_parse
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type WriteType struct {
+	Field1         string    `csv_parse:"field_1"`
+	Field2         int       `csv_parse:"field_2"`
+	Field3         float64   `csv_parse:"field_3"`
+	Field4         time.Time `csv_parse:"field_4;timeLayout:20060102"`
+	UnlabeledField []byte
+}
+
+func TestWriteCsv(t *testing.T) {
+	builder := strings.Builder{}
+	records := []WriteType{
+		{Field1: "value_1", Field2: 2, Field3: 39.7525, Field4: time.Date(2023, 5, 12, 0, 0, 0, 0, time.UTC)},
+		{Field1: "value, with comma", Field2: -1},
+	}
+	err := WriteCsv(&builder, records)
+	assert.NoError(t, err)
+	assert.Equal(t, "field_1,field_2,field_3,field_4\nvalue_1,2,39.7525,20230512\n\"value, with comma\",-1,0,\n", builder.String())
+}
+
+func TestWriteCsvRoundTrip(t *testing.T) {
+	builder := strings.Builder{}
+	records := []CustomWriteType{{Field1: 1}}
+	err := WriteCsv(&builder, records)
+	assert.NoError(t, err)
+
+	recordProvider, err := BeginParseCsv[CustomWriteType](strings.NewReader(builder.String()))
+	assert.NoError(t, err)
+	newRecord, err := recordProvider.FetchNext()
+	assert.NoError(t, err)
+	assert.Equal(t, records[0], newRecord)
+}
+
+func TestWriteExtraColumns(t *testing.T) {
+	builder := strings.Builder{}
+	records := []ExtraColumnsType{
+		{Field1: "value_1", Extra: map[string]string{"zone": "1", "field_1": "ignored"}},
+		{Field1: "value_2", Extra: map[string]string{"level": "2"}},
+		{Field1: "value_3"},
+	}
+	err := WriteCsv(&builder, records)
+	assert.NoError(t, err)
+	assert.Equal(t, "field_1,level,zone\nvalue_1,,1\nvalue_2,2,\nvalue_3,,\n", builder.String())
+}
+
+type CustomWriteType struct {
+	Field1 LetterAsNumberType `csv_parse:"field_1"`
+}
+
+func (custom LetterAsNumberType) ConvertToCsv() (string, error) {
+	switch custom {
+	case 1:
+		return "a", nil
+	default:
+		return "b", nil
+	}
+}
@@ -0,0 +1,92 @@
+package csv_parse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// How much of the input is checked when detecting its encoding
+const encodingSampleSize = 64 * 1024
+
+// Wraps input so that it is read as UTF-8. A byte order mark at the start of the input always
+// takes precedence over encodingName, and is removed
+func decodeInput(input io.Reader, encodingName string) (io.Reader, error) {
+	var decoder transform.Transformer
+	if encodingName != "" {
+		explicitEncoding, err := htmlindex.Get(encodingName)
+		if err != nil {
+			return nil, fmt.Errorf("unknown encoding %s", encodingName)
+		}
+		decoder = explicitEncoding.NewDecoder()
+	} else {
+		bufferedInput := bufio.NewReaderSize(input, encodingSampleSize)
+		sample, _ := bufferedInput.Peek(encodingSampleSize)
+		input = bufferedInput
+		decoder = &utf8OrWindows1252Decoder{}
+		if !isUtf8Prefix(sample) {
+			// Files that aren't UTF-8 are almost always exported from Excel on Windows
+			decoder = charmap.Windows1252.NewDecoder()
+		}
+	}
+	return transform.NewReader(input, unicode.BOMOverride(decoder)), nil
+}
+
+// Decodes UTF-8 until it finds bytes that aren't, past the sample that was checked, and then decodes
+// the rest of the input as Windows-1252. When the sample was ASCII, like in a file whose only accented
+// names come later, that is the same as detecting Windows-1252 from the start
+type utf8OrWindows1252Decoder struct {
+	windows1252 transform.Transformer // Set once invalid UTF-8 is found
+}
+
+func (decoder *utf8OrWindows1252Decoder) Transform(dst []byte, src []byte, atEOF bool) (int, int, error) {
+	nDst, nSrc := 0, 0
+	for nSrc < len(src) {
+		if decoder.windows1252 != nil {
+			n, m, err := decoder.windows1252.Transform(dst[nDst:], src[nSrc:], atEOF)
+			return nDst + n, nSrc + m, err
+		}
+		r, size := utf8.DecodeRune(src[nSrc:])
+		if r == utf8.RuneError && size <= 1 {
+			if !atEOF && !utf8.FullRune(src[nSrc:]) {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			decoder.windows1252 = charmap.Windows1252.NewDecoder()
+			continue
+		}
+		if nDst+size > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], src[nSrc:nSrc+size])
+		nSrc += size
+	}
+	return nDst, nSrc, nil
+}
+
+func (decoder *utf8OrWindows1252Decoder) Reset() {
+	decoder.windows1252 = nil
+}
+
+// Like utf8.Valid, but allows the data to end part way through a rune, since it is a sample
+func isUtf8Prefix(data []byte) bool {
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size <= 1 {
+			return !utf8.FullRune(data)
+		}
+		data = data[size:]
+	}
+	return true
+}
+
+// Column names are matched ignoring case and surrounding whitespace
+func normalizeColumnName(columnName string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(columnName, "\ufeff")))
+}
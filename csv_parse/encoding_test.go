@@ -0,0 +1,85 @@
+package csv_parse
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseSingleRecord(t *testing.T, input []byte, encoding string) TestType {
+	recordProvider, err := BeginParseCsvWithOptions[TestType](bytes.NewReader(input), Options{Encoding: encoding})
+	assert.NoError(t, err)
+	newRecord, err := recordProvider.FetchNext()
+	assert.NoError(t, err)
+	return newRecord
+}
+
+func TestDetectsWindows1252(t *testing.T) {
+	// "Café" with é as the single Windows-1252 byte 0xE9
+	input := []byte("field_1,field_2\nCaf\xe9,2")
+	assert.Equal(t, "Café", parseSingleRecord(t, input, "").Field1)
+}
+
+func TestDetectsWindows1252AfterSample(t *testing.T) {
+	input := []byte("field_1,field_2\n")
+	for len(input) <= encodingSampleSize {
+		input = append(input, "Union Station,1\n"...)
+	}
+	input = append(input, "Montr\xe9al,2\nQu\xe9bec,3"...)
+	recordProvider, err := BeginParseCsvWithOptions[TestType](bytes.NewReader(input), Options{})
+	assert.NoError(t, err)
+	var records []TestType
+	for {
+		record, err := recordProvider.FetchNext()
+		if err == EOF {
+			break
+		}
+		assert.NoError(t, err)
+		records = append(records, record)
+	}
+	assert.Equal(t, TestType{Field1: "Union Station", Field2: 1}, records[0])
+	assert.Equal(t, TestType{Field1: "Montréal", Field2: 2}, records[len(records)-2])
+	assert.Equal(t, TestType{Field1: "Québec", Field2: 3}, records[len(records)-1])
+
+	// Valid UTF-8 after the sample is kept as is
+	input = append(input[:len(input)-len("Montr\xe9al,2\nQu\xe9bec,3")], "Montréal,2"...)
+	recordProvider, err = BeginParseCsvWithOptions[TestType](bytes.NewReader(input), Options{})
+	assert.NoError(t, err)
+	var last TestType
+	for {
+		record, err := recordProvider.FetchNext()
+		if err == EOF {
+			break
+		}
+		last = record
+	}
+	assert.Equal(t, "Montréal", last.Field1)
+}
+
+func TestDetectsUtf16(t *testing.T) {
+	input := []byte{0xff, 0xfe}
+	for _, c := range "field_1,field_2\nCafé,2" {
+		input = append(input, byte(c), 0)
+	}
+	assert.Equal(t, TestType{Field1: "Café", Field2: 2}, parseSingleRecord(t, input, ""))
+}
+
+func TestExplicitEncoding(t *testing.T) {
+	// 0xE9 is é in Windows-1252, but Ú in the older IBM code page 850
+	input := []byte("field_1,field_2\n\xe9,2")
+	assert.Equal(t, "é", parseSingleRecord(t, input, "windows-1252").Field1)
+
+	// Valid UTF-8 is kept as is
+	assert.Equal(t, "é", parseSingleRecord(t, []byte("field_1,field_2\né,2"), "").Field1)
+
+	_, err := BeginParseCsvWithOptions[TestType](bytes.NewReader(input), Options{Encoding: "klingon"})
+	assert.EqualError(t, err, "unknown encoding klingon")
+}
+
+func TestIsUtf8Prefix(t *testing.T) {
+	assert.True(t, isUtf8Prefix([]byte("Caf\xc3\xa9")))
+	// Cut part way through é
+	assert.True(t, isUtf8Prefix([]byte("Caf\xc3")))
+	assert.False(t, isUtf8Prefix([]byte("Caf\xe9 au lait")))
+}
@@ -0,0 +1,123 @@
+package csv_parse
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+)
+
+// Optional holds a value that may be missing from the CSV. An empty cell parses to an
+// Optional with Valid set to false, rather than to the zero value of T. Optional also
+// implements sql.Scanner and driver.Valuer, so missing values are stored as NULL
+type Optional[T any] struct {
+	V     T
+	Valid bool
+}
+
+func NewOptional[T any](value T) Optional[T] {
+	return Optional[T]{V: value, Valid: true}
+}
+
+// Get returns the value if it is present, or fallback if it is missing
+func (o Optional[T]) Get(fallback T) T {
+	if o.Valid {
+		return o.V
+	}
+	return fallback
+}
+
+type optionalFromCsvConverter interface {
+	convertFromCsv(value string, timeLayout string) error
+}
+
+type optionalToCsvConverter interface {
+	convertToCsv(timeLayout string) (string, error)
+}
+
+func (o *Optional[T]) valueType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (o *Optional[T]) convertFromCsv(value string, timeLayout string) error {
+	if value == "" {
+		*o = Optional[T]{}
+		return nil
+	}
+	converted, err := convertValueToType(value, o.valueType(), timeLayout)
+	if err != nil {
+		return err
+	}
+	o.V = reflect.ValueOf(converted).Convert(o.valueType()).Interface().(T)
+	o.Valid = true
+	return nil
+}
+
+func (o Optional[T]) convertToCsv(timeLayout string) (string, error) {
+	if !o.Valid {
+		return "", nil
+	}
+	return convertTypeToValue(reflect.ValueOf(o.V), timeLayout)
+}
+
+func (o Optional[T]) Value() (driver.Value, error) {
+	if !o.Valid {
+		return nil, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(o.V)
+}
+
+func (o *Optional[T]) Scan(src any) error {
+	if src == nil {
+		*o = Optional[T]{}
+		return nil
+	}
+	valueType := o.valueType()
+	if bytes, ok := src.([]byte); ok {
+		src = string(bytes)
+	}
+	srcValue := reflect.ValueOf(src)
+	if !srcValue.CanConvert(valueType) {
+		return errors.New("cannot scan " + srcValue.Type().String() + " into Optional[" + valueType.String() + "]")
+	}
+	o.V = srcValue.Convert(valueType).Interface().(T)
+	o.Valid = true
+	return nil
+}
+
+// GormDataType tells gorm which column type to create for the wrapped type
+func (o Optional[T]) GormDataType() string {
+	valueType := o.valueType()
+	if valueType == reflect.TypeOf(time.Time{}) {
+		return "time"
+	}
+	switch valueType.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "uint"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	default:
+		return "string"
+	}
+}
+
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.V)
+}
+
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*o = Optional[T]{}
+		return nil
+	}
+	o.Valid = true
+	return json.Unmarshal(data, &o.V)
+}
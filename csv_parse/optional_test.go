@@ -0,0 +1,70 @@
+package csv_parse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type OptionalType struct {
+	Field1 Optional[int]                `csv_parse:"field_1"`
+	Field2 *float64                     `csv_parse:"field_2"`
+	Field3 Optional[LetterAsNumberType] `csv_parse:"field_3"`
+	Field4 Optional[int]                `csv_parse:"field_4;default:7"`
+}
+
+func TestParseOptionalFields(t *testing.T) {
+	recordProvider, err := BeginParseCsv[OptionalType](strings.NewReader("field_1,field_2,field_3,field_4\n0,1.5,a,\n,,,"))
+	assert.NoError(t, err)
+
+	newRecord, err := recordProvider.FetchNext()
+	assert.NoError(t, err)
+	assert.Equal(t, NewOptional(0), newRecord.Field1)
+	assert.Equal(t, 1.5, *newRecord.Field2)
+	assert.Equal(t, NewOptional(LetterAsNumberType(1)), newRecord.Field3)
+	assert.Equal(t, NewOptional(7), newRecord.Field4)
+
+	// Empty values are missing, rather than zero
+	newRecord, err = recordProvider.FetchNext()
+	assert.NoError(t, err)
+	assert.False(t, newRecord.Field1.Valid)
+	assert.Nil(t, newRecord.Field2)
+	assert.False(t, newRecord.Field3.Valid)
+	assert.Equal(t, 3, newRecord.Field1.Get(3))
+}
+
+func TestParseOptionalFieldError(t *testing.T) {
+	recordProvider, err := BeginParseCsv[OptionalType](strings.NewReader("field_1,field_2\nabc,1"))
+	assert.NoError(t, err)
+	_, err = recordProvider.FetchNext()
+	assert.EqualError(t, err, `line 2, column field_1: strconv.ParseInt: parsing "abc": invalid syntax`)
+}
+
+func TestWriteOptionalFields(t *testing.T) {
+	builder := strings.Builder{}
+	value := 2.25
+	records := []OptionalType{
+		{Field1: NewOptional(0), Field2: &value, Field3: NewOptional(LetterAsNumberType(1)), Field4: NewOptional(7)},
+		{},
+	}
+	err := WriteCsv(&builder, records)
+	assert.NoError(t, err)
+	assert.Equal(t, "field_1,field_2,field_3,field_4\n0,2.25,a,7\n,,,\n", builder.String())
+}
+
+func TestOptionalSql(t *testing.T) {
+	value, err := Optional[LetterAsNumberType]{}.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+	value, err = NewOptional(LetterAsNumberType(1)).Value()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), value)
+
+	var scanned Optional[LetterAsNumberType]
+	assert.NoError(t, scanned.Scan(int64(1)))
+	assert.Equal(t, NewOptional(LetterAsNumberType(1)), scanned)
+	assert.NoError(t, scanned.Scan(nil))
+	assert.False(t, scanned.Valid)
+	assert.Error(t, scanned.Scan("a"))
+}
@@ -0,0 +1,87 @@
+package csv_parse
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ParseError describes a row, or a single field in a row, that could not be parsed
+type ParseError struct {
+	Line   int
+	Column string // Empty when the whole row could not be read
+	Value  string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	if e.Column == "" {
+		// Errors from encoding/csv already include the line number
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("line %d, column %s: %s", e.Line, e.Column, e.Err.Error())
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+type ErrorMode int8
+
+const (
+	// Stop at the first error, returning it from FetchNext
+	Strict ErrorMode = iota
+	// Skip any row with an error, and continue to the next row
+	SkipRow
+	// Set any field that can't be parsed to its default value, and keep the row. Rows that
+	// can't be read at all are skipped
+	DefaultField
+)
+
+type Options struct {
+	ErrorMode ErrorMode
+	// Called for every error that is skipped or defaulted, when ErrorMode is not Strict
+	OnError func(*ParseError)
+	// Name of the input's character encoding, such as "windows-1252" or "utf-16le". When empty, the
+	// input is read as UTF-8, unless it starts with a UTF-16 byte order mark or isn't valid UTF-8,
+	// in which case it is read as UTF-16 or Windows-1252
+	Encoding string
+	// Remove leading and trailing whitespace from every value
+	TrimValues bool
+	// Allow rows with more or fewer values than the header. Missing values are handled as though
+	// their columns weren't in the file, and extra values are ignored
+	AllowRaggedRows bool
+}
+
+// These functions are used for the cobra CLI tool to parse user specified error modes
+func (e *ErrorMode) String() string {
+	switch *e {
+	case Strict:
+		return "strict"
+	case SkipRow:
+		return "skip-row"
+	case DefaultField:
+		return "default-field"
+	default:
+		return "unknown"
+	}
+}
+
+func (e *ErrorMode) Set(v string) error {
+	switch v {
+	case "strict":
+		*e = Strict
+		return nil
+	case "skip-row":
+		*e = SkipRow
+		return nil
+	case "default-field":
+		*e = DefaultField
+		return nil
+	default:
+		return errors.New(`must be one of "strict", "skip-row" or "default-field"`)
+	}
+}
+
+func (e *ErrorMode) Type() string {
+	return "csv_parse.ErrorMode"
+}
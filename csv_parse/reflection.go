@@ -6,8 +6,12 @@ import (
 	"strings"
 )
 
+// Tag for a map[string]string field that collects every column without a field of its own
+const ExtraColumnsTag = ",extra"
+
 type decodeInfo struct {
-	fields []fieldDecodeInfo
+	fields        []fieldDecodeInfo
+	extraFieldIdx int // -1 when there is no extra columns field
 }
 
 type fieldDecodeInfo struct {
@@ -18,11 +22,20 @@ type fieldDecodeInfo struct {
 
 // GetDecodeInfo reads the reflection tags
 func GetDecodeInfo(t reflect.Type) (decodeInfo, error) {
-	var result decodeInfo
+	result := decodeInfo{extraFieldIdx: -1}
 	result.fields = make([]fieldDecodeInfo, t.NumField())
 	for i := 0; i < t.NumField(); i++ {
 		fieldTag := t.Field(i).Tag.Get("csv_parse")
-		if fieldTag != "" {
+		if fieldTag == ExtraColumnsTag {
+			fieldType := t.Field(i).Type
+			if fieldType.Kind() != reflect.Map || fieldType.Key().Kind() != reflect.String || fieldType.Elem().Kind() != reflect.String {
+				return result, errors.New("Field " + t.Field(i).Name + " must be a map[string]string to hold extra columns")
+			}
+			if result.extraFieldIdx != -1 {
+				return result, errors.New("Only one field can hold extra columns")
+			}
+			result.extraFieldIdx = i
+		} else if fieldTag != "" {
 			fieldTag = strings.Trim(fieldTag, " ")
 			if !strings.Contains(fieldTag, ";") {
 				result.fields[i] = fieldDecodeInfo{csvName: fieldTag}
@@ -63,3 +76,7 @@ func GetDecodeInfo(t reflect.Type) (decodeInfo, error) {
 type TypeFromCsvConverter interface {
 	ConvertFromCsv(string) error
 }
+
+type TypeToCsvConverter interface {
+	ConvertToCsv() (string, error)
+}
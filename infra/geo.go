@@ -0,0 +1,53 @@
+package infra
+
+import "math"
+
+const earthRadiusMeters = 6371000
+
+// Great-circle distance between two WGS84 coordinates, using the haversine formula
+func DistanceMeters(latitude1 float64, longitude1 float64, latitude2 float64, longitude2 float64) float64 {
+	lat1 := latitude1 * math.Pi / 180
+	lat2 := latitude2 * math.Pi / 180
+	deltaLat := (latitude2 - latitude1) * math.Pi / 180
+	deltaLon := (longitude2 - longitude1) * math.Pi / 180
+
+	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(deltaLon/2)*math.Sin(deltaLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+type Coordinate struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Shortest distance from a point to a path made of straight segments between consecutive
+// coordinates. Segments are projected onto a plane around the point, which is accurate for
+// the short distances between transit stops
+func DistanceToPathMeters(point Coordinate, path []Coordinate) float64 {
+	if len(path) == 0 {
+		return math.Inf(1)
+	}
+	if len(path) == 1 {
+		return DistanceMeters(point.Latitude, point.Longitude, path[0].Latitude, path[0].Longitude)
+	}
+
+	metersPerDegreeLat := earthRadiusMeters * math.Pi / 180
+	metersPerDegreeLon := metersPerDegreeLat * math.Cos(point.Latitude*math.Pi/180)
+	project := func(coordinate Coordinate) (float64, float64) {
+		return (coordinate.Longitude - point.Longitude) * metersPerDegreeLon, (coordinate.Latitude - point.Latitude) * metersPerDegreeLat
+	}
+
+	minDistance := math.Inf(1)
+	for i := 1; i < len(path); i++ {
+		x1, y1 := project(path[i-1])
+		x2, y2 := project(path[i])
+		dx, dy := x2-x1, y2-y1
+		// Fraction along the segment of the point closest to the origin (our projected point)
+		fraction := 0.0
+		if dx != 0 || dy != 0 {
+			fraction = math.Max(0, math.Min(1, -(x1*dx+y1*dy)/(dx*dx+dy*dy)))
+		}
+		minDistance = math.Min(minDistance, math.Hypot(x1+fraction*dx, y1+fraction*dy))
+	}
+	return minDistance
+}
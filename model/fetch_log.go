@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+type FetchOutcome int8
+
+const (
+	FetchSucceeded  FetchOutcome = 0
+	FetchHttpError  FetchOutcome = 1 // The server couldn't be reached, or didn't respond with 200 OK
+	FetchParseError FetchOutcome = 2 // The response wasn't a valid feed
+	FetchDuplicate  FetchOutcome = 3 // The feed hadn't changed since it was last stored
+	FetchOtherError FetchOutcome = 4 // Like a failed database write
+)
+
+// One poll of a feed url by the store command. A run of failed fetches is an outage of the feed
+type FetchLog struct {
+	Id         uint      `gorm:"primaryKey"`
+	Namespace  string    `gorm:"index"`
+	Url        string    `gorm:"not null;default:null"`
+	EntityType string    `gorm:"not null;default:null"` // static, vehicle_positions, trip_updates or alerts
+	FetchTime  time.Time `gorm:"index;not null;default:null"`
+	DurationMs int64
+	Outcome    FetchOutcome
+	HttpStatus int    // The status of the last attempt, or 0 if there was no response
+	Attempts   int    // How many requests were made, including retries
+	Entities   uint64 // How many vehicle positions, trip updates or alerts were stored
+	// How many fetches of this url in a row have failed, including this one. 0 when this one didn't fail
+	ConsecutiveFailures int
+	Error               string `gorm:"default:null"`
+}
+
+// What the store command knows about the last static feed it stored from a url, so it can skip
+// downloading or parsing a feed that hasn't changed
+type StaticFetchState struct {
+	Namespace    string `gorm:"primaryKey"`
+	Url          string `gorm:"primaryKey;not null;default:null"`
+	ETag         string `gorm:"column:etag;default:null"` // Sent back in If-None-Match
+	LastModified string `gorm:"default:null"`             // Sent back in If-Modified-Since
+	ZipHash      string `gorm:"default:null"`             // Hex SHA-256 of the zip file
+	UpdateTime   time.Time
+}
@@ -0,0 +1,170 @@
+package model
+
+import "github.com/samc1213/gtfs-analyze/csv_parse"
+
+type PaymentMethod int8
+
+const (
+	PaidOnBoard        PaymentMethod = 0
+	PaidBeforeBoarding PaymentMethod = 1
+)
+
+type FareTransfers int8
+
+const (
+	NoTransfers  FareTransfers = 0
+	OneTransfer  FareTransfers = 1
+	TwoTransfers FareTransfers = 2
+)
+
+// Fares v1, from fare_attributes.txt
+type FareAttribute struct {
+	Version          string                            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo         *FeedInfo                         `gorm:"foreignKey:Version;belongsTo"`
+	Id               string                            `csv_parse:"fare_id" gorm:"primaryKey;not null;default:null"`
+	Price            float64                           `csv_parse:"price"`
+	CurrencyType     string                            `csv_parse:"currency_type" gorm:"default:null"`
+	PaymentMethod    PaymentMethod                     `csv_parse:"payment_method"`
+	Transfers        csv_parse.Optional[FareTransfers] `csv_parse:"transfers"` // Missing means unlimited transfers
+	AgencyId         string                            `csv_parse:"agency_id" gorm:"default:null"`
+	TransferDuration csv_parse.Optional[int32]         `csv_parse:"transfer_duration"` // In seconds
+	Extra            map[string]string                 `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+// Fares v1, from fare_rules.txt. Every column is part of the key, since a fare can have many rules
+// and any of the columns other than fare_id can be empty
+type FareRule struct {
+	Version       string            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo      *FeedInfo         `gorm:"foreignKey:Version;belongsTo"`
+	FareId        string            `csv_parse:"fare_id" gorm:"primaryKey;not null;default:null"`
+	RouteId       string            `csv_parse:"route_id" gorm:"primaryKey"`
+	OriginId      string            `csv_parse:"origin_id" gorm:"primaryKey"`      // A zone_id from stops.txt
+	DestinationId string            `csv_parse:"destination_id" gorm:"primaryKey"` // A zone_id from stops.txt
+	ContainsId    string            `csv_parse:"contains_id" gorm:"primaryKey"`    // A zone_id from stops.txt
+	Extra         map[string]string `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+type FareMediaType int8
+
+const (
+	NoFareMedia         FareMediaType = 0
+	PhysicalPaperTicket FareMediaType = 1
+	PhysicalTransitCard FareMediaType = 2
+	ContactlessEmv      FareMediaType = 3
+	MobileApp           FareMediaType = 4
+)
+
+type DurationLimitType int8
+
+const (
+	DepartureToArrival   DurationLimitType = 0
+	DepartureToDeparture DurationLimitType = 1
+	ArrivalToDeparture   DurationLimitType = 2
+	ArrivalToArrival     DurationLimitType = 3
+)
+
+type FareTransferType int8
+
+const (
+	FromLegPlusTransfer          FareTransferType = 0 // A + AB
+	FromLegPlusTransferPlusToLeg FareTransferType = 1 // A + AB + B
+	TransferOnly                 FareTransferType = 2 // AB
+)
+
+// Fares v2, from fare_media.txt
+type FareMedia struct {
+	Version  string            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo *FeedInfo         `gorm:"foreignKey:Version;belongsTo"`
+	Id       string            `csv_parse:"fare_media_id" gorm:"primaryKey;not null;default:null"`
+	Name     string            `csv_parse:"fare_media_name" gorm:"default:null"`
+	Type     FareMediaType     `csv_parse:"fare_media_type"`
+	Extra    map[string]string `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+// Fares v2, from fare_products.txt. A product can have a different price on each fare media
+type FareProduct struct {
+	Version     string            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo    *FeedInfo         `gorm:"foreignKey:Version;belongsTo"`
+	Id          string            `csv_parse:"fare_product_id" gorm:"primaryKey;not null;default:null"`
+	Name        string            `csv_parse:"fare_product_name" gorm:"default:null"`
+	FareMediaId string            `csv_parse:"fare_media_id" gorm:"primaryKey"`
+	Amount      float64           `csv_parse:"amount"`
+	Currency    string            `csv_parse:"currency" gorm:"default:null"`
+	Extra       map[string]string `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+// Fares v2, from fare_leg_rules.txt
+type FareLegRule struct {
+	Version              string                    `gorm:"primaryKey;not null;default:null"`
+	FeedInfo             *FeedInfo                 `gorm:"foreignKey:Version;belongsTo"`
+	LegGroupId           string                    `csv_parse:"leg_group_id" gorm:"default:null"`
+	NetworkId            string                    `csv_parse:"network_id" gorm:"primaryKey"`
+	FromAreaId           string                    `csv_parse:"from_area_id" gorm:"primaryKey"`
+	ToAreaId             string                    `csv_parse:"to_area_id" gorm:"primaryKey"`
+	FromTimeframeGroupId string                    `csv_parse:"from_timeframe_group_id" gorm:"primaryKey"`
+	ToTimeframeGroupId   string                    `csv_parse:"to_timeframe_group_id" gorm:"primaryKey"`
+	FareProductId        string                    `csv_parse:"fare_product_id" gorm:"primaryKey;not null;default:null"`
+	RulePriority         csv_parse.Optional[int32] `csv_parse:"rule_priority"`
+	Extra                map[string]string         `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+// Fares v2, from fare_transfer_rules.txt
+type FareTransferRule struct {
+	Version           string                                `gorm:"primaryKey;not null;default:null"`
+	FeedInfo          *FeedInfo                             `gorm:"foreignKey:Version;belongsTo"`
+	FromLegGroupId    string                                `csv_parse:"from_leg_group_id" gorm:"primaryKey"`
+	ToLegGroupId      string                                `csv_parse:"to_leg_group_id" gorm:"primaryKey"`
+	TransferCount     csv_parse.Optional[int32]             `csv_parse:"transfer_count"` // -1 means unlimited transfers
+	DurationLimit     csv_parse.Optional[int32]             `csv_parse:"duration_limit"` // In seconds. Missing means no limit
+	DurationLimitType csv_parse.Optional[DurationLimitType] `csv_parse:"duration_limit_type"`
+	FareTransferType  FareTransferType                      `csv_parse:"fare_transfer_type"`
+	FareProductId     string                                `csv_parse:"fare_product_id" gorm:"primaryKey"` // Missing means the transfer is free
+	Extra             map[string]string                     `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+// From areas.txt
+type Area struct {
+	Version  string            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo *FeedInfo         `gorm:"foreignKey:Version;belongsTo"`
+	Id       string            `csv_parse:"area_id" gorm:"primaryKey;not null;default:null"`
+	Name     string            `csv_parse:"area_name" gorm:"default:null"`
+	Extra    map[string]string `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+// From stop_areas.txt
+type StopArea struct {
+	Version  string            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo *FeedInfo         `gorm:"foreignKey:Version;belongsTo"`
+	AreaId   string            `csv_parse:"area_id" gorm:"primaryKey;not null;default:null"`
+	StopId   string            `csv_parse:"stop_id" gorm:"primaryKey;not null;default:null"`
+	Extra    map[string]string `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+// From timeframes.txt. Missing start and end times cover the whole day
+type Timeframe struct {
+	Version          string               `gorm:"primaryKey;not null;default:null"`
+	FeedInfo         *FeedInfo            `gorm:"foreignKey:Version;belongsTo"`
+	TimeframeGroupId string               `csv_parse:"timeframe_group_id" gorm:"primaryKey;not null;default:null"`
+	StartTime        ArrivalDepartureTime `csv_parse:"start_time;default:00:00:00" gorm:"primaryKey"`
+	EndTime          ArrivalDepartureTime `csv_parse:"end_time;default:24:00:00" gorm:"primaryKey"`
+	ServiceId        string               `csv_parse:"service_id" gorm:"primaryKey;not null;default:null"`
+	Extra            map[string]string    `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+// From networks.txt
+type Network struct {
+	Version  string            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo *FeedInfo         `gorm:"foreignKey:Version;belongsTo"`
+	Id       string            `csv_parse:"network_id" gorm:"primaryKey;not null;default:null"`
+	Name     string            `csv_parse:"network_name" gorm:"default:null"`
+	Extra    map[string]string `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+// From route_networks.txt. A route belongs to at most one network
+type RouteNetwork struct {
+	Version   string            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo  *FeedInfo         `gorm:"foreignKey:Version;belongsTo"`
+	NetworkId string            `csv_parse:"network_id" gorm:"not null;default:null"`
+	RouteId   string            `csv_parse:"route_id" gorm:"primaryKey;not null;default:null"`
+	Extra     map[string]string `csv_parse:",extra" gorm:"serializer:json"`
+}
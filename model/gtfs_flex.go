@@ -0,0 +1,67 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/samc1213/gtfs-analyze/csv_parse"
+)
+
+// From locations.geojson. A zone where riders can be picked up or dropped off anywhere, used by
+// demand-responsive services
+type Location struct {
+	Version     string                     `gorm:"primaryKey;not null;default:null"`
+	FeedInfo    *FeedInfo                  `gorm:"foreignKey:Version;belongsTo"`
+	Id          string                     `gorm:"primaryKey;not null;default:null"`
+	Name        string                     `gorm:"default:null"`          // The stop_name property
+	Description string                     `gorm:"default:null"`          // The stop_desc property
+	Geometry    string                     `gorm:"not null;default:null"` // The GeoJSON Polygon or MultiPolygon, as it is in the file
+	Extra       map[string]json.RawMessage `gorm:"serializer:json"`       // Any other properties of the feature
+}
+
+// From location_groups.txt. A group of stops where riders can be picked up or dropped off
+type LocationGroup struct {
+	Version  string            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo *FeedInfo         `gorm:"foreignKey:Version;belongsTo"`
+	Id       string            `csv_parse:"location_group_id" gorm:"primaryKey;not null;default:null"`
+	Name     string            `csv_parse:"location_group_name" gorm:"default:null"`
+	Extra    map[string]string `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+// From location_group_stops.txt
+type LocationGroupStop struct {
+	Version         string            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo        *FeedInfo         `gorm:"foreignKey:Version;belongsTo"`
+	LocationGroupId string            `csv_parse:"location_group_id" gorm:"primaryKey;not null;default:null"`
+	StopId          string            `csv_parse:"stop_id" gorm:"primaryKey;not null;default:null"`
+	Extra           map[string]string `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+type BookingType int8
+
+const (
+	RealTimeBooking  BookingType = 0
+	SameDayBooking   BookingType = 1 // Must be booked some time in advance on the same day
+	PriorDaysBooking BookingType = 2 // Must be booked one or more days in advance
+)
+
+// From booking_rules.txt. How riders book a pickup or drop off of a demand-responsive service
+type BookingRule struct {
+	Version                string                                   `gorm:"primaryKey;not null;default:null"`
+	FeedInfo               *FeedInfo                                `gorm:"foreignKey:Version;belongsTo"`
+	Id                     string                                   `csv_parse:"booking_rule_id" gorm:"primaryKey;not null;default:null"`
+	BookingType            BookingType                              `csv_parse:"booking_type"`
+	PriorNoticeDurationMin csv_parse.Optional[int32]                `csv_parse:"prior_notice_duration_min"` // In minutes
+	PriorNoticeDurationMax csv_parse.Optional[int32]                `csv_parse:"prior_notice_duration_max"` // In minutes
+	PriorNoticeLastDay     csv_parse.Optional[int32]                `csv_parse:"prior_notice_last_day"`
+	PriorNoticeLastTime    csv_parse.Optional[ArrivalDepartureTime] `csv_parse:"prior_notice_last_time"`
+	PriorNoticeStartDay    csv_parse.Optional[int32]                `csv_parse:"prior_notice_start_day"`
+	PriorNoticeStartTime   csv_parse.Optional[ArrivalDepartureTime] `csv_parse:"prior_notice_start_time"`
+	PriorNoticeServiceId   string                                   `csv_parse:"prior_notice_service_id" gorm:"default:null"`
+	Message                string                                   `csv_parse:"message" gorm:"default:null"`
+	PickupMessage          string                                   `csv_parse:"pickup_message" gorm:"default:null"`
+	DropOffMessage         string                                   `csv_parse:"drop_off_message" gorm:"default:null"`
+	PhoneNumber            string                                   `csv_parse:"phone_number" gorm:"default:null"`
+	InfoUrl                string                                   `csv_parse:"info_url" gorm:"default:null"`
+	BookingUrl             string                                   `csv_parse:"booking_url" gorm:"default:null"`
+	Extra                  map[string]string                        `csv_parse:",extra" gorm:"serializer:json"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import "github.com/samc1213/gtfs-analyze/csv_parse"
+
+type PathwayMode int8
+
+const (
+	Walkway        PathwayMode = 1
+	Stairs         PathwayMode = 2
+	MovingSidewalk PathwayMode = 3
+	Escalator      PathwayMode = 4
+	Elevator       PathwayMode = 5
+	FareGate       PathwayMode = 6
+	ExitGate       PathwayMode = 7
+)
+
+type PathwayDirection int8
+
+const (
+	Unidirectional PathwayDirection = 0
+	Bidirectional  PathwayDirection = 1
+)
+
+// From levels.txt
+type Level struct {
+	Version  string            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo *FeedInfo         `gorm:"foreignKey:Version;belongsTo"`
+	Id       string            `csv_parse:"level_id" gorm:"primaryKey;not null;default:null"`
+	Index    float64           `csv_parse:"level_index"` // 0 is the ground level, negative levels are underground
+	Name     string            `csv_parse:"level_name" gorm:"default:null"`
+	Extra    map[string]string `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+// From pathways.txt. A pathway links two locations of a station, such as a platform and an entrance
+type Pathway struct {
+	Version              string                      `gorm:"primaryKey;not null;default:null"`
+	FeedInfo             *FeedInfo                   `gorm:"foreignKey:Version;belongsTo"`
+	Id                   string                      `csv_parse:"pathway_id" gorm:"primaryKey;not null;default:null"`
+	FromStopId           string                      `csv_parse:"from_stop_id" gorm:"not null;default:null"`
+	ToStopId             string                      `csv_parse:"to_stop_id" gorm:"not null;default:null"`
+	Mode                 PathwayMode                 `csv_parse:"pathway_mode"`
+	IsBidirectional      PathwayDirection            `csv_parse:"is_bidirectional"`
+	Length               csv_parse.Optional[float64] `csv_parse:"length"`         // In meters
+	TraversalTime        csv_parse.Optional[int32]   `csv_parse:"traversal_time"` // In seconds
+	StairCount           csv_parse.Optional[int32]   `csv_parse:"stair_count"`    // Negative when the stairs go down from from_stop_id
+	MaxSlope             csv_parse.Optional[float64] `csv_parse:"max_slope"`
+	MinWidth             csv_parse.Optional[float64] `csv_parse:"min_width"` // In meters
+	SignpostedAs         string                      `csv_parse:"signposted_as" gorm:"default:null"`
+	ReversedSignpostedAs string                      `csv_parse:"reversed_signposted_as" gorm:"default:null"`
+	Extra                map[string]string           `csv_parse:",extra" gorm:"serializer:json"`
+}
@@ -46,6 +46,8 @@ const (
 )
 
 type VehiclePosition struct {
+	// The feed this update is from, when one database stores many feeds. Empty for a single feed
+	Namespace string `gorm:"primaryKey"`
 	// Feed-unique id for this update
 	Id               string `gorm:"primaryKey;not null;default:null"`
 	MessageTimestamp uint64 `gorm:"index"`
@@ -80,3 +82,117 @@ type VehiclePosition struct {
 	OccupancyPercentage uint32
 	// Omit multicarriagedetails since it is many to one
 }
+
+type StopTimeUpdateScheduleRelationship int8
+
+const (
+	ScheduledStop   StopTimeUpdateScheduleRelationship = 0
+	SkippedStop     StopTimeUpdateScheduleRelationship = 1
+	NoDataStop      StopTimeUpdateScheduleRelationship = 2
+	UnscheduledStop StopTimeUpdateScheduleRelationship = 3
+)
+
+// A predicted arrival or departure. Either Delay or Time is set
+type StopTimeEvent struct {
+	Delay       int32 `json:"delay,omitempty"` // In seconds, relative to the schedule
+	Time        int64 `json:"time,omitempty"`  // Unix time
+	Uncertainty int32 `json:"uncertainty,omitempty"`
+}
+
+type StopTimeUpdate struct {
+	StopSequence         uint32                             `json:"stop_sequence,omitempty"`
+	StopId               string                             `json:"stop_id,omitempty"`
+	Arrival              *StopTimeEvent                     `json:"arrival,omitempty"`
+	Departure            *StopTimeEvent                     `json:"departure,omitempty"`
+	ScheduleRelationship StopTimeUpdateScheduleRelationship `json:"schedule_relationship"`
+}
+
+type TripUpdate struct {
+	Namespace        string `gorm:"primaryKey"`
+	Id               string `gorm:"primaryKey;not null;default:null"`
+	MessageTimestamp uint64 `gorm:"index"`
+	// Start Trip Object
+	TripId               string
+	RouteId              string
+	DirectionId          DirectionId
+	StartTime            ArrivalDepartureTime
+	StartDate            time.Time
+	ScheduleRelationship ScheduleRelationship
+	// End Trip Object
+	VehicleId       string `gorm:"default:null"`
+	Timestamp       uint64
+	Delay           int32            // In seconds. Only used for stops without a StopTimeUpdate
+	StopTimeUpdates []StopTimeUpdate `gorm:"serializer:json"`
+}
+
+type AlertCause int8
+
+const (
+	UnknownCause     AlertCause = 1
+	OtherCause       AlertCause = 2
+	TechnicalProblem AlertCause = 3
+	Strike           AlertCause = 4
+	Demonstration    AlertCause = 5
+	Accident         AlertCause = 6
+	Holiday          AlertCause = 7
+	Weather          AlertCause = 8
+	Maintenance      AlertCause = 9
+	Construction     AlertCause = 10
+	PoliceActivity   AlertCause = 11
+	MedicalEmergency AlertCause = 12
+)
+
+type AlertEffect int8
+
+const (
+	NoService          AlertEffect = 1
+	ReducedService     AlertEffect = 2
+	SignificantDelays  AlertEffect = 3
+	Detour             AlertEffect = 4
+	AdditionalService  AlertEffect = 5
+	ModifiedService    AlertEffect = 6
+	OtherEffect        AlertEffect = 7
+	UnknownEffect      AlertEffect = 8
+	StopMoved          AlertEffect = 9
+	NoEffect           AlertEffect = 10
+	AccessibilityIssue AlertEffect = 11
+)
+
+type AlertSeverityLevel int8
+
+const (
+	UnknownSeverity AlertSeverityLevel = 1
+	InfoSeverity    AlertSeverityLevel = 2
+	WarningSeverity AlertSeverityLevel = 3
+	SevereSeverity  AlertSeverityLevel = 4
+)
+
+// Unix times. A missing start or end is 0
+type AlertActivePeriod struct {
+	Start uint64 `json:"start,omitempty"`
+	End   uint64 `json:"end,omitempty"`
+}
+
+// The agency, route, trip or stop an alert is about
+type AlertInformedEntity struct {
+	AgencyId  string `json:"agency_id,omitempty"`
+	RouteId   string `json:"route_id,omitempty"`
+	RouteType *int32 `json:"route_type,omitempty"`
+	TripId    string `json:"trip_id,omitempty"`
+	StopId    string `json:"stop_id,omitempty"`
+}
+
+type Alert struct {
+	Namespace        string                `gorm:"primaryKey"`
+	Id               string                `gorm:"primaryKey;not null;default:null"`
+	MessageTimestamp uint64                `gorm:"index"`
+	ActivePeriods    []AlertActivePeriod   `gorm:"serializer:json"`
+	InformedEntities []AlertInformedEntity `gorm:"serializer:json"`
+	Cause            AlertCause
+	Effect           AlertEffect
+	SeverityLevel    AlertSeverityLevel
+	// Translated texts by language. A text without a language is stored under ""
+	Url             map[string]string `gorm:"serializer:json"`
+	HeaderText      map[string]string `gorm:"serializer:json"`
+	DescriptionText map[string]string `gorm:"serializer:json"`
+}
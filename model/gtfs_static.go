@@ -2,15 +2,19 @@ package model
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 	"strconv"
 	"time"
+
+	"github.com/samc1213/gtfs-analyze/csv_parse"
 )
 
 // See https://gtfs.org/schedule/reference for reference
 // This model is meant to be a direct copy of the GTFS reference schema,
 // except that each entity has a Version tag. This allows us to keep multiple versions
-// of a given GTFS schema in the same SQL tables
+// of a given GTFS schema in the same SQL tables. Columns that aren't in the model, such as
+// agency-specific extensions, are kept in each entity's Extra map and stored as JSON
 
 type RouteType int8
 
@@ -79,53 +83,58 @@ const (
 )
 
 type Agency struct {
-	Version  string    `gorm:"primaryKey;not null;default:null"`
-	FeedInfo *FeedInfo `gorm:"foreignKey:Version;belongsTo"`
-	Id       string    `csv_parse:"agency_id" gorm:"primaryKey;not null;default:null"`
-	Name     string    `csv_parse:"agency_name" gorm:"default:null"`
-	Url      string    `csv_parse:"agency_url" gorm:"default:null"`
-	Timezone string    `csv_parse:"agency_timezone" gorm:"default:null"`
-	Language string    `csv_parse:"agency_lang" gorm:"default:null"`
-	Phone    string    `csv_parse:"agency_phone" gorm:"default:null"`
-	FareUrl  string    `csv_parse:"agency_fare_url" gorm:"default:null"`
-	Email    string    `csv_parse:"agency_email" gorm:"default:null"`
+	Version  string            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo *FeedInfo         `gorm:"foreignKey:Version;belongsTo"`
+	Id       string            `csv_parse:"agency_id" gorm:"primaryKey;not null;default:null"`
+	Name     string            `csv_parse:"agency_name" gorm:"default:null"`
+	Url      string            `csv_parse:"agency_url" gorm:"default:null"`
+	Timezone string            `csv_parse:"agency_timezone" gorm:"default:null"`
+	Language string            `csv_parse:"agency_lang" gorm:"default:null"`
+	Phone    string            `csv_parse:"agency_phone" gorm:"default:null"`
+	FareUrl  string            `csv_parse:"agency_fare_url" gorm:"default:null"`
+	Email    string            `csv_parse:"agency_email" gorm:"default:null"`
+	Extra    map[string]string `csv_parse:",extra" gorm:"serializer:json"`
 }
 
 type Stop struct {
-	Version            string       `gorm:"primaryKey;not null;default:null"`
-	FeedInfo           *FeedInfo    `gorm:"foreignKey:Version;belongsTo"`
-	Id                 string       `csv_parse:"stop_id" gorm:"primaryKey;not null;default:null"`
-	Code               string       `csv_parse:"stop_code" gorm:"default:null"`
-	Name               string       `csv_parse:"stop_name" gorm:"default:null"`
-	TtsName            string       `csv_parse:"tts_stop_name" gorm:"default:null"`
-	Description        string       `csv_parse:"stop_desc" gorm:"default:null"`
-	Latitude           float64      `csv_parse:"stop_lat"` // Use 64 bits to provide better native support for PostGIS, etc. However 32 bits provides plenty of precision
-	Longitude          float64      `csv_parse:"stop_lon"` // Use 64 bits to provide better native support for PostGIS, etc. However 32 bits provides plenty of precision
-	ZoneId             string       `csv_parse:"zone_id" gorm:"default:null"`
-	Url                string       `csv_parse:"stop_url" gorm:"default:null"`
-	LocationType       LocationType `csv_parse:"location_type"`
-	ParentStationId    string       `csv_parse:"parent_station" gorm:"default:null"`
-	ParentStation      *Stop        `gorm:"foreignKey:ParentStationId"`
-	Timezone           string       `csv_parse:"stop_timezone" gorm:"default:null"`
-	WheelchairBoarding int8         `csv_parse:"wheelchair_boarding"`
+	Version            string                      `gorm:"primaryKey;not null;default:null"`
+	FeedInfo           *FeedInfo                   `gorm:"foreignKey:Version;belongsTo"`
+	Id                 string                      `csv_parse:"stop_id" gorm:"primaryKey;not null;default:null"`
+	Code               string                      `csv_parse:"stop_code" gorm:"default:null"`
+	Name               string                      `csv_parse:"stop_name" gorm:"default:null"`
+	TtsName            string                      `csv_parse:"tts_stop_name" gorm:"default:null"`
+	Description        string                      `csv_parse:"stop_desc" gorm:"default:null"`
+	Latitude           csv_parse.Optional[float64] `csv_parse:"stop_lat"` // Use 64 bits to provide better native support for PostGIS, etc. However 32 bits provides plenty of precision
+	Longitude          csv_parse.Optional[float64] `csv_parse:"stop_lon"` // Use 64 bits to provide better native support for PostGIS, etc. However 32 bits provides plenty of precision
+	ZoneId             string                      `csv_parse:"zone_id" gorm:"default:null"`
+	Url                string                      `csv_parse:"stop_url" gorm:"default:null"`
+	LocationType       LocationType                `csv_parse:"location_type"`
+	ParentStationId    string                      `csv_parse:"parent_station" gorm:"default:null"`
+	ParentStation      *Stop                       `gorm:"foreignKey:ParentStationId"`
+	Timezone           string                      `csv_parse:"stop_timezone" gorm:"default:null"`
+	WheelchairBoarding int8                        `csv_parse:"wheelchair_boarding"`
+	LevelId            string                      `csv_parse:"level_id" gorm:"default:null"`
+	PlatformCode       string                      `csv_parse:"platform_code" gorm:"default:null"`
+	Extra              map[string]string           `csv_parse:",extra" gorm:"serializer:json"`
 }
 
 type Route struct {
-	Version           string                  `gorm:"primaryKey;not null;default:null"`
-	FeedInfo          *FeedInfo               `gorm:"foreignKey:Version;belongsTo"`
-	Id                string                  `csv_parse:"route_id" gorm:"primaryKey;not null;default:null"`
-	AgencyId          string                  `csv_parse:"agency_id" gorm:"default:null"`
-	ShortName         string                  `csv_parse:"route_short_name" gorm:"default:null"`
-	LongName          string                  `csv_parse:"route_long_name" gorm:"default:null"`
-	Description       string                  `csv_parse:"route_desc" gorm:"default:null"`
-	Type              RouteType               `csv_parse:"route_type"`
-	Url               string                  `csv_parse:"route_url" gorm:"default:null"`
-	Color             string                  `csv_parse:"route_color" gorm:"default:null"`
-	TextColor         string                  `csv_parse:"route_text_color" gorm:"default:null"`
-	SortOrder         int32                   `csv_parse:"route_sort_order"`
-	ContinuousPickup  ContinuousPickupDropoff `csv_parse:"continuous_pickup"`
-	ContinuousDropoff ContinuousPickupDropoff `csv_parse:"continuous_drop_off"`
-	NetworkId         string                  `csv_parse:"network_id"`
+	Version           string                    `gorm:"primaryKey;not null;default:null"`
+	FeedInfo          *FeedInfo                 `gorm:"foreignKey:Version;belongsTo"`
+	Id                string                    `csv_parse:"route_id" gorm:"primaryKey;not null;default:null"`
+	AgencyId          string                    `csv_parse:"agency_id" gorm:"default:null"`
+	ShortName         string                    `csv_parse:"route_short_name" gorm:"default:null"`
+	LongName          string                    `csv_parse:"route_long_name" gorm:"default:null"`
+	Description       string                    `csv_parse:"route_desc" gorm:"default:null"`
+	Type              RouteType                 `csv_parse:"route_type"`
+	Url               string                    `csv_parse:"route_url" gorm:"default:null"`
+	Color             string                    `csv_parse:"route_color" gorm:"default:null"`
+	TextColor         string                    `csv_parse:"route_text_color" gorm:"default:null"`
+	SortOrder         csv_parse.Optional[int32] `csv_parse:"route_sort_order"`
+	ContinuousPickup  ContinuousPickupDropoff   `csv_parse:"continuous_pickup;default:1"`
+	ContinuousDropoff ContinuousPickupDropoff   `csv_parse:"continuous_drop_off;default:1"`
+	NetworkId         string                    `csv_parse:"network_id"`
+	Extra             map[string]string         `csv_parse:",extra" gorm:"serializer:json"`
 }
 
 type Trip struct {
@@ -134,14 +143,27 @@ type Trip struct {
 	Id                   string    `csv_parse:"trip_id" gorm:"primaryKey;not null;default:null"`
 	RouteId              string    `csv_parse:"route_id"`
 	Route                *Route
-	ServiceId            string               `csv_parse:"service_id" gorm:"default: null"`
-	Headsign             string               `csv_parse:"trip_headsign" gorm:"default: null"`
-	ShortName            string               `csv_parse:"trip_short_name" gorm:"default: null"`
-	DirectionId          DirectionId          `csv_parse:"direction_id"`
-	BlockId              string               `csv_parse:"block_id"`
-	ShapeId              string               `csv_parse:"shape_id"`
-	WheelchairAccessible WheelchairAccessible `csv_parse:"wheelchair_accessible"`
-	BikesAllowed         BikesAllowed         `csv_parse:"bikes_allowed"`
+	ServiceId            string                          `csv_parse:"service_id" gorm:"default: null"`
+	Headsign             string                          `csv_parse:"trip_headsign" gorm:"default: null"`
+	ShortName            string                          `csv_parse:"trip_short_name" gorm:"default: null"`
+	DirectionId          csv_parse.Optional[DirectionId] `csv_parse:"direction_id"`
+	BlockId              string                          `csv_parse:"block_id"`
+	ShapeId              string                          `csv_parse:"shape_id"`
+	WheelchairAccessible WheelchairAccessible            `csv_parse:"wheelchair_accessible"`
+	BikesAllowed         BikesAllowed                    `csv_parse:"bikes_allowed"`
+	Extra                map[string]string               `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+// From shapes.txt. One point of the path a vehicle travels, in the order of Sequence
+type ShapePoint struct {
+	Version      string                      `gorm:"primaryKey;not null;default:null"`
+	FeedInfo     *FeedInfo                   `gorm:"foreignKey:Version;belongsTo"`
+	ShapeId      string                      `csv_parse:"shape_id" gorm:"primaryKey;not null;default:null"`
+	Latitude     float64                     `csv_parse:"shape_pt_lat"`
+	Longitude    float64                     `csv_parse:"shape_pt_lon"`
+	Sequence     int32                       `csv_parse:"shape_pt_sequence" gorm:"primaryKey;not null;default:null"`
+	DistTraveled csv_parse.Optional[float64] `csv_parse:"shape_dist_traveled"` // In the units of stop_times.txt's shape_dist_traveled
+	Extra        map[string]string           `csv_parse:",extra" gorm:"serializer:json"`
 }
 
 // Store arrival and departure times as "seconds after midnight", to handle
@@ -183,6 +205,16 @@ func (custom *ArrivalDepartureTime) ConvertFromCsv(input string) error {
 	return nil
 }
 
+// Write the time back out in the HH:MM:SS format used by GTFS, allowing hours past 24
+func (custom ArrivalDepartureTime) ConvertToCsv() (string, error) {
+	seconds := int(custom)
+	hours := seconds / (HOURS_TO_MINUTES * MINUTES_TO_SECONDS)
+	seconds -= hours * HOURS_TO_MINUTES * MINUTES_TO_SECONDS
+	minutes := seconds / MINUTES_TO_SECONDS
+	seconds -= minutes * MINUTES_TO_SECONDS
+	return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds), nil
+}
+
 func NewArrivalTime(date time.Time) ArrivalDepartureTime {
 	year, month, day := date.Date()
 	var baseTime time.Time
@@ -196,19 +228,28 @@ func NewArrivalTime(date time.Time) ArrivalDepartureTime {
 }
 
 type StopTime struct {
-	Version          string                  `gorm:"primaryKey;not null;default:null"`
-	FeedInfo         *FeedInfo               `gorm:"foreignKey:Version;belongsTo"`
-	TripId           string                  `csv_parse:"trip_id" gorm:"primaryKey;not null;default:null"`
-	Trip             *Trip                   `gorm:"foreignKey:trip_id"`
-	ArrivalTime      ArrivalDepartureTime    `csv_parse:"arrival_time" gorm:"default:null"`
-	DepartureTime    ArrivalDepartureTime    `csv_parse:"departure_time" gorm:"default:null"`
-	StopId           string                  `csv_parse:"stop_id" gorm:"not null;default:null"`
-	Stop             *Stop                   `gorm:"foreignKey:stop_id"`
-	StopSequence     int32                   `csv_parse:"stop_sequence" gorm:"primaryKey;not null;default:null"`
-	StopHeadsign     string                  `csv_parse:"stop_headsign" gorm:"default:null"`
-	PickupType       PickupDropoffType       `csv_parse:"pickup_type;default:0"`
-	DropoffType      PickupDropoffType       `csv_parse:"dropoff_type;default:0"`
-	ContinuousPickup ContinuousPickupDropoff `csv_parse:"continuous_pickup"`
+	Version           string                                      `gorm:"primaryKey;not null;default:null"`
+	FeedInfo          *FeedInfo                                   `gorm:"foreignKey:Version;belongsTo"`
+	TripId            string                                      `csv_parse:"trip_id" gorm:"primaryKey;not null;default:null"`
+	Trip              *Trip                                       `gorm:"foreignKey:trip_id"`
+	ArrivalTime       csv_parse.Optional[ArrivalDepartureTime]    `csv_parse:"arrival_time"` // Only required for the first and last stops, and timepoints
+	DepartureTime     csv_parse.Optional[ArrivalDepartureTime]    `csv_parse:"departure_time"`
+	StopId            string                                      `csv_parse:"stop_id" gorm:"default:null"` // Empty when the stop time is for a location group or location
+	Stop              *Stop                                       `gorm:"foreignKey:stop_id"`
+	LocationGroupId   string                                      `csv_parse:"location_group_id" gorm:"default:null"`
+	LocationId        string                                      `csv_parse:"location_id" gorm:"default:null"`
+	StopSequence      int32                                       `csv_parse:"stop_sequence" gorm:"primaryKey;not null;default:null"`
+	StopHeadsign      string                                      `csv_parse:"stop_headsign" gorm:"default:null"`
+	PickupType        PickupDropoffType                           `csv_parse:"pickup_type;default:0"`
+	DropoffType       PickupDropoffType                           `csv_parse:"drop_off_type;default:0"`
+	ContinuousPickup  csv_parse.Optional[ContinuousPickupDropoff] `csv_parse:"continuous_pickup"`   // When missing, the route's continuous_pickup applies
+	ShapeDistTraveled csv_parse.Optional[float64]                 `csv_parse:"shape_dist_traveled"` // How far along the trip's shape the stop is, in the units of shapes.txt
+	// Demand-responsive stop times have a window when riders can be picked up or dropped off, instead of arrival and departure times
+	StartPickupDropOffWindow csv_parse.Optional[ArrivalDepartureTime] `csv_parse:"start_pickup_drop_off_window"`
+	EndPickupDropOffWindow   csv_parse.Optional[ArrivalDepartureTime] `csv_parse:"end_pickup_drop_off_window"`
+	PickupBookingRuleId      string                                   `csv_parse:"pickup_booking_rule_id" gorm:"default:null"`
+	DropOffBookingRuleId     string                                   `csv_parse:"drop_off_booking_rule_id" gorm:"default:null"`
+	Extra                    map[string]string                        `csv_parse:",extra" gorm:"serializer:json"`
 }
 
 type ServiceAvailable int8
@@ -219,18 +260,86 @@ const (
 )
 
 type Calendar struct {
-	Version   string           `gorm:"primaryKey;not null;default:null"`
-	FeedInfo  *FeedInfo        `gorm:"foreignKey:Version;belongsTo"`
-	ServiceId string           `csv_parse:"service_id" gorm:"primaryKey;not null;default:null"`
-	Monday    ServiceAvailable `csv_parse:"monday" gorm:"not null"`
-	Tuesday   ServiceAvailable `csv_parse:"tuesday" gorm:"not null"`
-	Wednesday ServiceAvailable `csv_parse:"wednesday" gorm:"not null"`
-	Thursday  ServiceAvailable `csv_parse:"thursday" gorm:"not null"`
-	Friday    ServiceAvailable `csv_parse:"friday" gorm:"not null"`
-	Saturday  ServiceAvailable `csv_parse:"saturday" gorm:"not null"`
-	Sunday    ServiceAvailable `csv_parse:"sunday" gorm:"not null"`
-	StartDate time.Time        `csv_parse:"start_date;timeLayout:20060102" gorm:"not null;default:null"`
-	EndDate   time.Time        `csv_parse:"end_date;timeLayout:20060102" gorm:"not null;default:null"`
+	Version   string            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo  *FeedInfo         `gorm:"foreignKey:Version;belongsTo"`
+	ServiceId string            `csv_parse:"service_id" gorm:"primaryKey;not null;default:null"`
+	Monday    ServiceAvailable  `csv_parse:"monday" gorm:"not null"`
+	Tuesday   ServiceAvailable  `csv_parse:"tuesday" gorm:"not null"`
+	Wednesday ServiceAvailable  `csv_parse:"wednesday" gorm:"not null"`
+	Thursday  ServiceAvailable  `csv_parse:"thursday" gorm:"not null"`
+	Friday    ServiceAvailable  `csv_parse:"friday" gorm:"not null"`
+	Saturday  ServiceAvailable  `csv_parse:"saturday" gorm:"not null"`
+	Sunday    ServiceAvailable  `csv_parse:"sunday" gorm:"not null"`
+	StartDate time.Time         `csv_parse:"start_date;timeLayout:20060102" gorm:"not null;default:null"`
+	EndDate   time.Time         `csv_parse:"end_date;timeLayout:20060102" gorm:"not null;default:null"`
+	Extra     map[string]string `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+type TransferType int8
+
+const (
+	RecommendedTransfer      TransferType = 0
+	TimedTransfer            TransferType = 1
+	MinimumTimeTransfer      TransferType = 2
+	TransferNotPossible      TransferType = 3
+	InSeatTransfer           TransferType = 4
+	InSeatTransferNotAllowed TransferType = 5
+)
+
+// From transfers.txt. A transfer can be between stops, routes or trips, so every id column is
+// part of the key, and any of them can be empty
+type Transfer struct {
+	Version         string                    `gorm:"primaryKey;not null;default:null"`
+	FeedInfo        *FeedInfo                 `gorm:"foreignKey:Version;belongsTo"`
+	FromStopId      string                    `csv_parse:"from_stop_id" gorm:"primaryKey"`
+	ToStopId        string                    `csv_parse:"to_stop_id" gorm:"primaryKey"`
+	FromRouteId     string                    `csv_parse:"from_route_id" gorm:"primaryKey"`
+	ToRouteId       string                    `csv_parse:"to_route_id" gorm:"primaryKey"`
+	FromTripId      string                    `csv_parse:"from_trip_id" gorm:"primaryKey"`
+	ToTripId        string                    `csv_parse:"to_trip_id" gorm:"primaryKey"`
+	TransferType    TransferType              `csv_parse:"transfer_type"`
+	MinTransferTime csv_parse.Optional[int32] `csv_parse:"min_transfer_time"` // In seconds
+	Extra           map[string]string         `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+// From translations.txt. A translation applies either to one record, by record_id and record_sub_id,
+// or to every record whose field has the field_value
+type Translation struct {
+	Version     string            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo    *FeedInfo         `gorm:"foreignKey:Version;belongsTo"`
+	TableName   string            `csv_parse:"table_name" gorm:"primaryKey;not null;default:null"`
+	FieldName   string            `csv_parse:"field_name" gorm:"primaryKey;not null;default:null"`
+	Language    string            `csv_parse:"language" gorm:"primaryKey;not null;default:null"`
+	Translation string            `csv_parse:"translation" gorm:"not null;default:null"`
+	RecordId    string            `csv_parse:"record_id" gorm:"primaryKey"`
+	RecordSubId string            `csv_parse:"record_sub_id" gorm:"primaryKey"`
+	FieldValue  string            `csv_parse:"field_value" gorm:"primaryKey"`
+	Extra       map[string]string `csv_parse:",extra" gorm:"serializer:json"`
+}
+
+type AttributionRole int8
+
+const (
+	DoesNotHaveRole AttributionRole = 0
+	HasRole         AttributionRole = 1
+)
+
+// From attributions.txt. An attribution without an agency, route or trip applies to the whole feed
+type Attribution struct {
+	Version          string            `gorm:"primaryKey;not null;default:null"`
+	FeedInfo         *FeedInfo         `gorm:"foreignKey:Version;belongsTo"`
+	Id               string            `csv_parse:"attribution_id" gorm:"primaryKey"`
+	AgencyId         string            `csv_parse:"agency_id" gorm:"primaryKey"`
+	RouteId          string            `csv_parse:"route_id" gorm:"primaryKey"`
+	TripId           string            `csv_parse:"trip_id" gorm:"primaryKey"`
+	OrganizationName string            `csv_parse:"organization_name" gorm:"primaryKey;not null;default:null"`
+	IsProducer       AttributionRole   `csv_parse:"is_producer"`
+	IsOperator       AttributionRole   `csv_parse:"is_operator"`
+	IsAuthority      AttributionRole   `csv_parse:"is_authority"`
+	Url              string            `csv_parse:"attribution_url" gorm:"default:null"`
+	Email            string            `csv_parse:"attribution_email" gorm:"default:null"`
+	Phone            string            `csv_parse:"attribution_phone" gorm:"default:null"`
+	Extra            map[string]string `csv_parse:",extra" gorm:"serializer:json"`
 }
 
 type FeedInfo struct {
@@ -243,20 +352,43 @@ type FeedInfo struct {
 	// While Version is optional, and the entire FeedInfo file is optional, this application
 	// will generate a version if it is not included in the feed, in order to track changes to
 	// the GTFS feed and save all historical versions throughout time
-	Version      string    `csv_parse:"feed_version" gorm:"unique;primaryKey;not null;default:null"`
-	DownloadTime time.Time `gorm:"default:null;not null"`
-	ContactEmail string    `csv_parse:"feed_contact_email" gorm:"default:null"`
-	ContactUrl   string    `csv_parse:"feed_contact_url" gorm:"default:null"`
+	Version      string            `csv_parse:"feed_version" gorm:"unique;primaryKey;not null;default:null"`
+	DownloadTime time.Time         `gorm:"default:null;not null"`
+	Namespace    string            `gorm:"index;default:null"` // The feed this version is from, when one database stores many feeds
+	ContactEmail string            `csv_parse:"feed_contact_email" gorm:"default:null"`
+	ContactUrl   string            `csv_parse:"feed_contact_url" gorm:"default:null"`
+	Extra        map[string]string `csv_parse:",extra" gorm:"serializer:json"`
 }
 
 type GtfsStaticFeed struct {
-	Agency   []Agency
-	Stop     []Stop
-	Route    []Route
-	Trip     []Trip
-	StopTime []StopTime
-	Calendar []Calendar
-	FeedInfo FeedInfo
+	Agency            []Agency
+	Stop              []Stop
+	Route             []Route
+	Trip              []Trip
+	StopTime          []StopTime
+	ShapePoint        []ShapePoint
+	Calendar          []Calendar
+	Transfer          []Transfer
+	Level             []Level
+	Pathway           []Pathway
+	Translation       []Translation
+	Attribution       []Attribution
+	Location          []Location
+	LocationGroup     []LocationGroup
+	LocationGroupStop []LocationGroupStop
+	BookingRule       []BookingRule
+	FareAttribute     []FareAttribute
+	FareRule          []FareRule
+	FareMedia         []FareMedia
+	FareProduct       []FareProduct
+	FareLegRule       []FareLegRule
+	FareTransferRule  []FareTransferRule
+	Area              []Area
+	StopArea          []StopArea
+	Timeframe         []Timeframe
+	Network           []Network
+	RouteNetwork      []RouteNetwork
+	FeedInfo          FeedInfo
 }
 
 func GetAllModels() []interface{} {
@@ -266,8 +398,35 @@ func GetAllModels() []interface{} {
 		&Route{},
 		&Trip{},
 		&StopTime{},
+		&ShapePoint{},
 		&Calendar{},
+		&Transfer{},
+		&Level{},
+		&Pathway{},
+		&Translation{},
+		&Attribution{},
+		&Location{},
+		&LocationGroup{},
+		&LocationGroupStop{},
+		&BookingRule{},
+		&FareAttribute{},
+		&FareRule{},
+		&FareMedia{},
+		&FareProduct{},
+		&FareLegRule{},
+		&FareTransferRule{},
+		&Area{},
+		&StopArea{},
+		&Timeframe{},
+		&Network{},
+		&RouteNetwork{},
 		&FeedInfo{},
 		&VehiclePosition{},
+		&TripUpdate{},
+		&Alert{},
+		&FetchLog{},
+		&StaticFetchState{},
+		&RawFeedMessage{},
+		&RawPayload{},
 	}
 }
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// A GTFS-RT FeedMessage archived exactly as it was fetched, so it can be ingested again by the replay
+// command. A message whose payload is the same as one already archived for the feed isn't archived again
+type RawFeedMessage struct {
+	Id              uint      `gorm:"primaryKey"`
+	Namespace       string    `gorm:"uniqueIndex:idx_raw_feed_message"`
+	EntityType      string    `gorm:"uniqueIndex:idx_raw_feed_message;not null;default:null"` // vehicle_positions, trip_updates or alerts
+	PayloadHash     string    `gorm:"uniqueIndex:idx_raw_feed_message;not null;default:null"` // Hex SHA-256 of the payload
+	HeaderTimestamp uint64    `gorm:"index"`
+	FetchTime       time.Time `gorm:"not null;default:null"`
+	Size            int       // Of the payload before compression
+}
+
+// The gzipped payload of archived messages, stored once however many messages have it. Only used when
+// the archive isn't in a directory
+type RawPayload struct {
+	Hash string `gorm:"primaryKey"`
+	Data []byte `gorm:"not null"`
+}
@@ -0,0 +1,220 @@
+package parquet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+
+	pq "github.com/parquet-go/parquet-go"
+)
+
+// The type of a column's values, and how they are stored in the file
+type Type int8
+
+const (
+	Boolean Type = iota
+	Int32
+	Int64
+	Float
+	Double
+	String
+	Date      // A time.Time's date, stored as days since 1970-01-01
+	Timestamp // A time.Time, stored as milliseconds since the Unix epoch
+)
+
+func (t Type) String() string {
+	switch t {
+	case Boolean:
+		return "Boolean"
+	case Int32:
+		return "Int32"
+	case Int64:
+		return "Int64"
+	case Float:
+		return "Float"
+	case Double:
+		return "Double"
+	case String:
+		return "String"
+	case Date:
+		return "Date"
+	case Timestamp:
+		return "Timestamp"
+	}
+	return fmt.Sprintf("Type(%d)", t)
+}
+
+func (t Type) node() pq.Node {
+	switch t {
+	case Boolean:
+		return pq.Leaf(pq.BooleanType)
+	case Int32:
+		return pq.Int(32)
+	case Int64:
+		return pq.Int(64)
+	case Float:
+		return pq.Leaf(pq.FloatType)
+	case Double:
+		return pq.Leaf(pq.DoubleType)
+	case String:
+		return pq.String()
+	case Date:
+		return pq.Date()
+	}
+	return pq.Timestamp(pq.Millisecond)
+}
+
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool // Whether the column can hold nulls
+}
+
+// How many rows are buffered in memory before they are written out as a row group
+const rowGroupSize = 100000
+
+// Writes rows to a Parquet file with github.com/parquet-go/parquet-go, gzipping each column. The
+// file is only complete once Close is called
+type Writer struct {
+	writer  *pq.Writer
+	columns []Column
+	// The index of each column in the file, where they are ordered by name
+	fileColumnIdxs []int
+	row            pq.Row // In the order of the file's columns
+}
+
+func NewWriter(output io.Writer, columns []Column) (*Writer, error) {
+	group := make(pq.Group, len(columns))
+	for _, column := range columns {
+		if column.Name == "" {
+			return nil, errors.New("every column needs a name")
+		}
+		if _, ok := group[column.Name]; ok {
+			return nil, fmt.Errorf("more than one column is named %s", column.Name)
+		}
+		if column.Type < Boolean || column.Type > Timestamp {
+			return nil, fmt.Errorf("column %s has unknown type %s", column.Name, column.Type)
+		}
+		node := column.Type.node()
+		if column.Optional {
+			node = pq.Optional(node)
+		}
+		group[column.Name] = pq.Compressed(node, &pq.Gzip)
+	}
+	schema := pq.NewSchema("schema", group)
+
+	fileColumnIdxs := make([]int, len(columns))
+	for i, column := range columns {
+		leaf, _ := schema.Lookup(column.Name)
+		fileColumnIdxs[i] = leaf.ColumnIndex
+	}
+	return &Writer{
+		writer:         pq.NewWriter(output, schema, pq.MaxRowsPerRowGroup(rowGroupSize), pq.CreatedBy("gtfs-analyze", "", "")),
+		columns:        columns,
+		fileColumnIdxs: fileColumnIdxs,
+		row:            make(pq.Row, len(columns)),
+	}, nil
+}
+
+// Writes a row with a value for each column, in order. A value can be nil, or a nil pointer, in an
+// optional column. Otherwise it must be, or point to, a bool for Boolean columns, an integer for Int32
+// and Int64 columns, a float for Float and Double columns, a string or []byte for String columns and
+// a time.Time for Date and Timestamp columns. Named types, like enums, are written as their
+// underlying type
+func (writer *Writer) WriteRow(values []interface{}) error {
+	if len(values) != len(writer.columns) {
+		return fmt.Errorf("row has %d values, but there are %d columns", len(values), len(writer.columns))
+	}
+	for i, column := range writer.columns {
+		value, err := getValue(column, values[i])
+		if err != nil {
+			return fmt.Errorf("column %s: %w", column.Name, err)
+		}
+		definitionLevel := 0
+		if column.Optional && !value.IsNull() {
+			definitionLevel = 1
+		}
+		writer.row[writer.fileColumnIdxs[i]] = value.Level(0, definitionLevel, writer.fileColumnIdxs[i])
+	}
+	_, err := writer.writer.WriteRows([]pq.Row{writer.row})
+	return err
+}
+
+// Converts a value for a column, or returns a null value for nil
+func getValue(column Column, value interface{}) (pq.Value, error) {
+	reflected := reflect.ValueOf(value)
+	for reflected.Kind() == reflect.Pointer && !reflected.IsNil() {
+		reflected = reflected.Elem()
+	}
+	if !reflected.IsValid() || reflected.Kind() == reflect.Pointer {
+		if !column.Optional {
+			return pq.Value{}, errors.New("null value in a column that isn't optional")
+		}
+		return pq.NullValue(), nil
+	}
+
+	wrongType := fmt.Errorf("can't write %s as %s", reflected.Type(), column.Type)
+	switch column.Type {
+	case Boolean:
+		if reflected.Kind() != reflect.Bool {
+			return pq.Value{}, wrongType
+		}
+		return pq.BooleanValue(reflected.Bool()), nil
+	case Int32, Int64:
+		var integer int64
+		switch reflected.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			integer = reflected.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if reflected.Uint() > math.MaxInt64 {
+				return pq.Value{}, fmt.Errorf("%d is too large for %s", reflected.Uint(), column.Type)
+			}
+			integer = int64(reflected.Uint())
+		default:
+			return pq.Value{}, wrongType
+		}
+		if column.Type == Int64 {
+			return pq.Int64Value(integer), nil
+		}
+		if integer < math.MinInt32 || integer > math.MaxInt32 {
+			return pq.Value{}, fmt.Errorf("%d is too large for %s", integer, column.Type)
+		}
+		return pq.Int32Value(int32(integer)), nil
+	case Float, Double:
+		if reflected.Kind() != reflect.Float32 && reflected.Kind() != reflect.Float64 {
+			return pq.Value{}, wrongType
+		}
+		if column.Type == Double {
+			return pq.DoubleValue(reflected.Float()), nil
+		}
+		return pq.FloatValue(float32(reflected.Float())), nil
+	case String:
+		switch {
+		case reflected.Kind() == reflect.String:
+			return pq.ByteArrayValue([]byte(reflected.String())), nil
+		case reflected.Kind() == reflect.Slice && reflected.Type().Elem().Kind() == reflect.Uint8:
+			return pq.ByteArrayValue(reflected.Bytes()), nil
+		}
+		return pq.Value{}, wrongType
+	case Date, Timestamp:
+		timeValue, ok := reflected.Interface().(time.Time)
+		if !ok {
+			return pq.Value{}, wrongType
+		}
+		if column.Type == Timestamp {
+			return pq.Int64Value(timeValue.UnixMilli()), nil
+		}
+		year, month, day := timeValue.Date()
+		days := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+		return pq.Int32Value(int32(days)), nil
+	}
+	return pq.Value{}, wrongType
+}
+
+// Writes the remaining rows and the file's metadata. Doesn't close the output
+func (writer *Writer) Close() error {
+	return writer.writer.Close()
+}
@@ -0,0 +1,115 @@
+package parquet
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	pq "github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// Reads every row of a file, with each row's values by column name
+func readRows(t *testing.T, file []byte) (*pq.Schema, []map[string]pq.Value) {
+	reader := pq.NewReader(bytes.NewReader(file))
+	defer reader.Close()
+	schema := reader.Schema()
+	var rows []map[string]pq.Value
+	buffer := make([]pq.Row, 10)
+	for {
+		n, err := reader.ReadRows(buffer)
+		for _, row := range buffer[:n] {
+			values := make(map[string]pq.Value)
+			for _, value := range row {
+				values[schema.Columns()[value.Column()][0]] = value.Clone()
+			}
+			rows = append(rows, values)
+		}
+		if err == io.EOF {
+			return schema, rows
+		}
+		assert.NoError(t, err)
+	}
+}
+
+func TestWriteRows(t *testing.T) {
+	columns := []Column{
+		{Name: "stop_id", Type: String},
+		{Name: "stop_sequence", Type: Int32},
+		{Name: "position_timestamp", Type: Int64},
+		{Name: "bearing", Type: Float, Optional: true},
+		{Name: "latitude", Type: Double},
+		{Name: "timepoint", Type: Boolean, Optional: true},
+		{Name: "service_date", Type: Date},
+		{Name: "arrival", Type: Timestamp, Optional: true},
+	}
+	type routeType int8
+	arrival := time.Date(2023, 6, 8, 8, 31, 0, 0, time.UTC)
+	latitude := 39.7525
+	var output bytes.Buffer
+	writer, err := NewWriter(&output, columns)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.WriteRow([]interface{}{"stop1", routeType(3), uint64(1686213060), float32(90), &latitude, true, arrival, &arrival}))
+	assert.NoError(t, writer.WriteRow([]interface{}{[]byte("stop2"), int32(2), int64(1686213960), nil, -104.9875, nil, arrival, (*time.Time)(nil)}))
+	assert.EqualError(t, writer.WriteRow([]interface{}{nil, 3, 0, nil, 0.0, nil, arrival, nil}), "column stop_id: null value in a column that isn't optional")
+	assert.EqualError(t, writer.WriteRow([]interface{}{"stop3", math.MaxInt64, 0, nil, 0.0, nil, arrival, nil}), "column stop_sequence: 9223372036854775807 is too large for Int32")
+	assert.EqualError(t, writer.WriteRow([]interface{}{"stop3", 3, 0, nil, "north", nil, arrival, nil}), "column latitude: can't write string as Double")
+	assert.NoError(t, writer.WriteRow([]interface{}{"stop3", 3, 0, nil, 0.0, false, arrival, nil}))
+	assert.NoError(t, writer.Close())
+
+	schema, rows := readRows(t, output.Bytes())
+	assert.Equal(t, len(columns), len(schema.Fields()))
+	for _, column := range columns {
+		leaf, ok := schema.Lookup(column.Name)
+		assert.True(t, ok, column.Name)
+		assert.Equal(t, column.Optional, leaf.Node.Optional(), column.Name)
+	}
+	serviceDate, _ := schema.Lookup("service_date")
+	assert.NotNil(t, serviceDate.Node.Type().LogicalType().Date)
+	arrivalColumn, _ := schema.Lookup("arrival")
+	timestampType := arrivalColumn.Node.Type().LogicalType().Timestamp
+	assert.True(t, timestampType.IsAdjustedToUTC)
+	assert.NotNil(t, timestampType.Unit.Millis)
+	stopId, _ := schema.Lookup("stop_id")
+	assert.NotNil(t, stopId.Node.Type().LogicalType().UTF8)
+
+	assert.Equal(t, 3, len(rows))
+	assert.Equal(t, []string{"stop1", "stop2", "stop3"}, []string{rows[0]["stop_id"].String(), rows[1]["stop_id"].String(), rows[2]["stop_id"].String()})
+	assert.EqualValues(t, 3, rows[0]["stop_sequence"].Int32())
+	assert.EqualValues(t, 1686213060, rows[0]["position_timestamp"].Int64())
+	assert.Equal(t, float32(90), rows[0]["bearing"].Float())
+	assert.True(t, rows[1]["bearing"].IsNull())
+	assert.Equal(t, -104.9875, rows[1]["latitude"].Double())
+	assert.True(t, rows[0]["timepoint"].Boolean())
+	assert.True(t, rows[1]["timepoint"].IsNull())
+	assert.False(t, rows[2]["timepoint"].Boolean())
+	assert.EqualValues(t, 19516, rows[0]["service_date"].Int32())
+	assert.Equal(t, arrival.UnixMilli(), rows[0]["arrival"].Int64())
+	assert.True(t, rows[1]["arrival"].IsNull())
+}
+
+func TestWriteRowGroups(t *testing.T) {
+	var output bytes.Buffer
+	writer, err := NewWriter(&output, []Column{{Name: "id", Type: Int64}})
+	assert.NoError(t, err)
+	for i := 0; i < rowGroupSize+1; i++ {
+		assert.NoError(t, writer.WriteRow([]interface{}{i}))
+	}
+	assert.NoError(t, writer.Close())
+
+	file, err := pq.OpenFile(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	assert.NoError(t, err)
+	assert.EqualValues(t, rowGroupSize+1, file.NumRows())
+	assert.Equal(t, 2, len(file.RowGroups()))
+	_, rows := readRows(t, output.Bytes())
+	assert.EqualValues(t, rowGroupSize, rows[rowGroupSize]["id"].Int64())
+}
+
+func TestNewWriterChecksColumns(t *testing.T) {
+	_, err := NewWriter(io.Discard, []Column{{Name: "id", Type: String}, {Name: "id", Type: Int32}})
+	assert.EqualError(t, err, "more than one column is named id")
+	_, err = NewWriter(io.Discard, []Column{{Type: String}})
+	assert.EqualError(t, err, "every column needs a name")
+}